@@ -1,20 +1,157 @@
 package cmd
 
 import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
 	"github.com/davidmasek/beacon/conf"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 func loadConfig(cmd *cobra.Command) (*conf.Config, error) {
-	configFile, err := cmd.Flags().GetString("config")
+	configFile, err := configPath(cmd)
 	if err != nil {
 		return nil, err
 	}
+	return conf.DefaultConfigFrom(configFile)
+}
 
+// Config file path from CLI flag, or the default path if not set
+func configPath(cmd *cobra.Command) (string, error) {
+	configFile, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return "", err
+	}
 	if configFile != "" {
-		return conf.DefaultConfigFrom(configFile)
+		return configFile, nil
+	}
+	return conf.DefaultConfigPath()
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Check and inspect Beacon configuration",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Check config file and report errors and warnings with line numbers",
+	Long: `Check config file and report errors and warnings with line numbers.
+
+Errors, such as unknown keys or invalid values, prevent Beacon from starting.
+Warnings, such as disabled services, are likely mistakes but do not prevent startup.
+An unreadable token_file is a warning, as the file might exist only where
+Beacon runs. Beacon still refuses to start if it cannot read the file.
+Environment variable overrides (BEACON_*) are applied when validating.
+
+Checks the file from --config (or the default config file) if not specified.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := configPath(cmd)
+		if err != nil {
+			return err
+		}
+		if len(args) > 0 {
+			path = args[0]
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		config, issues := conf.CheckConfig(data, filepath.Dir(path))
+		for _, issue := range issues {
+			location := path
+			if issue.File != "" {
+				location = issue.File
+			}
+			if issue.Line > 0 {
+				location = fmt.Sprintf("%s:%d", location, issue.Line)
+			}
+			cmd.Printf("%s: %s: %s\n", location, issue.Level(), issue.Message)
+		}
+		if config == nil {
+			// issues already printed
+			cmd.SilenceUsage = true
+			return fmt.Errorf("config %s is invalid", path)
+		}
+		cmd.Printf("%s: OK (%d services, %d warnings)\n", path, len(config.AllServices()), len(issues))
+		return nil
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Args:  cobra.ExactArgs(0),
+	Short: "Print effective config, including environment variable overrides",
+	Long: `Print effective config as YAML, after applying defaults
+and environment variable overrides (BEACON_*). Secrets are masked.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		path, err := configPath(cmd)
+		if err != nil {
+			return err
+		}
+		// connection string can contain password
+		if rabbitUrl, err := url.Parse(config.RabbitConn); err == nil {
+			config.RabbitConn = rabbitUrl.Redacted()
+		}
+		// stdout (unlike cmd.Print) so that the output can be redirected to file
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "# config file: %s\n", path)
+		overrides := envOverrides()
+		if len(overrides) > 0 {
+			fmt.Fprintf(out, "# overridden by environment: %s\n", strings.Join(overrides, ", "))
+		}
+		encoder := yaml.NewEncoder(out)
+		encoder.SetIndent(2)
+		err = encoder.Encode(config)
+		if err != nil {
+			return err
+		}
+		return encoder.Close()
+	},
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Args:  cobra.ExactArgs(0),
+	Short: "Print JSON Schema of the config file",
+	Long: `Print JSON Schema of the config file. Editors can use it
+for autocompletion and validation of beacon.yaml.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		schema, err := conf.JsonSchema()
+		if err != nil {
+			return err
+		}
+		_, err = cmd.OutOrStdout().Write(schema)
+		return err
+	},
+}
+
+// Names (not values) of set environment variables with Beacon prefix
+func envOverrides() []string {
+	names := []string{}
+	for _, entry := range os.Environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		if strings.HasPrefix(name, conf.ENV_VAR_PREFIX) {
+			names = append(names, name)
+		}
 	}
+	slices.Sort(names)
+	return names
+}
 
-	config, err := conf.DefaultConfig()
-	return config, err
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configSchemaCmd)
 }
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/monitor"
+	"github.com/davidmasek/beacon/storage"
+	"github.com/spf13/cobra"
+)
+
+// Local time format accepted in addition to RFC3339
+const CLI_TIME_FORMAT = "2006-01-02 15:04"
+
+var maintenanceCmd = &cobra.Command{
+	Use:   "maintenance",
+	Short: "Manage maintenance windows, during which failures are not reported",
+}
+
+// Parse RFC3339 timestamp or local time such as "2025-03-01 02:00" in the given location
+func parseCliTime(value string, loc *time.Location) (time.Time, error) {
+	if value == "now" {
+		return time.Now(), nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return parsed, nil
+	}
+	parsed, err = time.ParseInLocation(CLI_TIME_FORMAT, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC3339 or %q", value, CLI_TIME_FORMAT)
+	}
+	return parsed, nil
+}
+
+// Build maintenance window from `maintenance add` flags
+func maintenanceFromFlags(cmd *cobra.Command, loc *time.Location) (conf.MaintenanceWindow, error) {
+	flags := cmd.Flags()
+	window := conf.MaintenanceWindow{}
+	var err error
+	if window.Services, err = flags.GetStringSlice("service"); err != nil {
+		return window, err
+	}
+	if window.Tags, err = flags.GetStringSlice("tag"); err != nil {
+		return window, err
+	}
+	if window.Reason, err = flags.GetString("reason"); err != nil {
+		return window, err
+	}
+	if window.At, err = flags.GetString("at"); err != nil {
+		return window, err
+	}
+	days, err := flags.GetString("days")
+	if err != nil {
+		return window, err
+	}
+	if days != "" {
+		if err = window.Days.ParseString(days); err != nil {
+			return window, fmt.Errorf("invalid --days: %w", err)
+		}
+	}
+	duration, err := flags.GetString("duration")
+	if err != nil {
+		return window, err
+	}
+	if duration != "" {
+		if window.Duration, err = conf.ParseDuration(duration); err != nil {
+			return window, fmt.Errorf("invalid --duration: %w", err)
+		}
+	}
+	if window.IsRecurring() {
+		return window, nil
+	}
+
+	start, err := flags.GetString("start")
+	if err != nil {
+		return window, err
+	}
+	end, err := flags.GetString("end")
+	if err != nil {
+		return window, err
+	}
+	length, err := flags.GetString("for")
+	if err != nil {
+		return window, err
+	}
+	if window.Start, err = parseCliTime(start, loc); err != nil {
+		return window, fmt.Errorf("invalid --start: %w", err)
+	}
+	if end == "" && length == "" {
+		return window, fmt.Errorf("use --end or --for for one-off window, or --at and --duration for recurring window")
+	}
+	if end != "" && length != "" {
+		return window, fmt.Errorf("use either --end or --for, not both")
+	}
+	if end != "" {
+		if window.End, err = parseCliTime(end, loc); err != nil {
+			return window, fmt.Errorf("invalid --end: %w", err)
+		}
+	}
+	if length != "" {
+		lengthDuration, err := conf.ParseDuration(length)
+		if err != nil {
+			return window, fmt.Errorf("invalid --for: %w", err)
+		}
+		window.End = window.Start.Add(lengthDuration)
+	}
+	return window, nil
+}
+
+var maintenanceAddCmd = &cobra.Command{
+	Use:   "add",
+	Args:  cobra.ExactArgs(0),
+	Short: "Add maintenance window for services or tags",
+	Long: `Add maintenance window for services or tags.
+
+One-off window starts at --start (default now) and lasts until --end or for given duration (--for).
+Recurring window starts at --at on each of --days (default every day) and lasts for --duration.
+Times are in the configured timezone unless given in RFC3339 format.
+
+Examples:
+  beacon maintenance add --service api --for 2h --reason "Database upgrade"
+  beacon maintenance add --tag db --days "Sat Sun" --at 02:00 --duration 1h`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withConfigDb(cmd, func(config *conf.Config, db storage.Storage) error {
+			loc := config.Timezone.Location
+			window, err := maintenanceFromFlags(cmd, loc)
+			if err != nil {
+				return err
+			}
+			created, err := monitor.CreateMaintenanceWindow(db, window, time.Now())
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Created maintenance window %d: %s\n", created.Id, created.Schedule(loc))
+			return nil
+		})
+	},
+}
+
+var maintenanceListCmd = &cobra.Command{
+	Use:   "list",
+	Args:  cobra.ExactArgs(0),
+	Short: "List maintenance windows, including windows from config",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withConfigDb(cmd, func(config *conf.Config, db storage.Storage) error {
+			windows, err := monitor.MaintenanceWindows(db, config)
+			if err != nil {
+				return err
+			}
+			if len(windows) == 0 {
+				cmd.Println("No maintenance windows found")
+				return nil
+			}
+			loc := config.Timezone.Location
+			now := time.Now()
+			cmd.Println("ID\tSERVICES\tTAGS\tSCHEDULE\tSTATUS\tREASON")
+			for _, window := range windows {
+				id := "config"
+				if window.Id != 0 {
+					id = strconv.Itoa(window.Id)
+				}
+				status := "scheduled"
+				if window.IsActive(now, loc) {
+					status = "active"
+				} else if !window.IsRecurring() && !window.End.After(now) {
+					status = "finished"
+				}
+				cmd.Printf("%s\t%s\t%s\t%s\t%s\t%s\n",
+					id,
+					strings.Join(window.Services, " "),
+					strings.Join(window.Tags, " "),
+					window.Schedule(loc),
+					status,
+					window.Reason,
+				)
+			}
+			return nil
+		})
+	},
+}
+
+var maintenanceDeleteCmd = &cobra.Command{
+	Use:   "delete <id>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Delete maintenance window. Windows from config must be removed from the config file.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid maintenance window id %q", args[0])
+		}
+		return withDb(cmd, func(db storage.Storage) error {
+			err := db.DeleteMaintenanceWindow(id)
+			if err != nil {
+				return fmt.Errorf("cannot delete maintenance window %d: %w", id, err)
+			}
+			cmd.Println("Deleted maintenance window", id)
+			return nil
+		})
+	},
+}
+
+func init() {
+	flags := maintenanceAddCmd.Flags()
+	flags.StringSlice("service", nil, "Service id, repeatable")
+	flags.StringSlice("tag", nil, "Service tag, repeatable")
+	flags.String("reason", "", "Reason shown in the GUI")
+	flags.String("start", "now", "Start of one-off window, e.g. \"2025-03-01 02:00\"")
+	flags.String("end", "", "End of one-off window")
+	flags.String("for", "", "Length of one-off window, e.g. 2h")
+	flags.String("days", "", "Days of recurring window, e.g. \"Sat Sun\"")
+	flags.String("at", "", "Start time of recurring window, e.g. 02:00")
+	flags.String("duration", "", "Length of recurring window, e.g. 1h")
+	maintenanceCmd.AddCommand(maintenanceAddCmd)
+	maintenanceCmd.AddCommand(maintenanceListCmd)
+	maintenanceCmd.AddCommand(maintenanceDeleteCmd)
+
+	rootCmd.AddCommand(maintenanceCmd)
+}
@@ -2,8 +2,15 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/davidmasek/beacon/conf"
 	"github.com/davidmasek/beacon/jobs"
 	"github.com/davidmasek/beacon/logging"
 	"github.com/davidmasek/beacon/storage"
@@ -42,21 +49,48 @@ var startCmd = &cobra.Command{
 		}
 		defer func() {
 			closeErr := db.Close()
-			if err != nil {
-				err = closeErr
+			if closeErr != nil {
+				logger.Errorw("failed to close database", zap.Error(closeErr))
 			}
+			err = errors.Join(err, closeErr)
 		}()
 
 		// Overwrite existing config only if set on CLI.
 		// Prefer existing config otherwise, ignore the "Cobra" default.
 		portSet := cmd.Flag("port").Changed
-		if portSet {
-			config.Port = port
+		overrideFlags := func(config *conf.Config) {
+			if portSet {
+				config.Port = port
+			}
+		}
+		overrideFlags(config)
+		err = config.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+		path, err := configPath(cmd)
+		if err != nil {
+			return err
 		}
+		live := conf.NewLiveConfig(path, config, overrideFlags)
+
+		signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stopSignals()
+		reloadSignals := make(chan os.Signal, 1)
+		signal.Notify(reloadSignals, syscall.SIGHUP)
+		defer signal.Stop(reloadSignals)
+
 		server, err := web_server.StartServer(db, config)
 		if err != nil {
 			return err
 		}
+		live.OnReload(func(old *conf.Config, new *conf.Config) {
+			server.Reload(new)
+			changed := conf.RestartRequiredChanges(old, new)
+			if len(changed) > 0 {
+				logger.Warnw("Some config changes take effect only after restart", "settings", changed)
+			}
+		})
 
 		if !config.EmailConf.IsConfigured() {
 			missingFields := config.EmailConf.MissingConfigurationFields()
@@ -66,32 +100,94 @@ var startCmd = &cobra.Command{
 		err = jobs.VerifyQueueConnection(config)
 		if err != nil {
 			logger.Errorw("Cannot connect to RabbitMQ", zap.Error((err)))
-			return err
+			return errors.Join(err, server.Close())
 		}
 
-		ctx, cancelScheduler := context.WithCancel(context.Background())
-		go jobs.Start(ctx, db, config)
-		go jobs.ReadTasks(ctx, db, config)
+		jobsCtx, cancelJobs := context.WithCancel(context.Background())
+		defer cancelJobs()
+		var runningJobs sync.WaitGroup
+		runningJobs.Add(3)
+		go func() {
+			defer runningJobs.Done()
+			jobs.Start(jobsCtx, db, live)
+		}()
+		go func() {
+			defer runningJobs.Done()
+			jobs.ReadTasks(jobsCtx, db, live)
+		}()
+		go func() {
+			defer runningJobs.Done()
+			live.Watch(jobsCtx, conf.CONFIG_WATCH_INTERVAL)
+		}()
 
 		if stopServer {
-			err = server.Close()
+			err = shutdown(server, cancelJobs, &runningJobs, config.ShutdownTimeout)
 			if err != nil {
-				logger.Errorw("failed to close server", zap.Error(err))
+				return err
 			}
-			cancelScheduler()
 			cmd.Println(SERVER_SUCCESS_MESSAGE)
 			return nil
 		}
 
-		exit := make(chan struct{})
-		// block forever
-		<-exit
-		// not really needed, but linters complain otherwise
-		cancelScheduler()
-		return nil
+		var serverErr error
+	wait:
+		for {
+			select {
+			case <-reloadSignals:
+				logger.Info("Received SIGHUP, reloading config")
+				live.ReloadAndLog()
+			case <-signalCtx.Done():
+				logger.Info("Received stop signal, shutting down")
+				break wait
+			case serverErr = <-server.Errors():
+				logger.Errorw("Server failed, shutting down", zap.Error(serverErr))
+				break wait
+			}
+		}
+		// second signal kills the process immediately
+		stopSignals()
+
+		err = shutdown(server, cancelJobs, &runningJobs, live.Get().ShutdownTimeout)
+		if serverErr != nil {
+			return errors.Join(fmt.Errorf("server failed: %w", serverErr), err)
+		}
+		return err
 	},
 }
 
+// Stop the server and background jobs, waiting up to timeout for in-flight work to finish.
+func shutdown(server *web_server.Server, cancelJobs context.CancelFunc, runningJobs *sync.WaitGroup, timeout time.Duration) error {
+	logger := logging.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// stop scheduling new jobs and consuming the queue, in-flight jobs continue
+	cancelJobs()
+
+	var err error
+	if shutdownErr := server.Shutdown(ctx); shutdownErr != nil {
+		logger.Errorw("Server did not stop gracefully", zap.Error(shutdownErr))
+		err = fmt.Errorf("server shutdown: %w", shutdownErr)
+	}
+
+	jobsDone := make(chan struct{})
+	go func() {
+		runningJobs.Wait()
+		close(jobsDone)
+	}()
+	select {
+	case <-jobsDone:
+	case <-ctx.Done():
+		logger.Error("Background jobs did not finish in time")
+		err = errors.Join(err, fmt.Errorf("waiting for jobs: %w", ctx.Err()))
+	}
+
+	if err == nil {
+		logger.Info("Shutdown complete")
+	}
+	return err
+}
+
 func init() {
 	rootCmd.AddCommand(startCmd)
 
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/storage"
+	"github.com/spf13/cobra"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage API tokens",
+}
+
+func formatTokenTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(storage.TIME_FORMAT)
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Create new API token. The token value is printed only once.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		scopes, err := cmd.Flags().GetStringSlice("scope")
+		if err != nil {
+			return err
+		}
+		expires, err := cmd.Flags().GetString("expires")
+		if err != nil {
+			return err
+		}
+		var expiresAt time.Time
+		if expires != "" {
+			duration, err := conf.ParseDuration(expires)
+			if err != nil {
+				return fmt.Errorf("invalid --expires: %w", err)
+			}
+			if duration <= 0 {
+				return fmt.Errorf("invalid --expires: must be positive")
+			}
+			expiresAt = time.Now().Add(duration)
+		}
+		return withDb(cmd, func(db storage.Storage) error {
+			value, token, err := db.CreateApiToken(name, scopes, expiresAt)
+			if err != nil {
+				return err
+			}
+			cmd.PrintErrf("Created token %d (%s) with scopes: %s\n", token.Id, token.Name, strings.Join(token.Scopes, " "))
+			cmd.PrintErrln("Store the token now, it will not be shown again:")
+			fmt.Fprintln(cmd.OutOrStdout(), value)
+			return nil
+		})
+	},
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Args:  cobra.ExactArgs(0),
+	Short: "List API tokens",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withDb(cmd, func(db storage.Storage) error {
+			tokens, err := db.ListApiTokens()
+			if err != nil {
+				return err
+			}
+			if len(tokens) == 0 {
+				cmd.Println("No tokens found")
+				return nil
+			}
+			now := time.Now()
+			cmd.Println("ID\tNAME\tSCOPES\tEXPIRES\tLAST USED\tSTATUS")
+			for _, token := range tokens {
+				status := "active"
+				if !token.RevokedAt.IsZero() {
+					status = "revoked"
+				} else if !token.IsActive(now) {
+					status = "expired"
+				}
+				cmd.Printf("%d\t%s\t%s\t%s\t%s\t%s\n",
+					token.Id,
+					token.Name,
+					strings.Join(token.Scopes, " "),
+					formatTokenTime(token.ExpiresAt),
+					formatTokenTime(token.LastUsedAt),
+					status,
+				)
+			}
+			return nil
+		})
+	},
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <id>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Revoke API token",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid token id %q", args[0])
+		}
+		return withDb(cmd, func(db storage.Storage) error {
+			err := db.RevokeApiToken(id, time.Now())
+			if err != nil {
+				return fmt.Errorf("cannot revoke token %d: %w", id, err)
+			}
+			cmd.Println("Revoked token", id)
+			return nil
+		})
+	},
+}
+
+func init() {
+	tokenCreateCmd.Flags().StringSlice("scope", nil, "Token scope, repeatable: admin, read:all or beat:<service-id>")
+	tokenCreateCmd.Flags().String("expires", "", "Expire the token after given duration, e.g. 30d or 12h")
+	tokenCmd.AddCommand(tokenCreateCmd)
+	tokenCmd.AddCommand(tokenListCmd)
+	tokenCmd.AddCommand(tokenRevokeCmd)
+
+	rootCmd.AddCommand(tokenCmd)
+}
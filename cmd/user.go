@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/storage"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage users of the web GUI",
+}
+
+// Open DB from config, run `action` and close the DB
+func withDb(cmd *cobra.Command, action func(db storage.Storage) error) error {
+	return withConfigDb(cmd, func(config *conf.Config, db storage.Storage) error {
+		return action(db)
+	})
+}
+
+// Like withDb, for actions that also need the config
+func withConfigDb(cmd *cobra.Command, action func(config *conf.Config, db storage.Storage) error) (err error) {
+	config, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	db, err := storage.InitDB(config.DbPath)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer func() {
+		closeErr := db.Close()
+		err = errors.Join(err, closeErr)
+	}()
+	return action(config, db)
+}
+
+// Read password from terminal prompt (without echo) or from the first line of stdin if not a terminal
+func readPassword(cmd *cobra.Command) (string, error) {
+	in := cmd.InOrStdin()
+	if file, ok := in.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		cmd.Print("Password: ")
+		password, err := term.ReadPassword(int(file.Fd()))
+		cmd.Println()
+		if err != nil {
+			return "", err
+		}
+		cmd.Print("Repeat password: ")
+		repeated, err := term.ReadPassword(int(file.Fd()))
+		cmd.Println()
+		if err != nil {
+			return "", err
+		}
+		if string(password) != string(repeated) {
+			return "", fmt.Errorf("passwords do not match")
+		}
+		return string(password), nil
+	}
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("empty password")
+	}
+	return password, nil
+}
+
+var userAddCmd = &cobra.Command{
+	Use:   "add <email>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Create new user. Password is read from prompt or stdin.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		email := args[0]
+		return withDb(cmd, func(db storage.Storage) error {
+			existing, err := db.GetUser(email)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				return fmt.Errorf("%w: %s", storage.ErrEmailAlreadyUsed, email)
+			}
+			password, err := readPassword(cmd)
+			if err != nil {
+				return err
+			}
+			err = db.CreateUser(email, password)
+			if err != nil {
+				return err
+			}
+			cmd.Println("Created user", email)
+			return nil
+		})
+	},
+}
+
+var userListCmd = &cobra.Command{
+	Use:   "list",
+	Args:  cobra.ExactArgs(0),
+	Short: "List users",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withDb(cmd, func(db storage.Storage) error {
+			users, err := db.ListUsers()
+			if err != nil {
+				return err
+			}
+			if len(users) == 0 {
+				cmd.Println("No users found")
+				return nil
+			}
+			for _, user := range users {
+				cmd.Printf("%s\t(created %s)\n", user.Email(), user.CreatedAt().Format(storage.TIME_FORMAT))
+			}
+			return nil
+		})
+	},
+}
+
+var userDeleteCmd = &cobra.Command{
+	Use:   "delete <email>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Delete user",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		email := args[0]
+		return withDb(cmd, func(db storage.Storage) error {
+			err := db.DeleteUser(email)
+			if err != nil {
+				return fmt.Errorf("cannot delete %s: %w", email, err)
+			}
+			cmd.Println("Deleted user", email)
+			return nil
+		})
+	},
+}
+
+var userPasswdCmd = &cobra.Command{
+	Use:   "passwd <email>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Change user password. Password is read from prompt or stdin.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		email := args[0]
+		return withDb(cmd, func(db storage.Storage) error {
+			existing, err := db.GetUser(email)
+			if err != nil {
+				return err
+			}
+			if existing == nil {
+				return fmt.Errorf("cannot change password for %s: %w", email, storage.ErrUserNotFound)
+			}
+			password, err := readPassword(cmd)
+			if err != nil {
+				return err
+			}
+			err = db.UpdatePassword(email, password)
+			if err != nil {
+				return err
+			}
+			cmd.Println("Password changed for", email)
+			return nil
+		})
+	},
+}
+
+func init() {
+	userCmd.AddCommand(userAddCmd)
+	userCmd.AddCommand(userListCmd)
+	userCmd.AddCommand(userDeleteCmd)
+	userCmd.AddCommand(userPasswdCmd)
+
+	rootCmd.AddCommand(userCmd)
+}
@@ -0,0 +1,449 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Keys of a single service in config file, see NewServiceConfig
+var SERVICE_KEYS = []string{
+	"extends",
+	"url",
+	"status",
+	"content",
+	"timeout",
+	"enabled",
+	"public",
+	"display_name",
+	"note",
+	"token",
+	"token_file",
+	"tags",
+	"group",
+	"escalation",
+}
+
+// Keys allowed in defaults, which cannot extend templates
+func defaultsKeys() []string {
+	return slices.DeleteFunc(slices.Clone(SERVICE_KEYS), func(key string) bool {
+		return key == "extends"
+	})
+}
+
+// Problem found in config file, see CheckConfig
+type ConfigIssue struct {
+	// Included file, empty for the main config file
+	File string
+	// Line in config file, 0 if unknown (e.g. for values from env variables)
+	Line    int
+	Warning bool
+	Message string
+}
+
+func (issue ConfigIssue) Level() string {
+	if issue.Warning {
+		return "warning"
+	}
+	return "error"
+}
+
+func (issue ConfigIssue) String() string {
+	if issue.Line == 0 {
+		return fmt.Sprintf("%s: %s", issue.Level(), issue.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", issue.Line, issue.Level(), issue.Message)
+}
+
+var yamlLineRegex = regexp.MustCompile(`line (\d+): `)
+
+var yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// Check config file contents and report all problems found,
+// sorted by file (main config first) and line.
+//
+// Errors are problems that prevent Beacon from starting, such as invalid YAML,
+// unknown keys or values that do not pass Config.Validate. Warnings are
+// likely mistakes that do not prevent startup, such as disabled services.
+// Included files are checked as well, relative to baseDir.
+//
+// Returns the parsed config (including env variable overrides)
+// or nil if any errors were found.
+func CheckConfig(data []byte, baseDir string) (*Config, []ConfigIssue) {
+	root := &yaml.Node{}
+	err := yaml.Unmarshal(data, root)
+	if err != nil {
+		return nil, issuesFromError(err, 0)
+	}
+	envIssues := interpolateEnvIssues(root)
+	var doc *yaml.Node
+	if len(root.Content) > 0 {
+		doc = root.Content[0]
+	}
+
+	// Unknown keys are reported and removed, so that the rest of the config
+	// can be checked as well. Other errors prevent parsing the config.
+	issues := append(envIssues, removeAllUnknownKeys(doc)...)
+	parseable := true
+	templates := &ServiceTemplates{skipUnreadableTokenFiles: true}
+	if doc != nil {
+		err = doc.Decode(templates)
+		if err != nil {
+			issues = append(issues, issuesFromError(err, 0)...)
+			parseable = false
+		}
+	}
+	if parseable {
+		serviceIssues, ok := checkServices(doc, templates)
+		issues = append(issues, serviceIssues...)
+		parseable = ok
+	}
+
+	var config *Config
+	if parseable {
+		config, err = configFromNode(doc, true)
+		if err != nil {
+			issues = append(issues, issuesFromError(err, 0)...)
+		}
+	}
+	if config != nil {
+		issues = append(issues, validationIssues(config.Validate(), doc, "")...)
+		issues = append(issues, checkIncludedFiles(config, doc, baseDir)...)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		// issues without line (from env variables) last
+		if issues[i].Line == 0 || issues[j].Line == 0 {
+			return issues[j].Line == 0 && issues[i].Line != 0
+		}
+		return issues[i].Line < issues[j].Line
+	})
+	if hasErrors(issues) {
+		return nil, issues
+	}
+	return config, issues
+}
+
+// Check all services in the document, see checkService
+func checkServices(doc *yaml.Node, templates *ServiceTemplates) ([]ConfigIssue, bool) {
+	issues := []ConfigIssue{}
+	parseable := true
+	servicesNode := mappingValue(doc, "services")
+	if servicesNode != nil && servicesNode.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(servicesNode.Content); i += 2 {
+			serviceIssues, ok := checkService(servicesNode.Content[i], servicesNode.Content[i+1], templates)
+			issues = append(issues, serviceIssues...)
+			parseable = parseable && ok
+		}
+	}
+	return issues, parseable
+}
+
+// Convert errors from Validate to issues, looking up lines in doc
+func validationIssues(err error, doc *yaml.Node, file string) []ConfigIssue {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	issues := []ConfigIssue{}
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		line := 0
+		var keyErr *KeyError
+		if errors.As(err, &keyErr) {
+			line = keyLine(doc, keyErr.Key)
+		}
+		issues = append(issues, ConfigIssue{File: file, Line: line, Message: err.Error()})
+	}
+	return issues
+}
+
+// Check files from config.Include and add their services to config.
+// See also Config.includeServices.
+func checkIncludedFiles(config *Config, doc *yaml.Node, baseDir string) []ConfigIssue {
+	files, err := config.IncludedFiles(baseDir)
+	if err != nil {
+		return []ConfigIssue{{Line: keyLine(doc, "include"), Message: err.Error()}}
+	}
+	return config.loadIncludedFiles(files)
+}
+
+func hasErrors(issues []ConfigIssue) bool {
+	for _, issue := range issues {
+		if !issue.Warning {
+			return true
+		}
+	}
+	return false
+}
+
+// Convert (YAML) error to issues, extracting line numbers from the message if present.
+// Line is used for errors without line number in the message.
+func issuesFromError(err error, line int) []ConfigIssue {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		issues := []ConfigIssue{}
+		for _, err := range joined.Unwrap() {
+			issues = append(issues, issuesFromError(err, line)...)
+		}
+		return issues
+	}
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+	issues := []ConfigIssue{}
+	for _, message := range messages {
+		issue := ConfigIssue{Line: line, Message: message}
+		match := yamlLineRegex.FindStringSubmatchIndex(message)
+		if match != nil {
+			issue.Line, _ = strconv.Atoi(message[match[2]:match[3]])
+			issue.Message = message[:match[0]] + message[match[1]:]
+		}
+		issue.Message = strings.TrimPrefix(issue.Message, "yaml: ")
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// Value for the given key of a mapping node, nil if not found
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// Line of the given dotted key (such as "tls.cert_file" or "alert_routes.0.send_to"),
+// or of its closest parent if the key itself is not present. Returns 0 if not found at all.
+func keyLine(node *yaml.Node, key string) int {
+	line := 0
+	for node != nil && key != "" {
+		if node.Kind == yaml.SequenceNode {
+			index, rest, _ := strings.Cut(key, ".")
+			i, err := strconv.Atoi(index)
+			if err != nil || i < 0 || i >= len(node.Content) {
+				break
+			}
+			node = node.Content[i]
+			line = node.Line
+			key = rest
+			continue
+		}
+		if node.Kind != yaml.MappingNode {
+			break
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			name := node.Content[i].Value
+			// service ids can contain dots, so match by prefix instead of splitting the key
+			if key == name || strings.HasPrefix(key, name+".") {
+				line = node.Content[i].Line
+				next = node.Content[i+1]
+				key = strings.TrimPrefix(strings.TrimPrefix(key, name), ".")
+				break
+			}
+		}
+		node = next
+	}
+	return line
+}
+
+// Fields of struct type t by their YAML name, including inlined structs
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && strings.Contains(field.Tag.Get("yaml"), ",inline") {
+			for name, fieldType := range yamlFields(field.Type) {
+				fields[name] = fieldType
+			}
+		} else if name := yamlFieldName(field); name != "" {
+			fields[name] = field.Type
+		}
+	}
+	return fields
+}
+
+// Name of struct field in YAML, empty if not (un)marshalled
+func yamlFieldName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		// yaml.v3 default
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// Error listing keys that do not belong to the config, with line numbers.
+// Services are checked when decoding, see checkServiceKeys.
+func unknownKeysError(doc *yaml.Node) error {
+	errs := []error{}
+	for _, issue := range removeAllUnknownKeys(doc) {
+		errs = append(errs, fmt.Errorf("line %d: %s", issue.Line, issue.Message))
+	}
+	return errors.Join(errs...)
+}
+
+// Remove and report unknown keys everywhere except services
+func removeAllUnknownKeys(doc *yaml.Node) []ConfigIssue {
+	issues := removeUnknownKeys(doc, reflect.TypeOf(Config{}), "")
+	issues = append(issues, removeUnknownServiceKeys("defaults", mappingValue(doc, "defaults"), defaultsKeys())...)
+	templates := mappingValue(doc, "templates")
+	if templates != nil && templates.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(templates.Content); i += 2 {
+			label := "templates." + templates.Content[i].Value
+			issues = append(issues, removeUnknownServiceKeys(label, templates.Content[i+1], SERVICE_KEYS)...)
+		}
+	}
+	return issues
+}
+
+// Remove and report keys of service (or defaults, template) that are not allowed
+func removeUnknownServiceKeys(label string, node *yaml.Node, allowed []string) []ConfigIssue {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	issues := []ConfigIssue{}
+	known := []*yaml.Node{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if !slices.Contains(allowed, keyNode.Value) {
+			issues = append(issues, ConfigIssue{
+				Line:    keyNode.Line,
+				Message: fmt.Sprintf("[%s] unknown key %q", label, keyNode.Value),
+			})
+			continue
+		}
+		known = append(known, keyNode, node.Content[i+1])
+	}
+	node.Content = known
+	return issues
+}
+
+// Remove and report keys in mapping node that do not match any field of struct type t
+func removeUnknownKeys(node *yaml.Node, t reflect.Type, prefix string) []ConfigIssue {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	fields := yamlFields(t)
+	issues := []ConfigIssue{}
+	known := []*yaml.Node{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		fieldType, ok := fields[keyNode.Value]
+		if !ok {
+			issues = append(issues, ConfigIssue{
+				Line:    keyNode.Line,
+				Message: fmt.Sprintf("unknown key %q", prefix+keyNode.Value),
+			})
+			continue
+		}
+		known = append(known, keyNode, node.Content[i+1])
+		// types with custom unmarshalling are checked separately
+		if isPlainStruct(fieldType) {
+			issues = append(issues, removeUnknownKeys(node.Content[i+1], fieldType, prefix+keyNode.Value+".")...)
+		}
+		valueNode := node.Content[i+1]
+		if fieldType.Kind() == reflect.Slice && isPlainStruct(fieldType.Elem()) && valueNode.Kind == yaml.SequenceNode {
+			for j, item := range valueNode.Content {
+				issues = append(issues, removeUnknownKeys(item, fieldType.Elem(), fmt.Sprintf("%s%s[%d].", prefix, keyNode.Value, j))...)
+			}
+		}
+		if fieldType.Kind() == reflect.Map && isPlainStruct(fieldType.Elem()) && valueNode.Kind == yaml.MappingNode {
+			for j := 0; j+1 < len(valueNode.Content); j += 2 {
+				itemPrefix := fmt.Sprintf("%s%s.%s.", prefix, keyNode.Value, valueNode.Content[j].Value)
+				issues = append(issues, removeUnknownKeys(valueNode.Content[j+1], fieldType.Elem(), itemPrefix)...)
+			}
+		}
+	}
+	node.Content = known
+	return issues
+}
+
+func isPlainStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(yamlUnmarshalerType)
+}
+
+// Check a single service. Unknown keys are reported and removed.
+// Returns false if the service cannot be parsed.
+func checkService(keyNode *yaml.Node, valueNode *yaml.Node, templates *ServiceTemplates) ([]ConfigIssue, bool) {
+	id := keyNode.Value
+	issues := removeUnknownServiceKeys(id, valueNode, SERVICE_KEYS)
+	input := ServiceInput{}
+	err := valueNode.Decode(input)
+	if err != nil {
+		return append(issues, issuesFromError(err, keyNode.Line)...), false
+	}
+	service, err := NewServiceConfig(id, input, templates)
+	if err != nil {
+		line := keyNode.Line
+		var keyErr *KeyError
+		if errors.As(err, &keyErr) {
+			line = keyLine(valueNode, strings.TrimPrefix(keyErr.Key, serviceKey(id, "")))
+		}
+		return append(issues, ConfigIssue{Line: line, Message: err.Error()}), false
+	}
+	if !service.Enabled {
+		issues = append(issues, ConfigIssue{
+			Line:    keyLine(valueNode, "enabled"),
+			Warning: true,
+			Message: fmt.Sprintf("[%s] service is disabled", id),
+		})
+	}
+	// token file might exist only where Beacon runs, but Beacon does not start without it
+	if templates != nil && templates.skipUnreadableTokenFiles {
+		issues = append(issues, tokenFileIssues(id, keyNode, valueNode, input, templates)...)
+	}
+	return issues, true
+}
+
+// Warn about token file of the service that cannot be read
+func tokenFileIssues(id string, keyNode *yaml.Node, valueNode *yaml.Node, input ServiceInput, templates *ServiceTemplates) []ConfigIssue {
+	resolved, err := templates.resolve(id, input)
+	if err != nil {
+		return nil
+	}
+	tokenFile, _ := resolved["token_file"].(string)
+	if tokenFile == "" {
+		return nil
+	}
+	_, err = os.ReadFile(tokenFile)
+	if err == nil {
+		return nil
+	}
+	line := keyLine(valueNode, "token_file")
+	if line == 0 {
+		// set by template or defaults
+		line = keyNode.Line
+	}
+	return []ConfigIssue{{
+		Line:    line,
+		Warning: true,
+		Message: fmt.Sprintf("[%s] cannot read token file: %s", id, err),
+	}}
+}
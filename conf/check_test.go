@@ -0,0 +1,88 @@
+package conf
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckConfig(t *testing.T) {
+	config, issues := CheckConfig([]byte(`
+port: 8088
+tls:
+  cert_file: cert.pem
+  key_fle: key.pem
+services:
+  example.com:
+    url: "example.com"
+    statuses: [200]
+  backup:
+    enabled: false
+`), "")
+	require.Nil(t, config)
+	require.Equal(t, []ConfigIssue{
+		{Line: 3, Message: "tls requires both cert_file and key_file"},
+		{Line: 5, Message: `unknown key "tls.key_fle"`},
+		{Line: 8, Message: `[example.com] url must be absolute http(s) URL, got "example.com"`},
+		{Line: 9, Message: `[example.com] unknown key "statuses"`},
+		{Line: 11, Warning: true, Message: "[backup] service is disabled"},
+	}, issues)
+
+	config, issues = CheckConfig([]byte(`
+services:
+  backup:
+    timeout: 1h
+    enabled: "no"
+`), "")
+	require.Nil(t, config)
+	require.Equal(t, []ConfigIssue{
+		{Line: 5, Message: "[backup] invalid type for enabled, expected bool, got no"},
+	}, issues)
+
+	_, issues = CheckConfig([]byte("port: 8088\nservices\n  backup:\n"), "")
+	require.Len(t, issues, 1)
+	require.Equal(t, 2, issues[0].Line)
+	require.False(t, issues[0].Warning)
+
+	config, issues = CheckConfig([]byte("services:\n  backup:\n"), "")
+	require.NotNil(t, config)
+	require.Empty(t, issues)
+}
+
+func TestCheckConfigTokenFile(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.txt")
+	data := []byte(fmt.Sprintf(`
+templates:
+  cron:
+    token_file: %[1]s
+services:
+  backup:
+    token_file: %[1]s
+  cleanup:
+    extends: cron
+`, missing))
+	config, issues := CheckConfig(data, "")
+	require.NotNil(t, config, "unreadable token file is only a warning")
+	require.Len(t, issues, 2)
+	require.Equal(t, 7, issues[0].Line)
+	require.True(t, issues[0].Warning)
+	require.Contains(t, issues[0].Message, "[backup] cannot read token file")
+	require.Equal(t, 8, issues[1].Line)
+	require.Contains(t, issues[1].Message, "[cleanup] cannot read token file")
+
+	// but Beacon does not start without it
+	_, err := ConfigFromBytes(data)
+	require.ErrorContains(t, err, "[backup] cannot read token file")
+}
+
+func TestCheckConfigEnv(t *testing.T) {
+	t.Setenv("BEACON_REPORT_TIME", "30")
+	config, issues := CheckConfig([]byte("port: 8088\n"), "")
+	require.Nil(t, config)
+	require.Equal(t, []ConfigIssue{
+		{Line: 0, Message: "report_time must be between 0 and 23, got 30"},
+	}, issues)
+	require.Equal(t, "error: report_time must be between 0 and 23, got 30", issues[0].String())
+}
@@ -135,8 +135,11 @@ prefix: "[test]"
 		"beacon",
 		Secret{"h4xor", ""},
 		true,
-		"you@example.fake",
+		Addresses{"you@example.fake"},
+		nil,
+		nil,
 		"noreply@example.fake",
+		"",
 		"[test]",
 		"",
 		"",
@@ -165,8 +168,11 @@ sender: noreply@example.fake
 		"beacon",
 		Secret{"h4xor", ""},
 		false, // optional SSL
-		"you@example.fake",
+		Addresses{"you@example.fake"},
+		nil, // optional cc
+		nil, // optional bcc
 		"noreply@example.fake",
+		"", // optional reply_to
 		"", // optional prefix
 		"",
 		"",
@@ -206,3 +212,23 @@ func TestSecretFromFile(t *testing.T) {
 	err = os.Remove("secret-test.txt")
 	require.NoError(t, err)
 }
+
+func TestParseEmailAddresses(t *testing.T) {
+	t.Setenv("BEACON_EMAIL_BCC", "archive@example.fake, audit@example.fake")
+	config, err := ConfigFromBytes([]byte(`
+email:
+  send_to: "you@example.fake, Team <team@example.fake>"
+  cc:
+    - boss@example.fake
+  reply_to: support@example.fake
+`))
+	require.NoError(t, err)
+	assert.Equal(t, Addresses{"you@example.fake", "Team <team@example.fake>"}, config.EmailConf.SendTo)
+	assert.Equal(t, Addresses{"boss@example.fake"}, config.EmailConf.Cc)
+	assert.Equal(t, Addresses{"archive@example.fake", "audit@example.fake"}, config.EmailConf.Bcc)
+	require.NoError(t, config.Validate())
+
+	config.EmailConf.Cc = Addresses{"not an address"}
+	err = config.Validate()
+	require.ErrorContains(t, err, `email.cc: invalid email address "not an address"`)
+}
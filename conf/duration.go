@@ -0,0 +1,44 @@
+package conf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parse duration like time.ParseDuration, with additional support
+// for days (`30d`) and weeks (`2w`).
+func ParseDuration(value string) (time.Duration, error) {
+	multiplier := time.Duration(0)
+	if strings.HasSuffix(value, "d") {
+		multiplier = 24 * time.Hour
+	} else if strings.HasSuffix(value, "w") {
+		multiplier = 7 * 24 * time.Hour
+	}
+	if multiplier == 0 {
+		return time.ParseDuration(value)
+	}
+	count, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || count < 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return time.Duration(count) * multiplier, nil
+}
+
+// Format duration in the shortest form accepted by ParseDuration,
+// e.g. `2d` instead of `48h0m0s` or `90m` instead of `1h30m0s`.
+func FormatDuration(duration time.Duration) string {
+	day := 24 * time.Hour
+	if duration > 0 && duration%day == 0 {
+		return fmt.Sprintf("%dd", duration/day)
+	}
+	value := duration.String()
+	if strings.HasSuffix(value, "m0s") {
+		value = strings.TrimSuffix(value, "0s")
+	}
+	if strings.HasSuffix(value, "h0m") {
+		value = strings.TrimSuffix(value, "0m")
+	}
+	return value
+}
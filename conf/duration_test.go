@@ -0,0 +1,42 @@
+package conf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDuration(t *testing.T) {
+	duration, err := ParseDuration("30d")
+	require.NoError(t, err)
+	require.Equal(t, 30*24*time.Hour, duration)
+	duration, err = ParseDuration("2w")
+	require.NoError(t, err)
+	require.Equal(t, 14*24*time.Hour, duration)
+	duration, err = ParseDuration("90m")
+	require.NoError(t, err)
+	require.Equal(t, 90*time.Minute, duration)
+	_, err = ParseDuration("xd")
+	require.Error(t, err)
+}
+
+func TestFormatDuration(t *testing.T) {
+	testCases := map[time.Duration]string{
+		48 * time.Hour:             "2d",
+		14 * 24 * time.Hour:        "14d",
+		time.Hour:                  "1h",
+		90 * time.Minute:           "1h30m",
+		15 * time.Minute:           "15m",
+		30 * time.Second:           "30s",
+		time.Hour + 30*time.Second: "1h0m30s",
+		0:                          "0s",
+	}
+	for duration, expected := range testCases {
+		formatted := FormatDuration(duration)
+		require.Equal(t, expected, formatted)
+		parsed, err := ParseDuration(formatted)
+		require.NoError(t, err)
+		require.Equal(t, duration, parsed)
+	}
+}
@@ -0,0 +1,104 @@
+package conf
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// Step of EscalationPolicy
+type EscalationStep struct {
+	// Time since the incident opened, 0 to notify immediately
+	After time.Duration `yaml:"after"`
+	// Names from Config.NotificationTargets
+	Targets []string `yaml:"targets"`
+}
+
+// Notify targets in steps while an incident of the service is neither resolved nor acknowledged.
+// Each step is notified once. Selected by ServiceConfig.Escalation.
+type EscalationPolicy struct {
+	// Sorted by After
+	Steps []EscalationStep `yaml:"steps"`
+}
+
+// Number of steps that are due at `now` for incident opened at `openedAt`
+func (policy *EscalationPolicy) DueSteps(openedAt time.Time, now time.Time) int {
+	due := 0
+	for _, step := range policy.Steps {
+		if now.Before(openedAt.Add(step.After)) {
+			break
+		}
+		due++
+	}
+	return due
+}
+
+// Escalation policy of the service, nil if the service does not use one
+func (config *Config) ServiceEscalation(service *ServiceConfig) *EscalationPolicy {
+	if service.Escalation == "" {
+		return nil
+	}
+	policy, ok := config.EscalationPolicies[service.Escalation]
+	if !ok {
+		return nil
+	}
+	return &policy
+}
+
+// Targets of the given steps, in order, without duplicates
+func (config *Config) EscalationTargets(steps []EscalationStep) []NotificationTarget {
+	targets := []NotificationTarget{}
+	for _, step := range steps {
+		for _, name := range step.Targets {
+			if slices.ContainsFunc(targets, func(t NotificationTarget) bool { return t.Name == name }) {
+				continue
+			}
+			target := config.NotificationTargets[name]
+			target.Name = name
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// See Config.Validate
+func (config *Config) validateEscalation() []error {
+	errs := []error{}
+	if config.AlertRepeatInterval <= 0 {
+		errs = append(errs, keyErrorf("alert_repeat_interval", "alert_repeat_interval must be positive, got %s", config.AlertRepeatInterval))
+	}
+	for _, name := range slices.Sorted(maps.Keys(config.EscalationPolicies)) {
+		policy := config.EscalationPolicies[name]
+		if len(policy.Steps) == 0 {
+			errs = append(errs, keyErrorf(fmt.Sprintf("escalation_policies.%s", name), "escalation policy %q requires steps", name))
+		}
+		for i, step := range policy.Steps {
+			key := func(field string) string {
+				return fmt.Sprintf("escalation_policies.%s.steps.%d.%s", name, i, field)
+			}
+			if len(step.Targets) == 0 {
+				errs = append(errs, keyErrorf(key("targets"), "escalation policy %q step %d requires targets", name, i))
+			}
+			for _, target := range step.Targets {
+				if _, ok := config.NotificationTargets[target]; !ok {
+					errs = append(errs, keyErrorf(key("targets"), "escalation policy %q step %d unknown notification target %q", name, i, target))
+				}
+			}
+			if step.After < 0 {
+				errs = append(errs, keyErrorf(key("after"), "escalation policy %q step %d after must not be negative, got %s", name, i, step.After))
+			} else if i > 0 && step.After < policy.Steps[i-1].After {
+				errs = append(errs, keyErrorf(key("after"), "escalation policy %q step %d must not come before the previous step", name, i))
+			}
+		}
+	}
+	for _, service := range config.AllServices() {
+		if service.Escalation == "" {
+			continue
+		}
+		if _, ok := config.EscalationPolicies[service.Escalation]; !ok {
+			errs = append(errs, keyErrorf(serviceKey(service.Id, "escalation"), "[%s] unknown escalation policy %q", service.Id, service.Escalation))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,86 @@
+package conf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscalationPolicy(t *testing.T) {
+	config, err := ConfigFromBytes([]byte(`
+notification_targets:
+  oncall:
+    send_to: oncall@example.com
+  lead:
+    send_to: lead@example.com
+  cto:
+    send_to: cto@example.com
+escalation_policies:
+  backend:
+    steps:
+      - targets: [oncall]
+      - after: 15m
+        targets: [lead, oncall]
+      - after: 1h
+        targets: [cto]
+defaults:
+  escalation: backend
+services:
+  api:
+  website:
+    escalation:
+`))
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, config.AlertRepeatInterval)
+
+	policy := config.ServiceEscalation(config.Services.Get("api"))
+	require.NotNil(t, policy)
+	require.Len(t, policy.Steps, 3)
+	assert.Equal(t, 15*time.Minute, policy.Steps[1].After)
+	// empty value does not override defaults
+	assert.NotNil(t, config.ServiceEscalation(config.Services.Get("website")))
+	assert.Nil(t, config.ServiceEscalation(&ServiceConfig{Id: "adopted"}))
+
+	openedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, 1, policy.DueSteps(openedAt, openedAt))
+	assert.Equal(t, 1, policy.DueSteps(openedAt, openedAt.Add(14*time.Minute)))
+	assert.Equal(t, 2, policy.DueSteps(openedAt, openedAt.Add(15*time.Minute)))
+	assert.Equal(t, 3, policy.DueSteps(openedAt, openedAt.Add(2*time.Hour)))
+
+	targets := config.EscalationTargets(policy.Steps[1:])
+	assert.Equal(t, []string{"lead", "oncall", "cto"}, targetNames(targets))
+	assert.Equal(t, Addresses{"lead@example.com"}, targets[0].SendTo)
+}
+
+func TestEscalationPolicyCheck(t *testing.T) {
+	_, issues := CheckConfig([]byte(`
+notification_targets:
+  oncall:
+    send_to: oncall@example.com
+escalation_policies:
+  backend:
+    steps:
+      - after: 1h
+        targets: [oncall]
+      - after: 15m
+        targets: [pager]
+      - after: 2h
+        notify: [oncall]
+  empty:
+services:
+  api:
+    escalation: frontend
+alert_repeat_interval: 0s
+`), "")
+	require.Equal(t, []ConfigIssue{
+		{Line: 10, Message: `escalation policy "backend" step 1 must not come before the previous step`},
+		{Line: 11, Message: `escalation policy "backend" step 1 unknown notification target "pager"`},
+		{Line: 12, Message: `escalation policy "backend" step 2 requires targets`},
+		{Line: 13, Message: `unknown key "escalation_policies.backend.steps[2].notify"`},
+		{Line: 14, Message: `escalation policy "empty" requires steps`},
+		{Line: 17, Message: `[api] unknown escalation policy "frontend"`},
+		{Line: 18, Message: "alert_repeat_interval must be positive, got 0s"},
+	}, issues)
+}
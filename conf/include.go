@@ -0,0 +1,144 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+
+	"github.com/davidmasek/beacon/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// Contents of a file from Config.Include
+type includedFile struct {
+	Services ServicesList `yaml:"services"`
+}
+
+// Files matching Include patterns, sorted and without duplicates.
+// Relative patterns are relative to baseDir (working directory if empty).
+func (config *Config) IncludedFiles(baseDir string) ([]string, error) {
+	files := []string{}
+	for _, pattern := range config.Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(baseDir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+		}
+		files = append(files, matches...)
+	}
+	slices.Sort(files)
+	return slices.Compact(files), nil
+}
+
+// Read services from included file. Templates (can be nil) are applied to the services.
+// Sources (where each service id is defined) are used to report duplicates
+// and are updated with services of this file.
+//
+// Returns problems found, see CheckConfig, and services of the file if there are no errors.
+func readIncludedFile(path string, templates *ServiceTemplates, sources map[string]string) ([]ConfigIssue, []ServiceConfig) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []ConfigIssue{{Message: err.Error()}}, nil
+	}
+	root := &yaml.Node{}
+	err = yaml.Unmarshal(data, root)
+	if err != nil {
+		return issuesFromError(err, 0), nil
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+	issues := interpolateEnvIssues(root)
+	doc := root.Content[0]
+	for _, issue := range removeUnknownKeys(doc, reflect.TypeOf(includedFile{}), "") {
+		issue.Message += ", only services can be included"
+		issues = append(issues, issue)
+	}
+	for id, line := range serviceLines(doc) {
+		if source, ok := sources[id]; ok {
+			issues = append(issues, ConfigIssue{
+				Line:    line,
+				Message: fmt.Sprintf("[%s] duplicate service id, already defined in %s", id, source),
+			})
+			continue
+		}
+		sources[id] = path
+	}
+	serviceIssues, ok := checkServices(doc, templates)
+	issues = append(issues, serviceIssues...)
+	if !ok || hasErrors(issues) {
+		return issues, nil
+	}
+	file := includedFile{Services: ServicesList{templates: templates}}
+	err = doc.Decode(&file)
+	if err != nil {
+		return append(issues, issuesFromError(err, 0)...), nil
+	}
+	for _, service := range file.Services.Services {
+		issues = append(issues, validationIssues(service.Validate(), doc, "")...)
+	}
+	if hasErrors(issues) {
+		return issues, nil
+	}
+	return issues, file.Services.Services
+}
+
+// Lines where services are defined in the document, by service id
+func serviceLines(doc *yaml.Node) map[string]int {
+	lines := map[string]int{}
+	servicesNode := mappingValue(doc, "services")
+	if servicesNode == nil || servicesNode.Kind != yaml.MappingNode {
+		return lines
+	}
+	for i := 0; i+1 < len(servicesNode.Content); i += 2 {
+		lines[servicesNode.Content[i].Value] = servicesNode.Content[i].Line
+	}
+	return lines
+}
+
+// Add services from the files, skipping files with errors.
+// Returns problems of all files, with File set.
+func (config *Config) loadIncludedFiles(files []string) []ConfigIssue {
+	sources := map[string]string{}
+	for _, service := range config.AllServices() {
+		sources[service.Id] = "main config"
+	}
+	issues := []ConfigIssue{}
+	for _, file := range files {
+		fileIssues, services := readIncludedFile(file, &config.ServiceTemplates, sources)
+		for i := range fileIssues {
+			fileIssues[i].File = file
+		}
+		issues = append(issues, fileIssues...)
+		config.Services.Services = append(config.Services.Services, services...)
+	}
+	return issues
+}
+
+// Add services from included files.
+// Errors of all files are returned, prefixed with the file path. Warnings are logged.
+func (config *Config) includeServices(baseDir string) error {
+	logger := logging.Get()
+	files, err := config.IncludedFiles(baseDir)
+	if err != nil {
+		return err
+	}
+	errs := []error{}
+	for _, issue := range config.loadIncludedFiles(files) {
+		if issue.Warning {
+			logger.Warnw("Problem in included config file", "file", issue.File, "line", issue.Line, "problem", issue.Message)
+			continue
+		}
+		if issue.Line == 0 {
+			errs = append(errs, fmt.Errorf("%s: %s", issue.File, issue.Message))
+			continue
+		}
+		errs = append(errs, fmt.Errorf("%s: line %d: %s", issue.File, issue.Line, issue.Message))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,89 @@
+package conf
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncludeServices(t *testing.T) {
+	dir := t.TempDir()
+	err := os.Mkdir(filepath.Join(dir, "services.d"), 0755)
+	require.NoError(t, err)
+	path := filepath.Join(dir, "beacon.yaml")
+	start := time.Now()
+	writeTestConfig(t, path, `
+include: [services.d/*.yaml]
+defaults:
+  timeout: 2h
+services:
+  main-service:
+`, start)
+	writeTestConfig(t, filepath.Join(dir, "services.d", "a.yaml"), `
+services:
+  team-a:
+    url: https://example.com
+`, start)
+	writeTestConfig(t, filepath.Join(dir, "services.d", "b.yaml"), `
+services:
+  team-b:
+    timeout: 30m
+`, start)
+
+	config, err := DefaultConfigFrom(path)
+	require.NoError(t, err)
+	ids := []string{}
+	for _, service := range config.AllServices() {
+		ids = append(ids, service.Id)
+	}
+	require.Equal(t, []string{"main-service", "team-a", "team-b"}, ids)
+	require.Equal(t, 2*time.Hour, config.Services.Get("team-a").Timeout, "defaults applied")
+	require.Equal(t, 30*time.Minute, config.Services.Get("team-b").Timeout)
+
+	checked, issues := CheckConfig([]byte("include: [services.d/*.yaml]\n"), dir)
+	require.Empty(t, issues)
+	require.Len(t, checked.AllServices(), 2)
+
+	// duplicate ids are reported with file and line
+	bad := filepath.Join(dir, "services.d", "c.yaml")
+	writeTestConfig(t, bad, "services:\n  other:\n  team-a:\n", start)
+	_, err = DefaultConfigFrom(path)
+	require.ErrorContains(t, err, bad+": line 3: [team-a] duplicate service id, already defined in "+filepath.Join(dir, "services.d", "a.yaml"))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	_, issues = CheckConfig(data, dir)
+	require.Len(t, issues, 1)
+	require.Equal(t, bad, issues[0].File)
+	require.Equal(t, 3, issues[0].Line)
+
+	// only services can be included
+	writeTestConfig(t, bad, "port: 9000\nservices:\n  other:\n", start)
+	_, err = DefaultConfigFrom(path)
+	require.ErrorContains(t, err, bad+": line 1: ")
+	require.ErrorContains(t, err, "only services can be included")
+}
+
+func TestLiveConfigWatchIncluded(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "beacon.yaml")
+	start := time.Now()
+	writeTestConfig(t, path, "include: ['*.services.yaml']\n", start)
+	config, err := DefaultConfigFrom(path)
+	require.NoError(t, err)
+	require.Empty(t, config.AllServices())
+	live := NewLiveConfig(path, config, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go live.Watch(ctx, 10*time.Millisecond)
+
+	// new file matching the pattern
+	writeTestConfig(t, filepath.Join(dir, "a.services.yaml"), "services:\n  a:\n", start)
+	require.Eventually(t, func() bool {
+		return live.Get().Services.Get("a") != nil
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,138 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/davidmasek/beacon/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// Env variable that makes references to undefined variables in config an error.
+// By default they are replaced with empty string and a warning is logged.
+const STRICT_ENV_VAR = ENV_VAR_PREFIX + "STRICT_ENV"
+
+// ${VAR}, ${VAR:-default} or escaped $${ (written as literal ${)
+var variablePattern = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}\n]*))?\}`)
+
+// Reference to variable that is not set and has no default
+type undefinedVariable struct {
+	Name string
+	Line int
+}
+
+func (v undefinedVariable) issue(strict bool) ConfigIssue {
+	return ConfigIssue{
+		Line:    v.Line,
+		Warning: !strict,
+		Message: fmt.Sprintf("undefined environment variable %q", v.Name),
+	}
+}
+
+// Replace ${VAR} and ${VAR:-default} in value with values from lookup.
+// The default is used when the variable is unset or empty.
+// Undefined variables without default are replaced with empty string and returned.
+func interpolate(value string, lookup func(string) (string, bool)) (string, []string) {
+	undefined := []string{}
+	out := variablePattern.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+		groups := variablePattern.FindStringSubmatch(match)
+		name, fallback := groups[1], groups[2]
+		value, ok := lookup(name)
+		if value == "" && strings.Contains(match, ":-") {
+			value = fallback
+			ok = true
+		}
+		if !ok {
+			undefined = append(undefined, name)
+		}
+		return value
+	})
+	return out, undefined
+}
+
+// Interpolate scalar values in parsed YAML, see interpolate.
+// Keys and comments are left as they are. Values are used as they are,
+// without being parsed as YAML, so they cannot change the document structure.
+func interpolateNode(node *yaml.Node, lookup func(string) (string, bool)) []undefinedVariable {
+	undefined := []undefinedVariable{}
+	if node == nil {
+		return undefined
+	}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		value, names := interpolate(node.Value, lookup)
+		for _, name := range names {
+			undefined = append(undefined, undefinedVariable{name, node.Line})
+		}
+		if value != node.Value {
+			node.Value = value
+			if node.Style == 0 {
+				// resolve type from the new value, so that e.g. `port: ${PORT}` is a number
+				node.Tag = ""
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			undefined = append(undefined, interpolateNode(node.Content[i+1], lookup)...)
+		}
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			undefined = append(undefined, interpolateNode(child, lookup)...)
+		}
+	}
+	return undefined
+}
+
+// Whether undefined variables are errors, see STRICT_ENV_VAR
+func strictEnv() (bool, error) {
+	value := os.Getenv(STRICT_ENV_VAR)
+	if value == "" {
+		return false, nil
+	}
+	strict, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s value %q, expected true or false", STRICT_ENV_VAR, value)
+	}
+	return strict, nil
+}
+
+// Interpolate env variables in parsed config.
+// Undefined variables are logged, or returned as error in strict mode.
+func interpolateEnv(root *yaml.Node) error {
+	logger := logging.Get()
+	strict, err := strictEnv()
+	if err != nil {
+		return err
+	}
+	undefined := interpolateNode(root, os.LookupEnv)
+	errs := []error{}
+	for _, variable := range undefined {
+		if strict {
+			errs = append(errs, fmt.Errorf("line %d: undefined environment variable %q", variable.Line, variable.Name))
+			continue
+		}
+		logger.Warnw("Undefined environment variable in config, using empty value", "variable", variable.Name, "line", variable.Line)
+	}
+	return errors.Join(errs...)
+}
+
+// Interpolate env variables in parsed config for CheckConfig.
+// Undefined variables are reported as warnings, or errors in strict mode.
+func interpolateEnvIssues(root *yaml.Node) []ConfigIssue {
+	strict, err := strictEnv()
+	if err != nil {
+		return []ConfigIssue{{Message: err.Error()}}
+	}
+	issues := []ConfigIssue{}
+	for _, variable := range interpolateNode(root, os.LookupEnv) {
+		issues = append(issues, variable.issue(strict))
+	}
+	return issues
+}
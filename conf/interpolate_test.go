@@ -0,0 +1,78 @@
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestInterpolate(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		values := map[string]string{"HOST": "example.com", "EMPTY": "", "PORT": "8080", "NOTE": "a: b # c"}
+		value, ok := values[name]
+		return value, ok
+	}
+	root := &yaml.Node{}
+	err := yaml.Unmarshal([]byte(`
+# set ${COMMENTED} in production
+url: https://${HOST}/health
+timeout: ${TIMEOUT:-2h}
+port: ${PORT}
+empty: "${EMPTY:-default}"
+missing: "${MISSING}"
+literal: "$${HOST} $HOST ${not valid}"
+note: ${NOTE}
+list:
+  - ${HOST}
+  - "${PORT}"
+${HOST}: key
+`), root)
+	require.NoError(t, err)
+	undefined := interpolateNode(root, lookup)
+	require.Equal(t, []undefinedVariable{{"MISSING", 7}}, undefined)
+
+	values := map[string]any{}
+	err = root.Decode(&values)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"url":     "https://example.com/health",
+		"timeout": "2h",
+		"port":    8080,
+		"empty":   "default",
+		"missing": "",
+		"literal": "${HOST} $HOST ${not valid}",
+		"note":    "a: b # c",
+		"list":    []any{"example.com", "8080"},
+		"${HOST}": "key",
+	}, values)
+}
+
+func TestConfigEnvInterpolation(t *testing.T) {
+	t.Setenv("SITE_HOST", "staging.example.com")
+	config, err := ConfigFromBytes([]byte(`
+services:
+  site:
+    url: https://${SITE_HOST}
+    timeout: ${SITE_TIMEOUT:-30m}
+    token: ${SITE_TOKEN}
+`))
+	require.NoError(t, err)
+	site := config.Services.Get("site")
+	require.Equal(t, "https://staging.example.com", site.Url)
+	require.Equal(t, "30m0s", site.Timeout.String())
+	require.False(t, site.Token.IsSet())
+
+	t.Setenv(STRICT_ENV_VAR, "true")
+	_, err = ConfigFromBytes([]byte("# token: ${SITE_TOKEN}\nservices:\n  site:\n"))
+	require.NoError(t, err, "comments are not interpolated")
+	_, err = ConfigFromBytes([]byte("services:\n  site:\n    token: ${SITE_TOKEN}\n"))
+	require.ErrorContains(t, err, `line 3: undefined environment variable "SITE_TOKEN"`)
+	_, issues := CheckConfig([]byte("services:\n  site:\n    token: ${SITE_TOKEN}\n"), "")
+	require.Equal(t, []ConfigIssue{{Line: 3, Message: `undefined environment variable "SITE_TOKEN"`}}, issues)
+
+	t.Setenv(STRICT_ENV_VAR, "false")
+	config, issues = CheckConfig([]byte("services:\n  site:\n    token: ${SITE_TOKEN}\n"), "")
+	require.NotNil(t, config)
+	require.Equal(t, []ConfigIssue{{Line: 3, Warning: true, Message: `undefined environment variable "SITE_TOKEN"`}}, issues)
+}
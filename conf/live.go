@@ -0,0 +1,170 @@
+package conf
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/davidmasek/beacon/logging"
+	"go.uber.org/zap"
+)
+
+// How often to check config file for changes, see LiveConfig.Watch
+const CONFIG_WATCH_INTERVAL = 5 * time.Second
+
+// Config that can be reloaded from file while Beacon is running.
+//
+// Components should call Get whenever they need config values
+// instead of keeping the returned config for a long time.
+type LiveConfig struct {
+	path string
+	// applied to each loaded config, e.g. to keep CLI flags over file values
+	override func(*Config)
+	current  atomic.Pointer[Config]
+
+	// serializes reloads
+	mu sync.Mutex
+	// state of config file and included files at last reload, see filesFingerprint
+	fingerprint string
+	listeners   []func(old *Config, new *Config)
+}
+
+// Create live config for file `path`, starting with already loaded `config`.
+// Override (can be nil) is applied to each config loaded later.
+func NewLiveConfig(path string, config *Config, override func(*Config)) *LiveConfig {
+	live := &LiveConfig{path: path, override: override}
+	live.current.Store(config)
+	live.fingerprint, _ = filesFingerprint(path, config)
+	return live
+}
+
+// Current config. Returned config must not be modified.
+func (live *LiveConfig) Get() *Config {
+	return live.current.Load()
+}
+
+// Register function called after each successful reload
+func (live *LiveConfig) OnReload(listener func(old *Config, new *Config)) {
+	live.mu.Lock()
+	defer live.mu.Unlock()
+	live.listeners = append(live.listeners, listener)
+}
+
+// Read, parse and validate the config file and make it current.
+// Keeps the current config and returns error if the new one is invalid.
+func (live *LiveConfig) Reload() error {
+	live.mu.Lock()
+	defer live.mu.Unlock()
+	// remember the files even if invalid, to not reload them again until changed
+	defer func() {
+		live.fingerprint, _ = filesFingerprint(live.path, live.current.Load())
+	}()
+	config, err := configFromFile(live.path)
+	if err != nil {
+		return fmt.Errorf("cannot load config %q: %w", live.path, err)
+	}
+	if live.override != nil {
+		live.override(config)
+	}
+	err = config.Validate()
+	if err != nil {
+		return fmt.Errorf("invalid config %q: %w", live.path, err)
+	}
+	old := live.current.Swap(config)
+	for _, listener := range live.listeners {
+		listener(old, config)
+	}
+	return nil
+}
+
+// Modification times and sizes of config file and files included by config.
+// Include patterns are evaluated again, so added and removed files change the result.
+func filesFingerprint(path string, config *Config) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	var fingerprint strings.Builder
+	fmt.Fprintf(&fingerprint, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+	files, err := config.IncludedFiles(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&fingerprint, "%s %d %d\n", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return fingerprint.String(), nil
+}
+
+// Reload config when the file or included files change, until ctx is done.
+// Invalid config is logged and the previous config kept.
+func (live *LiveConfig) Watch(ctx context.Context, interval time.Duration) {
+	logger := logging.Get()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fingerprint, err := filesFingerprint(live.path, live.Get())
+			if err != nil {
+				logger.Errorw("Cannot check config file", "path", live.path, zap.Error(err))
+				continue
+			}
+			live.mu.Lock()
+			changed := fingerprint != live.fingerprint
+			live.mu.Unlock()
+			if !changed {
+				continue
+			}
+			logger.Infow("Config file changed, reloading", "path", live.path)
+			live.ReloadAndLog()
+		}
+	}
+}
+
+// Reload and log the result instead of returning error
+func (live *LiveConfig) ReloadAndLog() {
+	logger := logging.Get()
+	err := live.Reload()
+	if err != nil {
+		logger.Errorw("Config reload failed, keeping previous config", zap.Error(err))
+		return
+	}
+	logger.Infow("Config reloaded", "path", live.path)
+}
+
+// Settings that are read only on startup, changing them requires restart.
+// Returns names of such settings that differ between old and new.
+func RestartRequiredChanges(old *Config, new *Config) []string {
+	changed := []string{}
+	if old.Port != new.Port {
+		changed = append(changed, "port")
+	}
+	if old.HeartbeatPort != new.HeartbeatPort {
+		changed = append(changed, "heartbeat_port")
+	}
+	if old.Tls != new.Tls {
+		changed = append(changed, "tls")
+	}
+	if old.DbPath != new.DbPath {
+		changed = append(changed, "db_path")
+	}
+	if old.RabbitConn != new.RabbitConn {
+		changed = append(changed, "rabbit_conn")
+	}
+	if old.SchedulerPeriod != new.SchedulerPeriod {
+		changed = append(changed, "scheduler_period")
+	}
+	return changed
+}
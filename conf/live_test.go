@@ -0,0 +1,72 @@
+package conf
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestConfig(t *testing.T, path string, data string, modTime time.Time) {
+	err := os.WriteFile(path, []byte(data), 0644)
+	require.NoError(t, err)
+	// make sure the change is visible even on filesystems with coarse timestamps
+	err = os.Chtimes(path, modTime, modTime)
+	require.NoError(t, err)
+}
+
+func TestLiveConfigReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "beacon.yaml")
+	start := time.Now()
+	writeTestConfig(t, path, "port: 8000\nreport_time: 10\n", start)
+	config, err := DefaultConfigFrom(path)
+	require.NoError(t, err)
+	live := NewLiveConfig(path, config, func(c *Config) {
+		c.Port = 9000
+	})
+	reloads := 0
+	live.OnReload(func(old *Config, new *Config) {
+		reloads++
+		require.Equal(t, config, old)
+		require.Equal(t, []string{"port"}, RestartRequiredChanges(old, new))
+	})
+
+	writeTestConfig(t, path, "port: 8000\nreport_time: 12\n", start.Add(time.Second))
+	err = live.Reload()
+	require.NoError(t, err)
+	require.Equal(t, 1, reloads)
+	require.Equal(t, 12, live.Get().ReportAfter)
+	require.Equal(t, 9000, live.Get().Port, "override applied")
+	current := live.Get()
+
+	// invalid config keeps the previous one
+	writeTestConfig(t, path, "report_time: 30\n", start.Add(2*time.Second))
+	err = live.Reload()
+	require.ErrorContains(t, err, "report_time must be between 0 and 23")
+	writeTestConfig(t, path, "report_time: [\n", start.Add(3*time.Second))
+	err = live.Reload()
+	require.ErrorContains(t, err, "cannot load config")
+	require.Same(t, current, live.Get())
+	require.Equal(t, 1, reloads)
+}
+
+func TestLiveConfigWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "beacon.yaml")
+	start := time.Now()
+	writeTestConfig(t, path, "report_time: 10\n", start)
+	config, err := DefaultConfigFrom(path)
+	require.NoError(t, err)
+	live := NewLiveConfig(path, config, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go live.Watch(ctx, 10*time.Millisecond)
+
+	writeTestConfig(t, path, "report_time: 12\n", start.Add(time.Second))
+	require.Eventually(t, func() bool {
+		return live.Get().ReportAfter == 12
+	}, time.Second, 10*time.Millisecond)
+}
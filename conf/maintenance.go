@@ -0,0 +1,159 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Format of MaintenanceWindow.At
+const TIME_OF_DAY_FORMAT = "15:04"
+
+// Planned maintenance of services, during which failures are expected.
+// Failure notifications are not sent and the time does not count for uptime.
+//
+// One-off window lasts from Start until End. Recurring window starts at At
+// on each of the Days (every day if empty) and lasts for Duration.
+type MaintenanceWindow struct {
+	// Identifier of windows stored in DB (created using CLI or API), 0 for windows from config
+	Id     int    `yaml:"-"`
+	Reason string `yaml:"reason"`
+	// Services with these ids
+	Services []string `yaml:"services"`
+	// Services with any of these tags
+	Tags []string `yaml:"tags"`
+	// One-off window
+	Start time.Time `yaml:"start,omitempty"`
+	End   time.Time `yaml:"end,omitempty"`
+	// Recurring window, At is time of day (such as 02:30) in the configured timezone
+	Days     WeekdaysSet   `yaml:"days"`
+	At       string        `yaml:"at"`
+	Duration time.Duration `yaml:"duration"`
+}
+
+func (window *MaintenanceWindow) IsRecurring() bool {
+	return window.At != "" || window.Duration != 0 || !window.Days.IsEmpty()
+}
+
+// Check if the window selects the service
+func (window *MaintenanceWindow) Matches(service *ServiceConfig) bool {
+	return slices.Contains(window.Services, service.Id) || slices.ContainsFunc(window.Tags, service.HasTag)
+}
+
+// Check if the window overlaps [from, to).
+// Recurring windows are evaluated in the given location.
+func (window *MaintenanceWindow) Overlaps(from, to time.Time, loc *time.Location) bool {
+	if !window.IsRecurring() {
+		return window.Start.Before(to) && window.End.After(from)
+	}
+	at, err := time.Parse(TIME_OF_DAY_FORMAT, window.At)
+	if err != nil {
+		return false
+	}
+	// occurrence that started before `from` might still be in progress
+	day := from.Add(-window.Duration).In(loc)
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		if !window.Days.IsEmpty() && !window.Days.Contains(day) {
+			continue
+		}
+		start := time.Date(day.Year(), day.Month(), day.Day(), at.Hour(), at.Minute(), 0, 0, loc)
+		if start.Before(to) && start.Add(window.Duration).After(from) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if the window is in progress at given time
+func (window *MaintenanceWindow) IsActive(now time.Time, loc *time.Location) bool {
+	return window.Overlaps(now, now.Add(time.Nanosecond), loc)
+}
+
+// End of the window occurrence in progress at given time,
+// false if the window is not active.
+func (window *MaintenanceWindow) ActiveUntil(now time.Time, loc *time.Location) (time.Time, bool) {
+	if !window.IsRecurring() {
+		return window.End, window.IsActive(now, loc)
+	}
+	at, err := time.Parse(TIME_OF_DAY_FORMAT, window.At)
+	if err != nil {
+		return time.Time{}, false
+	}
+	until := time.Time{}
+	day := now.Add(-window.Duration).In(loc)
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	for ; !day.After(now); day = day.AddDate(0, 0, 1) {
+		if !window.Days.IsEmpty() && !window.Days.Contains(day) {
+			continue
+		}
+		start := time.Date(day.Year(), day.Month(), day.Day(), at.Hour(), at.Minute(), 0, 0, loc)
+		end := start.Add(window.Duration)
+		if !start.After(now) && end.After(now) && end.After(until) {
+			until = end
+		}
+	}
+	return until, !until.IsZero()
+}
+
+// Human-readable schedule, such as "Sat Sun at 02:00 for 2h"
+func (window *MaintenanceWindow) Schedule(loc *time.Location) string {
+	if !window.IsRecurring() {
+		format := "2006-01-02 15:04"
+		return fmt.Sprintf("%s - %s", window.Start.In(loc).Format(format), window.End.In(loc).Format(format))
+	}
+	days := "every day"
+	if !window.Days.IsEmpty() {
+		days = window.Days.String()
+	}
+	return fmt.Sprintf("%s at %s for %s", days, window.At, FormatDuration(window.Duration))
+}
+
+// Check the window definition, see Config.Validate.
+// Keys of returned KeyErrors are relative to the window, such as "end".
+func (window *MaintenanceWindow) Validate() error {
+	return errors.Join(window.validate(func(field string) string { return field }, "")...)
+}
+
+// Returned errors use key(field) as key and have the given message prefix
+func (window *MaintenanceWindow) validate(key func(field string) string, prefix string) []error {
+	errs := []error{}
+	if len(window.Services) == 0 && len(window.Tags) == 0 {
+		errs = append(errs, keyErrorf(key("services"), "%smaintenance window requires services or tags", prefix))
+	}
+	oneOff := !window.Start.IsZero() || !window.End.IsZero()
+	if oneOff && window.IsRecurring() {
+		return append(errs, keyErrorf(key("start"), "%suse either start and end, or at and duration, not both", prefix))
+	}
+	if !oneOff && !window.IsRecurring() {
+		return append(errs, keyErrorf(key("start"), "%smaintenance window requires start and end, or at and duration", prefix))
+	}
+	if oneOff {
+		if window.Start.IsZero() || window.End.IsZero() {
+			errs = append(errs, keyErrorf(key("end"), "%smaintenance window requires both start and end", prefix))
+		} else if !window.End.After(window.Start) {
+			errs = append(errs, keyErrorf(key("end"), "%send must be after start", prefix))
+		}
+		return errs
+	}
+	if _, err := time.Parse(TIME_OF_DAY_FORMAT, window.At); err != nil {
+		errs = append(errs, keyErrorf(key("at"), "%sat must be time of day such as 02:30, got %q", prefix, window.At))
+	}
+	if window.Duration <= 0 || window.Duration > DAYS_IN_WEEK*24*time.Hour {
+		errs = append(errs, keyErrorf(key("duration"), "%sduration must be positive and at most 7d, got %s", prefix, window.Duration))
+	}
+	return errs
+}
+
+// See Config.Validate
+func (config *Config) validateMaintenance() []error {
+	errs := []error{}
+	for i, window := range config.Maintenance {
+		key := func(field string) string {
+			return fmt.Sprintf("maintenance.%d.%s", i, field)
+		}
+		errs = append(errs, window.validate(key, fmt.Sprintf("maintenance[%d] ", i))...)
+	}
+	return errs
+}
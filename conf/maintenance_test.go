@@ -0,0 +1,99 @@
+package conf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceWindowOverlaps(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Prague")
+	require.NoError(t, err)
+	config, err := ConfigFromBytes([]byte(`
+maintenance:
+  - services: [api]
+    reason: Database upgrade
+    start: 2025-03-01T02:00:00+01:00
+    end: 2025-03-01T04:00:00+01:00
+  - tags: [db]
+    days: Sat Sun
+    at: "23:30"
+    duration: 1h
+`))
+	require.NoError(t, err)
+	require.NoError(t, config.Validate())
+	require.Len(t, config.Maintenance, 2)
+	oneOff, recurring := config.Maintenance[0], config.Maintenance[1]
+	assert.False(t, oneOff.IsRecurring())
+	assert.True(t, recurring.IsRecurring())
+
+	at := func(value string) time.Time {
+		parsed, err := time.ParseInLocation(time.DateTime, value, loc)
+		require.NoError(t, err)
+		return parsed
+	}
+	assert.True(t, oneOff.IsActive(at("2025-03-01 02:00:00"), loc))
+	assert.True(t, oneOff.IsActive(at("2025-03-01 03:59:00"), loc))
+	assert.False(t, oneOff.IsActive(at("2025-03-01 04:00:00"), loc))
+	assert.True(t, oneOff.Overlaps(at("2025-03-01 01:30:00"), at("2025-03-01 02:30:00"), loc))
+	assert.False(t, oneOff.Overlaps(at("2025-03-01 01:30:00"), at("2025-03-01 02:00:00"), loc))
+
+	// 2025-03-01 is Saturday
+	assert.True(t, recurring.IsActive(at("2025-03-01 23:45:00"), loc))
+	// continues after midnight
+	assert.True(t, recurring.IsActive(at("2025-03-02 00:15:00"), loc))
+	assert.False(t, recurring.IsActive(at("2025-03-02 00:30:00"), loc))
+	// Friday
+	assert.False(t, recurring.IsActive(at("2025-02-28 23:45:00"), loc))
+	// Sunday night into Monday
+	assert.True(t, recurring.IsActive(at("2025-03-03 00:00:00"), loc))
+	// evaluated in the given timezone
+	assert.False(t, recurring.IsActive(at("2025-03-01 23:45:00"), time.UTC))
+
+	until, ok := oneOff.ActiveUntil(at("2025-03-01 03:00:00"), loc)
+	assert.True(t, ok)
+	assert.True(t, at("2025-03-01 04:00:00").Equal(until))
+	until, ok = recurring.ActiveUntil(at("2025-03-01 23:45:00"), loc)
+	assert.True(t, ok)
+	assert.True(t, at("2025-03-02 00:30:00").Equal(until))
+	_, ok = recurring.ActiveUntil(at("2025-03-02 00:30:00"), loc)
+	assert.False(t, ok)
+
+	assert.Equal(t, "2025-03-01 02:00 - 2025-03-01 04:00", oneOff.Schedule(loc))
+	assert.Equal(t, "Sunday Saturday at 23:30 for 1h", recurring.Schedule(loc))
+
+	api := ServiceConfig{Id: "api"}
+	postgres := ServiceConfig{Id: "postgres", Tags: []string{"db"}}
+	assert.True(t, oneOff.Matches(&api))
+	assert.False(t, oneOff.Matches(&postgres))
+	assert.True(t, recurring.Matches(&postgres))
+}
+
+func TestMaintenanceWindowCheck(t *testing.T) {
+	data := []byte(`
+maintenance:
+  - start: 2025-03-01T04:00:00Z
+    end: 2025-03-01T02:00:00Z
+  - services: [api]
+    at: "25:00"
+    duration: 0s
+  - services: [api]
+    start: 2025-03-01T04:00:00Z
+    at: "02:00"
+  - services: [api]
+    reason: nothing else
+`)
+	_, issues := CheckConfig(data, "")
+	messages := map[int]string{}
+	for _, issue := range issues {
+		messages[issue.Line] += issue.Message + "\n"
+	}
+	assert.Contains(t, messages[3], "maintenance[0] maintenance window requires services or tags")
+	assert.Contains(t, messages[4], "maintenance[0] end must be after start")
+	assert.Contains(t, messages[6], `maintenance[1] at must be time of day such as 02:30, got "25:00"`)
+	assert.Contains(t, messages[7], "maintenance[1] duration must be positive")
+	assert.Contains(t, messages[9], "maintenance[2] use either start and end, or at and duration, not both")
+	assert.Contains(t, messages[11], "maintenance[3] maintenance window requires start and end, or at and duration")
+}
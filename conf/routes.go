@@ -0,0 +1,176 @@
+package conf
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Severity of a notification, see AlertRoute.Severities
+const (
+	// Service failed
+	SEVERITY_CRITICAL = "critical"
+	// Service status is not OK, but it did not fail either (e.g. unknown status)
+	SEVERITY_WARNING = "warning"
+	// Summary report
+	SEVERITY_INFO = "info"
+)
+
+var SEVERITIES = []string{SEVERITY_CRITICAL, SEVERITY_WARNING, SEVERITY_INFO}
+
+// Recipient of notifications, defined in config under a name
+// and referenced by AlertRoute.Targets
+type NotificationTarget struct {
+	// Name in config, or the address for targets given directly by send_to
+	Name string `yaml:"-"`
+	// Email recipients
+	SendTo Addresses `yaml:"send_to"`
+	Cc     Addresses `yaml:"cc"`
+	Bcc    Addresses `yaml:"bcc"`
+}
+
+// Email settings for sending to this target, based on the global email settings.
+// Recipients (including cc and bcc) are replaced by the target's.
+func (target *NotificationTarget) EmailConfig(base EmailConfig) EmailConfig {
+	base.SendTo = target.SendTo
+	base.Cc = target.Cc
+	base.Bcc = target.Bcc
+	return base
+}
+
+// Rule sending notifications of matching services to given targets
+// instead of EmailConfig.SendTo.
+//
+// Route without services, tags and groups matches all services.
+// Route with any of them matches services selected by at least one.
+type AlertRoute struct {
+	// Services with these ids
+	Services []string `yaml:"services"`
+	// Services with any of these tags
+	Tags []string `yaml:"tags"`
+	// Services in these groups
+	Groups []string `yaml:"groups"`
+	// Notification severities, all if empty. See SEVERITIES.
+	Severities []string `yaml:"severities"`
+	// Names from Config.NotificationTargets
+	Targets []string `yaml:"targets"`
+	// Recipients, shorthand for a target with only send_to
+	SendTo Addresses `yaml:"send_to"`
+	// Used only for services not matched by any other route
+	Default bool `yaml:"default"`
+}
+
+// Check if the route selects the service, ignoring severities
+func (route *AlertRoute) MatchesService(service *ServiceConfig) bool {
+	if len(route.Services) == 0 && len(route.Tags) == 0 && len(route.Groups) == 0 {
+		return true
+	}
+	return slices.Contains(route.Services, service.Id) ||
+		slices.ContainsFunc(route.Tags, service.HasTag) ||
+		(service.Group != "" && slices.Contains(route.Groups, service.Group))
+}
+
+func (route *AlertRoute) Matches(service *ServiceConfig, severity string) bool {
+	if len(route.Severities) > 0 && !slices.Contains(route.Severities, severity) {
+		return false
+	}
+	return route.MatchesService(service)
+}
+
+// Targets of the route, in order, with send_to last
+func (config *Config) routeTargets(route *AlertRoute) []NotificationTarget {
+	targets := []NotificationTarget{}
+	for _, name := range route.Targets {
+		target := config.NotificationTargets[name]
+		target.Name = name
+		targets = append(targets, target)
+	}
+	if len(route.SendTo) > 0 {
+		targets = append(targets, NotificationTarget{Name: route.SendTo.String(), SendTo: route.SendTo})
+	}
+	return targets
+}
+
+// Targets of notification with given severity about the service.
+//
+// All matching routes are used. If none matches, the default route is used,
+// or EmailConfig.SendTo if there is no default route or it does not match the severity.
+func (config *Config) AlertTargets(service *ServiceConfig, severity string) []NotificationTarget {
+	targets := []NotificationTarget{}
+	add := func(route *AlertRoute) {
+		for _, target := range config.routeTargets(route) {
+			if !slices.ContainsFunc(targets, func(t NotificationTarget) bool { return t.Name == target.Name }) {
+				targets = append(targets, target)
+			}
+		}
+	}
+	var defaultRoute *AlertRoute
+	matched := false
+	for i := range config.AlertRoutes {
+		route := &config.AlertRoutes[i]
+		if route.Default {
+			defaultRoute = route
+			continue
+		}
+		if route.Matches(service, severity) {
+			add(route)
+			matched = true
+		}
+	}
+	if matched {
+		return targets
+	}
+	if defaultRoute != nil && defaultRoute.Matches(service, severity) {
+		add(defaultRoute)
+		return targets
+	}
+	email := config.EmailConf
+	return []NotificationTarget{{Name: email.SendTo.String(), SendTo: email.SendTo, Cc: email.Cc, Bcc: email.Bcc}}
+}
+
+// See Config.Validate
+func (config *Config) validateRoutes() []error {
+	errs := []error{}
+	for _, name := range slices.Sorted(maps.Keys(config.NotificationTargets)) {
+		target := config.NotificationTargets[name]
+		key := func(field string) string {
+			return fmt.Sprintf("notification_targets.%s.%s", name, field)
+		}
+		if len(target.SendTo) == 0 {
+			errs = append(errs, keyErrorf(key("send_to"), "notification target %q requires send_to", name))
+		}
+		errs = append(errs, validateAddresses(key("send_to"), target.SendTo...)...)
+		errs = append(errs, validateAddresses(key("cc"), target.Cc...)...)
+		errs = append(errs, validateAddresses(key("bcc"), target.Bcc...)...)
+	}
+	defaults := 0
+	for i, route := range config.AlertRoutes {
+		key := func(field string) string {
+			return fmt.Sprintf("alert_routes.%d.%s", i, field)
+		}
+		if len(route.Targets) == 0 && len(route.SendTo) == 0 {
+			errs = append(errs, keyErrorf(key("targets"), "alert_routes[%d] requires targets or send_to", i))
+		}
+		errs = append(errs, validateAddresses(key("send_to"), route.SendTo...)...)
+		for _, name := range route.Targets {
+			if _, ok := config.NotificationTargets[name]; !ok {
+				errs = append(errs, keyErrorf(key("targets"), "alert_routes[%d] unknown notification target %q", i, name))
+			}
+		}
+		for _, severity := range route.Severities {
+			if !slices.Contains(SEVERITIES, severity) {
+				errs = append(errs, keyErrorf(key("severities"), "alert_routes[%d] unknown severity %q, expected one of %v", i, severity, SEVERITIES))
+			}
+		}
+		if route.Default {
+			defaults++
+			if len(route.Services) > 0 || len(route.Tags) > 0 || len(route.Groups) > 0 {
+				errs = append(errs, keyErrorf(key("default"), "alert_routes[%d] default route cannot select services", i))
+			}
+			if defaults > 1 {
+				errs = append(errs, keyErrorf(key("default"), "alert_routes[%d] only one default route is allowed", i))
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,133 @@
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func targetNames(targets []NotificationTarget) []string {
+	names := []string{}
+	for _, target := range targets {
+		names = append(names, target.Name)
+	}
+	return names
+}
+
+func TestServiceTagsAndGroups(t *testing.T) {
+	config, err := ConfigFromBytes([]byte(`
+email:
+  send_to: ops@example.com
+alert_routes:
+  - tags: [payments]
+    send_to: payments@example.com
+  - tags: [db, payments]
+    send_to: dba@example.com
+services:
+  checkout:
+    tags: [payments, frontend]
+    group: Shop
+  postgres:
+    tags: [db]
+    group: Infrastructure
+  homepage:
+`))
+	require.NoError(t, err)
+
+	checkout := config.Services.Get("checkout")
+	assert.Equal(t, []string{"payments", "frontend"}, checkout.Tags)
+	assert.Equal(t, "Shop", checkout.Group)
+	assert.True(t, checkout.HasTag("frontend"))
+	assert.False(t, checkout.HasTag("db"))
+
+	assert.Equal(t, []string{"Infrastructure", "Shop", ""}, ServiceGroups(config.AllServices()))
+
+	targets := config.AlertTargets(checkout, SEVERITY_CRITICAL)
+	assert.Equal(t, []string{"payments@example.com", "dba@example.com"}, targetNames(targets))
+	assert.Equal(t, Addresses{"payments@example.com"}, targets[0].SendTo)
+	assert.Equal(t, []string{"dba@example.com"}, targetNames(config.AlertTargets(config.Services.Get("postgres"), SEVERITY_CRITICAL)))
+	assert.Equal(t, []string{"ops@example.com"}, targetNames(config.AlertTargets(config.Services.Get("homepage"), SEVERITY_CRITICAL)))
+
+	_, err = ConfigFromBytes([]byte("services:\n  a:\n    tags: payments\n"))
+	require.ErrorContains(t, err, "[a] invalid type for field tags")
+}
+
+func TestAlertRouting(t *testing.T) {
+	config, err := ConfigFromBytes([]byte(`
+notification_targets:
+  ops:
+    send_to: ops@example.com
+  dba:
+    send_to: dba@example.com
+  frontend:
+    send_to: frontend@example.com
+  pager:
+    send_to: pager@example.com
+alert_routes:
+  - services: [postgres]
+    groups: [Databases]
+    targets: [dba]
+  - tags: [frontend]
+    targets: [frontend]
+    severities: [critical, warning]
+  - severities: [critical]
+    targets: [pager]
+  - default: true
+    targets: [ops]
+services:
+  postgres:
+  redis:
+    group: Databases
+  website:
+    tags: [frontend]
+  backup-job:
+`))
+	require.NoError(t, err)
+	service := config.Services.Get
+
+	assert.Equal(t, []string{"dba", "pager"}, targetNames(config.AlertTargets(service("postgres"), SEVERITY_CRITICAL)))
+	assert.Equal(t, []string{"dba"}, targetNames(config.AlertTargets(service("redis"), SEVERITY_WARNING)))
+	assert.Equal(t, []string{"frontend"}, targetNames(config.AlertTargets(service("website"), SEVERITY_WARNING)))
+	// default route only if nothing else matches
+	assert.Equal(t, []string{"pager"}, targetNames(config.AlertTargets(service("backup-job"), SEVERITY_CRITICAL)))
+	assert.Equal(t, []string{"ops"}, targetNames(config.AlertTargets(service("backup-job"), SEVERITY_WARNING)))
+	// summary reports
+	assert.Equal(t, []string{"ops"}, targetNames(config.AlertTargets(service("website"), SEVERITY_INFO)))
+	assert.Equal(t, []string{"dba"}, targetNames(config.AlertTargets(service("redis"), SEVERITY_INFO)))
+
+	// default route limited by severity falls back to email.send_to
+	config.AlertRoutes[3].Severities = []string{SEVERITY_CRITICAL}
+	config.EmailConf.SendTo = Addresses{"admin@example.com"}
+	assert.Equal(t, []string{"admin@example.com"}, targetNames(config.AlertTargets(service("backup-job"), SEVERITY_WARNING)))
+
+	email := config.NotificationTargets["dba"]
+	emailConf := email.EmailConfig(EmailConfig{SendTo: Addresses{"ops@example.com"}, Sender: "beacon@example.com"})
+	assert.Equal(t, Addresses{"dba@example.com"}, emailConf.SendTo)
+	assert.Equal(t, "beacon@example.com", emailConf.Sender)
+}
+
+func TestAlertRoutesCheck(t *testing.T) {
+	_, issues := CheckConfig([]byte(`
+notification_targets:
+  dba:
+    send_to: dba@example.com
+alert_routes:
+  - tags: [payments]
+    send_to: payments@example.com
+  - tags: [db]
+    sent_to: dba@example.com
+  - targets: [dba, pager]
+    severities: [urgent]
+  - default: true
+    tags: [x]
+    send_to: ops@example.com
+`), "")
+	require.Equal(t, []ConfigIssue{
+		{Line: 8, Message: "alert_routes[1] requires targets or send_to"},
+		{Line: 9, Message: `unknown key "alert_routes[1].sent_to"`},
+		{Line: 10, Message: `alert_routes[2] unknown notification target "pager"`},
+		{Line: 11, Message: `alert_routes[2] unknown severity "urgent", expected one of [critical warning info]`},
+		{Line: 12, Message: "alert_routes[3] default route cannot select services"},
+	}, issues)
+}
@@ -0,0 +1,179 @@
+package conf
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// JSON Schema of a single service, keys as in SERVICE_KEYS
+var serviceKeySchemas = map[string]map[string]any{
+	"extends": {
+		"type":        "string",
+		"description": "Name of template to take settings from, see templates",
+	},
+	"url": {
+		"type":        "string",
+		"description": "Website to check periodically. Services without url are checked by heartbeats.",
+	},
+	"status": {
+		"type":        "array",
+		"items":       map[string]any{"type": "integer", "minimum": 100, "maximum": 599},
+		"description": "Expected HTTP status codes, default [200]",
+	},
+	"content": {
+		"type":        "array",
+		"items":       map[string]any{"type": "string"},
+		"description": "Text that must be present in the response body",
+	},
+	"timeout": {
+		"type":        "string",
+		"description": "How long after the last successful check is the service considered failing, such as 24h or 30m",
+	},
+	"enabled": {
+		"type":        "boolean",
+		"description": "Disabled services are not checked, default true",
+	},
+	"public": {
+		"type":        "boolean",
+		"description": "Show on the public status page",
+	},
+	"display_name": {
+		"type":        "string",
+		"description": "Name shown on the public status page",
+	},
+	"note": {
+		"type":        "string",
+		"description": "Note shown on the public status page",
+	},
+	"token": {
+		"type":        "string",
+		"description": "Token required for heartbeats",
+	},
+	"token_file": {
+		"type":        "string",
+		"description": "File with token required for heartbeats",
+	},
+	"tags": {
+		"type":        "array",
+		"items":       map[string]any{"type": "string"},
+		"description": "Labels for filtering on the dashboard and routing alerts",
+	},
+	"group": {
+		"type":        "string",
+		"description": "Group shown as a section on the dashboard and in reports",
+	},
+	"escalation": {
+		"type":        "string",
+		"description": "Name of escalation policy used for incidents of the service",
+	},
+}
+
+var (
+	durationType    = reflect.TypeOf(time.Duration(0))
+	timeType        = reflect.TypeOf(time.Time{})
+	secretType      = reflect.TypeOf(Secret{})
+	addressesType   = reflect.TypeOf(Addresses{})
+	tzLocationType  = reflect.TypeOf(TzLocation{})
+	weekdaysSetType = reflect.TypeOf(WeekdaysSet{})
+	servicesType    = reflect.TypeOf(ServicesList{})
+	serviceInput    = reflect.TypeOf(ServiceInput{})
+)
+
+// Value can be left empty in YAML (such as `smtp_server:`), which is null in JSON Schema
+func nullable(typeName string) []string {
+	return []string{typeName, "null"}
+}
+
+func serviceSchema(keys []string) map[string]any {
+	properties := map[string]any{}
+	for _, key := range keys {
+		properties[key] = serviceKeySchemas[key]
+	}
+	return map[string]any{
+		"type":                 nullable("object"),
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+func typeSchema(t reflect.Type) map[string]any {
+	switch t {
+	case durationType:
+		return map[string]any{"type": nullable("string"), "description": "Duration such as 15m or 1h30m"}
+	case timeType:
+		return map[string]any{"type": nullable("string"), "description": "Timestamp such as 2025-03-01T02:00:00+01:00"}
+	case addressesType:
+		return map[string]any{
+			"type":        []string{"string", "array", "null"},
+			"items":       map[string]any{"type": "string"},
+			"description": "Email address, or list of addresses",
+		}
+	case secretType:
+		return map[string]any{"type": nullable("string")}
+	case tzLocationType:
+		return map[string]any{"type": nullable("string"), "description": "IANA time zone such as Europe/Prague"}
+	case weekdaysSetType:
+		return map[string]any{"type": nullable("string"), "description": "Space separated days such as Mon Tue Wed"}
+	case servicesType:
+		return map[string]any{
+			"type":                 nullable("object"),
+			"additionalProperties": serviceSchema(SERVICE_KEYS),
+			"description":          "Monitored services by id",
+		}
+	case serviceInput:
+		// used for defaults, see ServiceTemplates
+		schema := serviceSchema(defaultsKeys())
+		schema["description"] = "Settings applied to all services"
+		return schema
+	case reflect.TypeOf(map[string]ServiceInput{}):
+		return map[string]any{
+			"type":                 nullable("object"),
+			"additionalProperties": serviceSchema(SERVICE_KEYS),
+			"description":          "Named service settings, used by services with `extends: <name>`",
+		}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": nullable("boolean")}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": nullable("integer")}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": nullable("number")}
+	case reflect.Slice:
+		items := map[string]any{"type": "string"}
+		if t.Elem().Kind() == reflect.Struct {
+			items = typeSchema(t.Elem())
+		}
+		return map[string]any{"type": nullable("array"), "items": items}
+	case reflect.Map:
+		return map[string]any{"type": nullable("object"), "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		for name, fieldType := range yamlFields(t) {
+			properties[name] = typeSchema(fieldType)
+		}
+		return map[string]any{
+			"type":                 nullable("object"),
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	default:
+		return map[string]any{"type": nullable("string")}
+	}
+}
+
+// JSON Schema of the config file, for editor autocompletion and validation.
+// Generated from Config, see also CheckConfig.
+func JsonSchema() ([]byte, error) {
+	schema := typeSchema(reflect.TypeOf(Config{}))
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "Beacon config"
+	schema["type"] = "object"
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,37 @@
+package conf
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Schema file in repository root, regenerate with `beacon config schema > beacon.schema.json`
+const SCHEMA_FILE = "../beacon.schema.json"
+
+func TestJsonSchema(t *testing.T) {
+	schemaKeys := []string{}
+	for key := range serviceKeySchemas {
+		schemaKeys = append(schemaKeys, key)
+	}
+	require.ElementsMatch(t, SERVICE_KEYS, schemaKeys)
+
+	data, err := JsonSchema()
+	require.NoError(t, err)
+	schema := map[string]any{}
+	err = json.Unmarshal(data, &schema)
+	require.NoError(t, err)
+	properties := schema["properties"].(map[string]any)
+	for _, key := range []string{"services", "email", "report_time", "allowunknownheartbeats"} {
+		require.Contains(t, properties, key)
+	}
+	email := properties["email"].(map[string]any)["properties"].(map[string]any)
+	require.Contains(t, email, "smtp_password")
+	require.NotContains(t, properties, "envprefix")
+
+	saved, err := os.ReadFile(SCHEMA_FILE)
+	require.NoError(t, err)
+	require.Equal(t, string(data), string(saved), "schema file is outdated")
+}
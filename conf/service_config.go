@@ -1,8 +1,10 @@
 package conf
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -11,6 +13,75 @@ import (
 
 type ServicesList struct {
 	Services []ServiceConfig
+	// applied when decoding services, see Config.ServiceTemplates
+	templates *ServiceTemplates
+}
+
+// Keys of a service as written in config file, see NewServiceConfig
+type ServiceInput map[string]any
+
+// Hide token when printing defaults and templates, same as Secret
+func (input ServiceInput) MarshalYAML() (interface{}, error) {
+	out := make(map[string]any, len(input))
+	for key, value := range input {
+		out[key] = value
+	}
+	if _, ok := out["token"]; ok {
+		out["token"] = "*****"
+	}
+	return out, nil
+}
+
+// Settings shared by services in config file.
+//
+// Each service key is taken from the first place where it is set:
+//  1. the service itself
+//  2. template named by the service's `extends` (and templates it extends)
+//  3. defaults
+//  4. built-in defaults
+//
+// Lists (such as `status`) are replaced, not merged.
+type ServiceTemplates struct {
+	Defaults  ServiceInput            `yaml:"defaults"`
+	Templates map[string]ServiceInput `yaml:"templates"`
+	// Ignore token files that cannot be read instead of failing,
+	// used by CheckConfig which reports them as warnings
+	skipUnreadableTokenFiles bool
+}
+
+// Merge input with its templates and defaults, see ServiceTemplates
+func (t *ServiceTemplates) resolve(id string, input ServiceInput) (ServiceInput, error) {
+	layers := []ServiceInput{input}
+	seen := map[string]bool{}
+	current := input
+	for current["extends"] != nil {
+		name, ok := current["extends"].(string)
+		if !ok {
+			return nil, keyErrorf(serviceKey(id, "extends"), "[%s] invalid type for extends, expected string, got %v", id, current["extends"])
+		}
+		if seen[name] {
+			return nil, keyErrorf(serviceKey(id, "extends"), "[%s] template %q is part of extends cycle", id, name)
+		}
+		seen[name] = true
+		template, ok := t.Templates[name]
+		if !ok {
+			return nil, keyErrorf(serviceKey(id, "extends"), "[%s] unknown template %q", id, name)
+		}
+		layers = append(layers, template)
+		current = template
+	}
+	layers = append(layers, t.Defaults)
+
+	merged := ServiceInput{}
+	for i := len(layers) - 1; i >= 0; i-- {
+		for key, value := range layers[i] {
+			// empty value (e.g. `timeout:`) does not override
+			if key != "extends" && value != nil {
+				merged[key] = value
+			}
+		}
+	}
+	return merged, nil
 }
 
 type ServiceConfig struct {
@@ -18,6 +89,22 @@ type ServiceConfig struct {
 	Timeout time.Duration
 	Enabled bool
 	Token   Secret
+	// Show on the public status page
+	Public bool
+	// Name shown on the public status page, defaults to Id
+	DisplayName string
+	// Optional note shown on the public status page, e.g. incident description
+	Note string
+	// Labels for filtering and alert routing, see AlertRoute
+	Tags []string
+	// Section on the dashboard and in reports, empty for ungrouped services
+	Group string
+	// Name from Config.EscalationPolicies, empty to use alert routes
+	Escalation string
+	// Defined in web GUI and stored in DB instead of config file
+	Adopted bool `yaml:"-"`
+	// Not defined anywhere, only sent heartbeats (see AllowUnknownHeartbeats)
+	Unconfigured bool `yaml:"-"`
 	// web only below
 	Url         string
 	HttpStatus  []int
@@ -36,8 +123,16 @@ func defaultServiceConfig(id string) *ServiceConfig {
 	}
 }
 
-func NewServiceConfig(id string, input map[string]any) (*ServiceConfig, error) {
+// Create service from config file keys. Templates can be nil.
+func NewServiceConfig(id string, input ServiceInput, templates *ServiceTemplates) (*ServiceConfig, error) {
 	service := defaultServiceConfig(id)
+	if templates != nil {
+		var err error
+		input, err = templates.resolve(id, input)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if input == nil {
 		return service, nil
 	}
@@ -46,7 +141,7 @@ func NewServiceConfig(id string, input map[string]any) (*ServiceConfig, error) {
 		if url, ok := inputUrl.(string); ok {
 			service.Url = url
 		} else {
-			return nil, fmt.Errorf("[%s] url specified but not string, got %q", id, inputUrl)
+			return nil, keyErrorf(serviceKey(id, "url"), "[%s] url specified but not string, got %v", id, inputUrl)
 		}
 	}
 
@@ -59,14 +154,14 @@ func NewServiceConfig(id string, input map[string]any) (*ServiceConfig, error) {
 				if status, ok := s.(int); ok {
 					parsedStatuses = append(parsedStatuses, status)
 				} else {
-					return nil, fmt.Errorf("[%s] invalid value in status, got %v", id, s)
+					return nil, keyErrorf(serviceKey(id, "status"), "[%s] invalid value in status, got %v", id, s)
 				}
 			}
 			if len(parsedStatuses) > 0 {
 				service.HttpStatus = parsedStatuses
 			}
 		} else {
-			return nil, fmt.Errorf("[%s] invalid type for field status, got %q", id, inputStatus)
+			return nil, keyErrorf(serviceKey(id, "status"), "[%s] invalid type for field status, got %v", id, inputStatus)
 		}
 	}
 
@@ -80,12 +175,12 @@ func NewServiceConfig(id string, input map[string]any) (*ServiceConfig, error) {
 				if content, ok := c.(string); ok {
 					parsedContents = append(parsedContents, content)
 				} else {
-					return nil, fmt.Errorf("[%s] invalid value in content, got %v", id, c)
+					return nil, keyErrorf(serviceKey(id, "content"), "[%s] invalid value in content, got %v", id, c)
 				}
 			}
 			service.BodyContent = parsedContents
 		} else {
-			return nil, fmt.Errorf("[%s] invalid type for field content, got %q", id, inputContent)
+			return nil, keyErrorf(serviceKey(id, "content"), "[%s] invalid type for field content, got %v", id, inputContent)
 		}
 	}
 
@@ -94,11 +189,11 @@ func NewServiceConfig(id string, input map[string]any) (*ServiceConfig, error) {
 		if timeoutStr, ok := inputTimeout.(string); ok {
 			duration, err := time.ParseDuration(timeoutStr)
 			if err != nil {
-				return nil, fmt.Errorf("[%s] invalid duration format for timeout, got %q", id, timeoutStr)
+				return nil, keyErrorf(serviceKey(id, "timeout"), "[%s] invalid duration format for timeout, got %q", id, timeoutStr)
 			}
 			service.Timeout = duration
 		} else {
-			return nil, fmt.Errorf("[%s] invalid type for timeout, expected string, got %q", id, inputTimeout)
+			return nil, keyErrorf(serviceKey(id, "timeout"), "[%s] invalid type for timeout, expected string, got %v", id, inputTimeout)
 		}
 	}
 
@@ -107,7 +202,69 @@ func NewServiceConfig(id string, input map[string]any) (*ServiceConfig, error) {
 		if enabled, ok := inputEnabled.(bool); ok {
 			service.Enabled = enabled
 		} else {
-			return nil, fmt.Errorf("[%s] invalid type for enabled, expected bool, got %q", id, inputEnabled)
+			return nil, keyErrorf(serviceKey(id, "enabled"), "[%s] invalid type for enabled, expected bool, got %v", id, inputEnabled)
+		}
+	}
+
+	inputPublic := input["public"]
+	if inputPublic != nil {
+		if public, ok := inputPublic.(bool); ok {
+			service.Public = public
+		} else {
+			return nil, keyErrorf(serviceKey(id, "public"), "[%s] invalid type for public, expected bool, got %v", id, inputPublic)
+		}
+	}
+
+	inputDisplayName := input["display_name"]
+	if inputDisplayName != nil {
+		if displayName, ok := inputDisplayName.(string); ok {
+			service.DisplayName = displayName
+		} else {
+			return nil, keyErrorf(serviceKey(id, "display_name"), "[%s] invalid type for display_name, expected string, got %v", id, inputDisplayName)
+		}
+	}
+
+	inputNote := input["note"]
+	if inputNote != nil {
+		if note, ok := inputNote.(string); ok {
+			service.Note = note
+		} else {
+			return nil, keyErrorf(serviceKey(id, "note"), "[%s] invalid type for note, expected string, got %v", id, inputNote)
+		}
+	}
+
+	inputTags := input["tags"]
+	if inputTags != nil {
+		if tags, ok := inputTags.([]interface{}); ok {
+			var parsedTags []string
+			for _, t := range tags {
+				if tag, ok := t.(string); ok && tag != "" {
+					parsedTags = append(parsedTags, tag)
+				} else {
+					return nil, keyErrorf(serviceKey(id, "tags"), "[%s] invalid value in tags, got %v", id, t)
+				}
+			}
+			service.Tags = parsedTags
+		} else {
+			return nil, keyErrorf(serviceKey(id, "tags"), "[%s] invalid type for field tags, got %v", id, inputTags)
+		}
+	}
+
+	inputGroup := input["group"]
+	if inputGroup != nil {
+		if group, ok := inputGroup.(string); ok {
+			service.Group = group
+		} else {
+			return nil, keyErrorf(serviceKey(id, "group"), "[%s] invalid type for group, expected string, got %v", id, inputGroup)
+		}
+	}
+
+	inputEscalation := input["escalation"]
+	if inputEscalation != nil {
+		if escalation, ok := inputEscalation.(string); ok {
+			service.Escalation = escalation
+		} else {
+			return nil, keyErrorf(serviceKey(id, "escalation"), "[%s] invalid type for escalation, expected string, got %v", id, inputEscalation)
 		}
 	}
 
@@ -117,14 +274,14 @@ func NewServiceConfig(id string, input map[string]any) (*ServiceConfig, error) {
 		if tokenFileStr, ok := inputTokenFile.(string); ok {
 			if tokenFileStr != "" {
 				data, err := os.ReadFile(tokenFileStr)
-				if err != nil {
-					return nil, fmt.Errorf("[%s] cannot read token file: %w", id, err)
+				if err != nil && !(templates != nil && templates.skipUnreadableTokenFiles) {
+					return nil, keyErrorf(serviceKey(id, "token_file"), "[%s] cannot read token file: %w", id, err)
 				}
 				token := strings.TrimSpace(string(data))
 				service.Token.FromFile = token
 			}
 		} else {
-			return nil, fmt.Errorf("[%s] invalid type for timeout, expected string, got %q", id, inputTimeout)
+			return nil, keyErrorf(serviceKey(id, "token_file"), "[%s] invalid type for token_file, expected string, got %v", id, inputTokenFile)
 		}
 	}
 	inputToken := input["token"]
@@ -132,17 +289,57 @@ func NewServiceConfig(id string, input map[string]any) (*ServiceConfig, error) {
 		if tokenStr, ok := inputToken.(string); ok {
 			service.Token.Value = tokenStr
 		} else {
-			return nil, fmt.Errorf("[%s] invalid type for timeout, expected string, got %q", id, inputTimeout)
+			return nil, keyErrorf(serviceKey(id, "token"), "[%s] invalid type for token, expected string, got %v", id, inputToken)
 		}
 	}
 
 	return service, nil
 }
 
+// Name to show to the public, DisplayName if set, Id otherwise
+func (sc *ServiceConfig) Name() string {
+	if sc.DisplayName != "" {
+		return sc.DisplayName
+	}
+	return sc.Id
+}
+
+// Group names of the services, sorted, with ungrouped services ("") last
+func ServiceGroups(services []ServiceConfig) []string {
+	groups := []string{}
+	ungrouped := false
+	for _, service := range services {
+		if service.Group == "" {
+			ungrouped = true
+		} else if !slices.Contains(groups, service.Group) {
+			groups = append(groups, service.Group)
+		}
+	}
+	slices.Sort(groups)
+	if ungrouped {
+		groups = append(groups, "")
+	}
+	return groups
+}
+
+func (sc *ServiceConfig) HasTag(tag string) bool {
+	return slices.Contains(sc.Tags, tag)
+}
+
 func (sc *ServiceConfig) IsWebService() bool {
 	return sc.Url != ""
 }
 
+// Reject keys not in SERVICE_KEYS. Misspelled keys (such as `statuses`)
+// would otherwise be ignored and silently disable a check.
+func checkServiceKeys(id string, node *yaml.Node) error {
+	errs := []error{}
+	for _, issue := range removeUnknownServiceKeys(id, node, SERVICE_KEYS) {
+		errs = append(errs, fmt.Errorf("line %d: %s", issue.Line, issue.Message))
+	}
+	return errors.Join(errs...)
+}
+
 func (servicesList *ServicesList) UnmarshalYAML(node *yaml.Node) error {
 	if node.Kind != yaml.MappingNode {
 		return fmt.Errorf("expected a mapping node, got %v", node.Kind)
@@ -153,22 +350,78 @@ func (servicesList *ServicesList) UnmarshalYAML(node *yaml.Node) error {
 		keyNode := node.Content[i]
 		valueNode := node.Content[i+1]
 
-		input := map[string]any{}
-		err := valueNode.Decode(input)
+		err := checkServiceKeys(keyNode.Value, valueNode)
 		if err != nil {
 			return err
 		}
-
-		serviceConfig, err := NewServiceConfig(keyNode.Value, input)
+		input := ServiceInput{}
+		err = valueNode.Decode(input)
 		if err != nil {
 			return err
 		}
+
+		serviceConfig, err := NewServiceConfig(keyNode.Value, input, servicesList.templates)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", keyNode.Line, err)
+		}
 		services = append(services, *serviceConfig)
 	}
 	servicesList.Services = services
 	return nil
 }
 
+// Service in the same format as in config file
+type serviceConfigYaml struct {
+	Url         string   `yaml:"url,omitempty"`
+	HttpStatus  []int    `yaml:"status,omitempty"`
+	BodyContent []string `yaml:"content,omitempty"`
+	Timeout     string   `yaml:"timeout"`
+	Enabled     bool     `yaml:"enabled"`
+	Public      bool     `yaml:"public,omitempty"`
+	DisplayName string   `yaml:"display_name,omitempty"`
+	Note        string   `yaml:"note,omitempty"`
+	Tags        []string `yaml:"tags,omitempty"`
+	Group       string   `yaml:"group,omitempty"`
+	Escalation  string   `yaml:"escalation,omitempty"`
+	Token       *Secret  `yaml:"token,omitempty"`
+}
+
+func (sc ServiceConfig) MarshalYAML() (interface{}, error) {
+	out := serviceConfigYaml{
+		Url:         sc.Url,
+		BodyContent: sc.BodyContent,
+		Timeout:     sc.Timeout.String(),
+		Enabled:     sc.Enabled,
+		Public:      sc.Public,
+		DisplayName: sc.DisplayName,
+		Note:        sc.Note,
+		Tags:        sc.Tags,
+		Group:       sc.Group,
+		Escalation:  sc.Escalation,
+	}
+	if sc.IsWebService() {
+		out.HttpStatus = sc.HttpStatus
+	}
+	if sc.Token.IsSet() {
+		out.Token = &sc.Token
+	}
+	return out, nil
+}
+
+// Services keyed by id, as in config file
+func (servicesList ServicesList) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, service := range servicesList.Services {
+		valueNode := &yaml.Node{}
+		err := valueNode.Encode(service)
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: service.Id}, valueNode)
+	}
+	return node, nil
+}
+
 func (servicesList *ServicesList) Get(id string) *ServiceConfig {
 	for _, cfg := range servicesList.Services {
 		if cfg.Id == id {
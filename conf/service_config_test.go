@@ -85,3 +85,126 @@ services:
 	assert.Equal(t, "AaaDmh9Yr5rycRPHxb7nCDa", service.Token.FromFile)
 	assert.Equal(t, "Dmh9Yr5rycRPHxb7nCDa", service.Token.Value)
 }
+
+func TestPublicServiceConfig(t *testing.T) {
+	config, err := ConfigFromBytes([]byte(`
+services:
+  api:
+    public: true
+    display_name: "Public API"
+    note: "Degraded performance since 10:00 UTC"
+  internal-job:
+status_page:
+  title: "Acme Status"
+`))
+	require.NoError(t, err)
+
+	api := config.Services.Get("api")
+	require.NotNil(t, api)
+	assert.True(t, api.Public)
+	assert.Equal(t, "Public API", api.Name())
+	assert.Equal(t, "Degraded performance since 10:00 UTC", api.Note)
+
+	internal := config.Services.Get("internal-job")
+	require.NotNil(t, internal)
+	assert.False(t, internal.Public)
+	assert.Equal(t, "internal-job", internal.Name())
+
+	assert.Equal(t, "Acme Status", config.StatusPage.Title)
+
+	_, err = ConfigFromBytes([]byte(`
+services:
+  api:
+    public: "yes"
+`))
+	require.Error(t, err)
+}
+
+func TestUnknownKeysRejected(t *testing.T) {
+	_, err := ConfigFromBytes([]byte(`
+services:
+  website:
+    url: "https://example.com"
+    statuses: [200]
+    contents: ["Welcome"]
+`))
+	require.ErrorContains(t, err, `line 5: [website] unknown key "statuses"`)
+	require.ErrorContains(t, err, `line 6: [website] unknown key "contents"`)
+
+	_, err = ConfigFromBytes([]byte(`
+email:
+  smtp_srver: smtp.example.com
+report_tme: 5
+`))
+	require.ErrorContains(t, err, `line 3: unknown key "email.smtp_srver"`)
+	require.ErrorContains(t, err, `line 4: unknown key "report_tme"`)
+}
+
+func TestServiceTemplates(t *testing.T) {
+	config, err := ConfigFromBytes([]byte(`
+defaults:
+  timeout: 1h
+  status: [200]
+templates:
+  website:
+    status: [200, 301]
+    content: ["<html"]
+  shop:
+    extends: website
+    timeout: 10m
+services:
+  homepage:
+    url: "https://example.com"
+    extends: website
+  eshop:
+    extends: shop
+    url: "https://shop.example.com"
+    status: [200]
+  backup:
+  nightly-job:
+    timeout: 25h
+`))
+	require.NoError(t, err)
+
+	homepage := config.Services.Get("homepage")
+	require.Equal(t, time.Hour, homepage.Timeout, "from defaults")
+	require.Equal(t, []int{200, 301}, homepage.HttpStatus, "template over defaults")
+	require.Equal(t, []string{"<html"}, homepage.BodyContent)
+
+	eshop := config.Services.Get("eshop")
+	require.Equal(t, 10*time.Minute, eshop.Timeout, "nearest template first")
+	require.Equal(t, []int{200}, eshop.HttpStatus, "service over template")
+	require.Equal(t, []string{"<html"}, eshop.BodyContent, "from extended template")
+
+	require.Equal(t, time.Hour, config.Services.Get("backup").Timeout)
+	require.Equal(t, 25*time.Hour, config.Services.Get("nightly-job").Timeout)
+
+	_, err = ConfigFromBytes([]byte(`
+services:
+  homepage:
+    extends: website
+`))
+	require.ErrorContains(t, err, `[homepage] unknown template "website"`)
+
+	_, err = ConfigFromBytes([]byte(`
+templates:
+  a:
+    extends: b
+  b:
+    extends: a
+services:
+  homepage:
+    extends: a
+`))
+	require.ErrorContains(t, err, `[homepage] template "a" is part of extends cycle`)
+
+	_, err = ConfigFromBytes([]byte(`
+defaults:
+  extends: website
+templates:
+  website:
+    statuses: [200]
+`))
+	require.ErrorContains(t, err, `line 3: [defaults] unknown key "extends"`)
+	require.ErrorContains(t, err, `line 6: [templates.website] unknown key "statuses"`)
+}
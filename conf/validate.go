@@ -0,0 +1,126 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+)
+
+// Invalid value of a config key, such as "port" or "services.my-service.url".
+// Key allows finding the value in config file, see CheckConfig.
+type KeyError struct {
+	Key string
+	Err error
+}
+
+func (e *KeyError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+func keyErrorf(key string, format string, args ...any) error {
+	return &KeyError{Key: key, Err: fmt.Errorf(format, args...)}
+}
+
+func serviceKey(id string, field string) string {
+	return fmt.Sprintf("services.%s.%s", id, field)
+}
+
+func validatePort(name string, port int, optional bool) error {
+	if optional && port == 0 {
+		return nil
+	}
+	if port < 1 || port > 65535 {
+		return keyErrorf(name, "%s must be between 1 and 65535, got %d", name, port)
+	}
+	return nil
+}
+
+// Check that addresses can be parsed, such as "user@example.com" or "Name <user@example.com>"
+func validateAddresses(key string, addresses ...string) []error {
+	errs := []error{}
+	for _, address := range addresses {
+		if _, err := mail.ParseAddress(address); err != nil {
+			errs = append(errs, keyErrorf(key, "%s: invalid email address %q", key, address))
+		}
+	}
+	return errs
+}
+
+// Check values that are syntactically valid but cannot work,
+// such as negative timeouts or invalid URLs.
+// All problems found are returned as KeyError, joined using errors.Join.
+func (config *Config) Validate() error {
+	errs := []error{
+		validatePort("port", config.Port, false),
+		validatePort("heartbeat_port", config.HeartbeatPort, true),
+		validatePort("tls.redirect_port", config.Tls.RedirectPort, true),
+	}
+	if config.ReportAfter < 0 || config.ReportAfter > 23 {
+		errs = append(errs, keyErrorf("report_time", "report_time must be between 0 and 23, got %d", config.ReportAfter))
+	}
+	if config.SchedulerPeriod <= 0 {
+		errs = append(errs, keyErrorf("scheduler_period", "scheduler_period must be positive, got %s", config.SchedulerPeriod))
+	}
+	if config.WebCheckPeriod <= 0 {
+		errs = append(errs, keyErrorf("web_check_period", "web_check_period must be positive, got %s", config.WebCheckPeriod))
+	}
+	if config.ShutdownTimeout < 0 {
+		errs = append(errs, keyErrorf("shutdown_timeout", "shutdown_timeout must not be negative, got %s", config.ShutdownTimeout))
+	}
+	if (config.Tls.CertFile == "") != (config.Tls.KeyFile == "") {
+		errs = append(errs, keyErrorf("tls", "tls requires both cert_file and key_file"))
+	}
+	if config.Tls.RedirectPort != 0 && !config.Tls.IsEnabled() {
+		errs = append(errs, keyErrorf("tls.redirect_port", "tls.redirect_port requires tls.cert_file and tls.key_file"))
+	}
+	limits := config.RateLimit
+	if limits.PerIp < 0 || limits.PerIpBurst < 0 || limits.PerService < 0 || limits.PerServiceBurst < 0 || limits.MaxUnknownServices < 0 {
+		errs = append(errs, keyErrorf("rate_limit", "rate_limit values must not be negative"))
+	}
+	email := config.EmailConf
+	if !email.IsDebug() {
+		errs = append(errs, validateAddresses("email.send_to", email.SendTo...)...)
+	}
+	errs = append(errs, validateAddresses("email.cc", email.Cc...)...)
+	errs = append(errs, validateAddresses("email.bcc", email.Bcc...)...)
+	if email.ReplyTo != "" {
+		errs = append(errs, validateAddresses("email.reply_to", email.ReplyTo)...)
+	}
+	errs = append(errs, config.validateRoutes()...)
+	errs = append(errs, config.validateMaintenance()...)
+	errs = append(errs, config.validateEscalation()...)
+	for _, service := range config.AllServices() {
+		errs = append(errs, service.Validate())
+	}
+	return errors.Join(errs...)
+}
+
+// See Config.Validate
+func (sc *ServiceConfig) Validate() error {
+	key := func(field string) string {
+		return serviceKey(sc.Id, field)
+	}
+	errs := []error{}
+	if sc.Timeout <= 0 {
+		errs = append(errs, keyErrorf(key("timeout"), "[%s] timeout must be positive, got %s", sc.Id, sc.Timeout))
+	}
+	if sc.IsWebService() {
+		parsed, err := url.Parse(sc.Url)
+		if err != nil {
+			errs = append(errs, keyErrorf(key("url"), "[%s] invalid url: %w", sc.Id, err))
+		} else if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			errs = append(errs, keyErrorf(key("url"), "[%s] url must be absolute http(s) URL, got %q", sc.Id, sc.Url))
+		}
+	}
+	for _, status := range sc.HttpStatus {
+		if status < 100 || status > 599 {
+			errs = append(errs, keyErrorf(key("status"), "[%s] invalid HTTP status %d", sc.Id, status))
+		}
+	}
+	return errors.Join(errs...)
+}
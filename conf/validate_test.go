@@ -0,0 +1,37 @@
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	config, err := ConfigFromBytes([]byte(`
+services:
+  heartbeat-service:
+  web-service:
+    url: "https://example.com"
+    status: [200, 301]
+`))
+	require.NoError(t, err)
+	require.NoError(t, config.Validate())
+
+	testCases := map[string]string{
+		"port: 70000":                           "port must be between 1 and 65535",
+		"report_time: 24":                       "report_time must be between 0 and 23",
+		"scheduler_period: 0s":                  "scheduler_period must be positive",
+		"tls:\n  cert_file: cert.pem":           "tls requires both cert_file and key_file",
+		"tls:\n  redirect_port: 80":             "tls.redirect_port requires",
+		"rate_limit:\n  per_ip: -1":             "rate_limit values must not be negative",
+		"services:\n  x:\n    timeout: 0s":      "[x] timeout must be positive",
+		"services:\n  x:\n    url: example.com": "[x] url must be absolute http(s) URL",
+		"services:\n  x:\n    url: https://example.com\n    status: [999]": "[x] invalid HTTP status 999",
+	}
+	for data, expected := range testCases {
+		config, err := ConfigFromBytes([]byte(data))
+		require.NoError(t, err, data)
+		err = config.Validate()
+		require.ErrorContains(t, err, expected, data)
+	}
+}
@@ -35,6 +35,11 @@ func (s *WeekdaysSet) UnmarshalYAML(node *yaml.Node) error {
 }
 
 func (s WeekdaysSet) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+// Space separated days, such as "Monday Friday"
+func (s WeekdaysSet) String() string {
 	out := ""
 	for idx, present := range s.days {
 		if present {
@@ -45,7 +50,7 @@ func (s WeekdaysSet) MarshalYAML() (interface{}, error) {
 			out += time.Weekday(idx).String()
 		}
 	}
-	return out, nil
+	return out
 }
 
 func (s *WeekdaysSet) ParseString(value string) error {
@@ -6,6 +6,7 @@ import (
 
 	"github.com/davidmasek/beacon/conf"
 	"github.com/davidmasek/beacon/logging"
+	"github.com/davidmasek/beacon/monitor"
 	"github.com/davidmasek/beacon/reporting"
 	"github.com/davidmasek/beacon/scheduler"
 	"github.com/davidmasek/beacon/storage"
@@ -24,10 +25,18 @@ func RunAllJobs(db storage.Storage, config *conf.Config, now time.Time) error {
 	if err != nil {
 		return err
 	}
+	// catch status changes without new health check, e.g. heartbeat timeout
+	for _, report := range reports {
+		monitor.Events.SetStatus(report.ServiceCfg.Id, report.ServiceStatus, now)
+	}
 	err = reporting.SummaryReportJob(reports, db, config, now)
 	if err != nil {
 		return err
 	}
+	err = reporting.IncidentsJob(reports, db, config, now)
+	if err != nil {
+		return err
+	}
 	err = reporting.FailsReportJob(reports, db, config, now)
 	if err != nil {
 		return err
@@ -46,20 +55,23 @@ func RunAllJobs(db storage.Storage, config *conf.Config, now time.Time) error {
 //
 // Will not call run next job again until previous one returns, even
 // if specified interval passes.
-func Start(ctx context.Context, db storage.Storage, config *conf.Config) {
+//
+// Each run uses the config current at the time it starts.
+// Changes to SCHEDULER_PERIOD require restart.
+func Start(ctx context.Context, db storage.Storage, live *conf.LiveConfig) {
 	logger := logging.Get()
-	checkInterval := config.SchedulerPeriod
+	checkInterval := live.Get().SchedulerPeriod
 	err := scheduler.InitializeSentinel(db, time.Now())
 	if err != nil {
 		logger.Errorw("Failed to initialize job sentinel", zap.Error(err))
 	}
 
-	if err = RunAllJobs(db, config, time.Now()); err != nil {
+	if err = RunAllJobs(db, live.Get(), time.Now()); err != nil {
 		logger.Errorw("Scheduling work failed", zap.Error(err))
 	}
 
 	logger.Infow("Starting scheduler", "checkInterval", checkInterval)
 	scheduler.StartFunction(ctx, checkInterval, func(now time.Time) error {
-		return AddAll(db, config)
+		return AddAll(db, live.Get())
 	})
 }
@@ -70,9 +70,11 @@ func AddAll(db storage.Storage, config *conf.Config) error {
 	return nil
 }
 
-func ReadTasks(ctx context.Context, db storage.Storage, config *conf.Config) {
+// Consume tasks from the queue until ctx is done.
+// Each task uses the config current at the time it starts.
+func ReadTasks(ctx context.Context, db storage.Storage, live *conf.LiveConfig) {
 	logger := logging.Get()
-	conn, err := amqp.Dial(config.RabbitConn)
+	conn, err := amqp.Dial(live.Get().RabbitConn)
 	if err != nil {
 		logger.Errorw("Failed to connect to RabbitMQ", zap.Error(err))
 		return
@@ -98,6 +100,14 @@ func ReadTasks(ctx context.Context, db storage.Storage, config *conf.Config) {
 		return
 	}
 
+	// take one message at a time, so that nothing besides the message
+	// being processed is left unacknowledged on shutdown
+	err = ch.Qos(1, 0, false)
+	if err != nil {
+		logger.Errorw("Failed to set QoS", zap.Error(err))
+		return
+	}
+
 	msgs, err := ch.Consume(
 		q.Name, // queue
 		"",     // consumer
@@ -122,12 +132,22 @@ func ReadTasks(ctx context.Context, db storage.Storage, config *conf.Config) {
 				logger.Error("RabbitMQ delivery channel closed unexpectedly.")
 				return
 			}
+			// Both the context and delivery can be ready at the same time.
+			// Return the message to the queue instead of starting new work during shutdown.
+			if ctx.Err() != nil {
+				err = d.Nack(false, true)
+				if err != nil {
+					logger.Errorw("Failed to return message to queue", zap.Error(err))
+				}
+				logger.Info("Context cancelled. Stopping consumer.")
+				return
+			}
 			msg := string(d.Body)
 			logger.Infow("Received a message", "body", msg)
 
 			if msg == "all" {
 				now := time.Now()
-				err = RunAllJobs(db, config, now)
+				err = RunAllJobs(db, live.Get(), now)
 				if err != nil {
 					logger.Errorw("Error when running jobs.", zap.Error(err))
 				}
@@ -0,0 +1,125 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/davidmasek/beacon/logging"
+	"github.com/davidmasek/beacon/storage"
+)
+
+type EventType string
+
+const (
+	// New health check was stored
+	EVENT_HEALTH_CHECK EventType = "health_check"
+	// Service status changed
+	EVENT_STATUS_CHANGE EventType = "status_change"
+)
+
+// Buffered events per subscriber. Events are dropped for subscribers that fall behind.
+const EVENT_BUFFER_SIZE = 64
+
+type Event struct {
+	Type           EventType         `json:"type"`
+	ServiceId      string            `json:"service_id"`
+	Timestamp      string            `json:"timestamp"`
+	Status         ServiceStatus     `json:"status"`
+	PreviousStatus ServiceStatus     `json:"previous_status,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+}
+
+// In-process pub/sub for health check and status events.
+// Also keeps track of the last known status of each service to detect transitions.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+	statuses    map[string]ServiceStatus
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: map[chan Event]struct{}{},
+		statuses:    map[string]ServiceStatus{},
+	}
+}
+
+// Default broker, used by heartbeat listener and web checks
+var Events = NewBroker()
+
+// Subscribe to all events. Call the returned function to unsubscribe.
+func (b *Broker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, EVENT_BUFFER_SIZE)
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Send event to all subscribers without blocking
+func (b *Broker) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.publishLocked(event)
+}
+
+func (b *Broker) publishLocked(event Event) {
+	logger := logging.Get()
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			logger.Debugw("Dropping event for slow subscriber", "event", event.Type, "service", event.ServiceId)
+		}
+	}
+}
+
+// Publish stored health check and resulting status change, if any
+func (b *Broker) PublishHealthCheck(check *storage.HealthCheckInput) {
+	status := HealthCheckStatus(&storage.HealthCheck{
+		ServiceId: check.ServiceId,
+		Timestamp: check.Timestamp,
+		Metadata:  check.Metadata,
+	})
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.publishLocked(Event{
+		Type:      EVENT_HEALTH_CHECK,
+		ServiceId: check.ServiceId,
+		Timestamp: check.Timestamp.UTC().Format(storage.TIME_FORMAT),
+		Status:    status,
+		Metadata:  check.Metadata,
+	})
+	b.setStatusLocked(check.ServiceId, status, check.Timestamp)
+}
+
+// Record current service status, publishing an event if it changed.
+// Used for changes without new health check, such as a heartbeat timing out.
+func (b *Broker) SetStatus(serviceId string, status ServiceStatus, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.setStatusLocked(serviceId, status, now)
+}
+
+func (b *Broker) setStatusLocked(serviceId string, status ServiceStatus, now time.Time) {
+	previous, known := b.statuses[serviceId]
+	b.statuses[serviceId] = status
+	// first observation after startup is not a transition
+	if !known || previous == status {
+		return
+	}
+	b.publishLocked(Event{
+		Type:           EVENT_STATUS_CHANGE,
+		ServiceId:      serviceId,
+		Timestamp:      now.UTC().Format(storage.TIME_FORMAT),
+		Status:         status,
+		PreviousStatus: previous,
+	})
+}
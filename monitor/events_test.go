@@ -0,0 +1,78 @@
+package monitor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/davidmasek/beacon/logging"
+	"github.com/davidmasek/beacon/monitor"
+	"github.com/davidmasek/beacon/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, events <-chan monitor.Event) monitor.Event {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		require.FailNow(t, "no event received")
+		return monitor.Event{}
+	}
+}
+
+func TestBrokerEvents(t *testing.T) {
+	logging.InitTest(t)
+	broker := monitor.NewBroker()
+	events, unsubscribe := broker.Subscribe()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	broker.PublishHealthCheck(&storage.HealthCheckInput{ServiceId: "api", Timestamp: now})
+	event := receive(t, events)
+	assert.Equal(t, monitor.EVENT_HEALTH_CHECK, event.Type)
+	assert.Equal(t, "api", event.ServiceId)
+	assert.Equal(t, monitor.STATUS_OK, event.Status)
+	assert.Equal(t, "2025-01-01T12:00:00Z", event.Timestamp)
+	// first status is not a transition
+	assert.Len(t, events, 0)
+
+	broker.PublishHealthCheck(&storage.HealthCheckInput{
+		ServiceId: "api",
+		Timestamp: now.Add(time.Minute),
+		Metadata:  map[string]string{"status": "FAIL", "error": "timeout"},
+	})
+	event = receive(t, events)
+	assert.Equal(t, monitor.EVENT_HEALTH_CHECK, event.Type)
+	assert.Equal(t, monitor.STATUS_FAIL, event.Status)
+	assert.Equal(t, "timeout", event.Metadata["error"])
+	event = receive(t, events)
+	assert.Equal(t, monitor.EVENT_STATUS_CHANGE, event.Type)
+	assert.Equal(t, monitor.STATUS_FAIL, event.Status)
+	assert.Equal(t, monitor.STATUS_OK, event.PreviousStatus)
+
+	// same status -> no event
+	broker.SetStatus("api", monitor.STATUS_FAIL, now)
+	assert.Len(t, events, 0)
+	broker.SetStatus("api", monitor.STATUS_OK, now)
+	event = receive(t, events)
+	assert.Equal(t, monitor.EVENT_STATUS_CHANGE, event.Type)
+
+	unsubscribe()
+	_, open := <-events
+	assert.False(t, open, "channel should be closed after unsubscribe")
+	// publishing without subscribers and repeated unsubscribe do not block or panic
+	broker.SetStatus("api", monitor.STATUS_FAIL, now)
+	unsubscribe()
+}
+
+func TestBrokerSlowSubscriber(t *testing.T) {
+	logging.InitTest(t)
+	broker := monitor.NewBroker()
+	events, unsubscribe := broker.Subscribe()
+	defer unsubscribe()
+
+	for i := range monitor.EVENT_BUFFER_SIZE + 10 {
+		broker.Publish(monitor.Event{Type: monitor.EVENT_HEALTH_CHECK, ServiceId: "api", Timestamp: time.Unix(int64(i), 0).String()})
+	}
+	assert.Len(t, events, monitor.EVENT_BUFFER_SIZE)
+}
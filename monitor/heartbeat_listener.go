@@ -2,6 +2,7 @@ package monitor
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -14,6 +15,11 @@ import (
 	"github.com/davidmasek/beacon/storage"
 )
 
+var (
+	ErrTokenNotAllowed = errors.New("token does not allow this action")
+	ErrTokenInactive   = errors.New("token is expired or revoked")
+)
+
 type HeartbeatResponse struct {
 	ServiceId string `json:"service_id"`
 	Timestamp string `json:"timestamp"`
@@ -26,14 +32,56 @@ type StatusResponse struct {
 }
 
 func RegisterHeartbeatHandlers(db storage.Storage, mux *http.ServeMux, config *conf.Config) {
-	mux.HandleFunc("/services/{service_id}/beat", handleBeat(db, config))
-	mux.HandleFunc("/services/{service_id}/status", handleStatus(db, config))
+	limits := newHeartbeatLimits(config)
+	mux.HandleFunc("/services/{service_id}/beat", limits.wrap(handleBeat(db, config, limits)))
+	mux.HandleFunc("/services/{service_id}/status", limits.wrap(handleStatus(db, config)))
+}
+
+// Extract token from "Authorization: Bearer <token>" header
+func BearerToken(r *http.Request) (string, bool) {
+	authHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return "", false
+	}
+	return strings.TrimPrefix(authHeader, "Bearer "), true
+}
+
+// Check Beacon-managed API token from the request.
+// Returns nil token if the request does not contain a known API token.
+// Returns ErrTokenInactive if a known token is expired or revoked
+// and ErrTokenNotAllowed if it does not allow any of the given scopes.
+func CheckApiToken(db storage.Storage, r *http.Request, scopes ...string) (*storage.ApiToken, error) {
+	logger := logging.Get()
+	bearer, ok := BearerToken(r)
+	if !ok {
+		return nil, nil
+	}
+	apiToken, err := db.FindApiToken(bearer)
+	if err != nil || apiToken == nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !apiToken.IsActive(now) {
+		return apiToken, ErrTokenInactive
+	}
+	if !apiToken.Allows(now, scopes...) {
+		return apiToken, ErrTokenNotAllowed
+	}
+	err = db.TouchApiToken(apiToken.Id, now)
+	if err != nil {
+		// do not fail the request, last used time is only informative
+		logger.Errorw("Failed to update token last used time", "token", apiToken.Id, zap.Error(err))
+	}
+	return apiToken, nil
 }
 
 // Auth might be better handled by middleware function instead,
 // which might also extract and validate the service (or another middleware could do that).
 // For now, going with this simple function that returns if processing should stop (auth failed or something else is wrong).
-func checkAuth(w http.ResponseWriter, r *http.Request, config *conf.Config, service *conf.ServiceConfig) (stop bool) {
+//
+// Beacon-managed API tokens with one of the given scopes are accepted
+// for any service, in addition to the token from service config.
+func checkAuth(w http.ResponseWriter, r *http.Request, db storage.Storage, config *conf.Config, serviceId string, service *conf.ServiceConfig, scopes ...string) (stop bool) {
 	logger := logging.Get()
 	// service not found and unknown services not allowed
 	if service == nil && !config.AllowUnknownHeartbeats {
@@ -41,13 +89,33 @@ func checkAuth(w http.ResponseWriter, r *http.Request, config *conf.Config, serv
 		return true
 	}
 
+	apiToken, err := CheckApiToken(db, r, scopes...)
+	if errors.Is(err, ErrTokenNotAllowed) {
+		logger.Debugw("token scope insufficient", "service", serviceId, "token", apiToken.Id)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return true
+	}
+	if errors.Is(err, ErrTokenInactive) {
+		logger.Debugw("token expired or revoked", "service", serviceId, "token", apiToken.Id)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return true
+	}
+	if err != nil {
+		logger.Errorw("Failed to check API token", zap.Error(err))
+		http.Error(w, "Failed to check token", http.StatusInternalServerError)
+		return true
+	}
+	if apiToken != nil {
+		return false
+	}
+
 	refToken := ""
 	if service != nil {
 		refToken = service.Token.Get()
 	}
 	// auth not configured for a given service, but required
 	if refToken == "" && config.RequireHeartbeatAuth {
-		logger.Warnw("Auth required but not configured for a service", "service", service.Id)
+		logger.Warnw("Auth required but not configured for a service", "service", serviceId)
 		http.Error(w, "Service auth required but not configured", http.StatusForbidden)
 		return true
 	}
@@ -57,15 +125,13 @@ func checkAuth(w http.ResponseWriter, r *http.Request, config *conf.Config, serv
 		return false
 	}
 
-	authHeader := r.Header.Get("Authorization")
-	token := ""
+	token, ok := BearerToken(r)
 	// auth not present
-	if !strings.HasPrefix(authHeader, "Bearer ") {
+	if !ok {
 		logger.Debug("no auth")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return true
 	}
-	token = strings.TrimPrefix(authHeader, "Bearer ")
 
 	// bad token
 	if token != refToken {
@@ -76,7 +142,7 @@ func checkAuth(w http.ResponseWriter, r *http.Request, config *conf.Config, serv
 	return false
 }
 
-func handleBeat(db storage.Storage, config *conf.Config) http.HandlerFunc {
+func handleBeat(db storage.Storage, config *conf.Config, limits *heartbeatLimits) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		logger := logging.Get()
 		serviceId := r.PathValue("service_id")
@@ -84,11 +150,32 @@ func handleBeat(db storage.Storage, config *conf.Config) http.HandlerFunc {
 			http.Error(w, "Missing service_id", http.StatusBadRequest)
 			return
 		}
-		service := config.Services.Get(serviceId)
-		stop := checkAuth(w, r, config, service)
+		service, err := FindService(db, config, serviceId)
+		if err != nil {
+			logger.Errorw("Failed to find service", "service", serviceId, zap.Error(err))
+			http.Error(w, "Failed to find service", http.StatusInternalServerError)
+			return
+		}
+		stop := checkAuth(w, r, db, config, serviceId, service, storage.ScopeBeat(serviceId))
 		if stop {
 			return
 		}
+		if !limits.allowService(w, r, serviceId) {
+			return
+		}
+		if service == nil {
+			allowed, err := allowUnknownService(db, config, serviceId)
+			if err != nil {
+				logger.Errorw("Failed to check unknown services", zap.Error(err))
+				http.Error(w, "Failed to check service", http.StatusInternalServerError)
+				return
+			}
+			if !allowed {
+				rejectHeartbeat(ClientIp(r, config.RateLimit.TrustProxyHeaders), serviceId, REJECT_UNKNOWN_SERVICE_CAP, true)
+				http.Error(w, "Too many unknown services", http.StatusForbidden)
+				return
+			}
+		}
 
 		now := time.Now()
 		// Log the heartbeat to the database
@@ -98,6 +185,12 @@ func handleBeat(db storage.Storage, config *conf.Config) http.HandlerFunc {
 			http.Error(w, "Failed to log heartbeat", http.StatusInternalServerError)
 			return
 		}
+		metricsService := serviceId
+		if service == nil {
+			metricsService = METRICS_UNCONFIGURED_SERVICE
+		}
+		HeartbeatsTotal.WithLabelValues(metricsService).Inc()
+		Events.PublishHealthCheck(&storage.HealthCheckInput{ServiceId: serviceId, Timestamp: now})
 
 		response := HeartbeatResponse{
 			ServiceId: serviceId,
@@ -122,8 +215,13 @@ func handleStatus(db storage.Storage, config *conf.Config) http.HandlerFunc {
 			http.Error(w, "Missing service_id", http.StatusBadRequest)
 			return
 		}
-		service := config.Services.Get(serviceId)
-		stop := checkAuth(w, r, config, service)
+		service, err := FindService(db, config, serviceId)
+		if err != nil {
+			logger.Errorw("Failed to find service", "service", serviceId, zap.Error(err))
+			http.Error(w, "Failed to find service", http.StatusInternalServerError)
+			return
+		}
+		stop := checkAuth(w, r, db, config, serviceId, service, storage.ScopeBeat(serviceId), storage.SCOPE_READ_ALL)
 		if stop {
 			return
 		}
@@ -12,6 +12,7 @@ import (
 	"github.com/davidmasek/beacon/logging"
 	"github.com/davidmasek/beacon/monitor"
 	"github.com/davidmasek/beacon/storage"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -141,3 +142,111 @@ func TestHandleStatus_NoHeartbeat(t *testing.T) {
 	assert.Empty(t, statusResp.Timestamp)
 	assert.Equal(t, "never", statusResp.Message, "Expected 'never' when no heartbeats exist")
 }
+
+func TestHandleBeat_WithApiToken(t *testing.T) {
+	logging.InitTest(t)
+	db := storage.NewTestDb(t)
+	mux := http.NewServeMux()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	monitor.RegisterHeartbeatHandlers(db, mux, config)
+
+	beatToken, _, err := db.CreateApiToken("orange-beat", []string{storage.ScopeBeat("orange")}, time.Time{})
+	require.NoError(t, err)
+	readToken, _, err := db.CreateApiToken("reader", []string{storage.SCOPE_READ_ALL}, time.Time{})
+	require.NoError(t, err)
+
+	send := func(method string, url string, token string) int {
+		req := httptest.NewRequest(method, url, nil)
+		req.Header.Add("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// API token works instead of the token from config
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/services/orange/beat", beatToken))
+	// config token still works
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/services/orange/beat", "juiceM8"))
+	// scope is limited to a single service
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/services/banana/beat", beatToken))
+	// read scope does not allow sending heartbeats
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/services/orange/beat", readToken))
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/services/orange/status", readToken))
+	// unknown token falls back to config token check
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "/services/orange/beat", "bcn_unknown"))
+
+	hb, err := db.GetLatestHeartbeats("orange", 10)
+	require.NoError(t, err)
+	require.Len(t, hb, 2)
+
+	tokens, err := db.ListApiTokens()
+	require.NoError(t, err)
+	require.False(t, tokens[0].LastUsedAt.IsZero(), "last used time should be tracked")
+
+	err = db.RevokeApiToken(tokens[0].Id, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "/services/orange/beat", beatToken))
+}
+
+func TestHandleBeat_AdoptedService(t *testing.T) {
+	logging.InitTest(t)
+	db := storage.NewTestDb(t)
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	config.AllowUnknownHeartbeats = false
+	mux := http.NewServeMux()
+	monitor.RegisterHeartbeatHandlers(db, mux, config)
+
+	beat := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/services/stray/beat", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w.Code
+	}
+	require.Equal(t, http.StatusNotFound, beat())
+	err = db.AdoptService("stray", time.Hour, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, beat(), "adopted services are known")
+
+	services, err := monitor.AllServices(db, config)
+	require.NoError(t, err)
+	ids := []string{}
+	for _, service := range services {
+		ids = append(ids, service.Id)
+		require.False(t, service.Unconfigured)
+	}
+	require.Equal(t, []string{"banana", "orange", "stray"}, ids)
+}
+
+func TestHandleBeat_MetricsLabels(t *testing.T) {
+	logging.InitTest(t)
+	db := storage.NewTestDb(t)
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	require.True(t, config.AllowUnknownHeartbeats)
+	mux := http.NewServeMux()
+	monitor.RegisterHeartbeatHandlers(db, mux, config)
+	err = db.AdoptService("adopted-pear", time.Hour, time.Now())
+	require.NoError(t, err)
+
+	count := func(label string) float64 {
+		return testutil.ToFloat64(monitor.HeartbeatsTotal.WithLabelValues(label))
+	}
+	banana := count("banana")
+	adopted := count("adopted-pear")
+	unconfigured := count(monitor.METRICS_UNCONFIGURED_SERVICE)
+
+	for _, serviceId := range []string{"banana", "adopted-pear", "unknown-1", "unknown-2"} {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/services/%s/beat", serviceId), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, serviceId)
+	}
+
+	assert.Equal(t, banana+1, count("banana"))
+	assert.Equal(t, adopted+1, count("adopted-pear"))
+	assert.Equal(t, unconfigured+2, count(monitor.METRICS_UNCONFIGURED_SERVICE))
+	// no label created for unknown services
+	assert.False(t, monitor.HeartbeatsTotal.DeleteLabelValues("unknown-1"))
+}
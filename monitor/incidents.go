@@ -0,0 +1,37 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/davidmasek/beacon/logging"
+	"github.com/davidmasek/beacon/storage"
+)
+
+// Open incident when the service enters FAIL and resolve it when the service is OK again.
+// Other statuses keep the current incident, if any.
+// New incidents are not opened during maintenance, see conf.MaintenanceWindow.
+//
+// Returns the open incident, nil if none.
+func UpdateIncident(db storage.Storage, serviceId string, status ServiceStatus, inMaintenance bool, now time.Time) (*storage.Incident, error) {
+	logger := logging.Get()
+	incident, err := db.OpenServiceIncident(serviceId)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case status == STATUS_FAIL && incident == nil && !inMaintenance:
+		incident, err = db.OpenIncident(serviceId, now)
+		if err != nil {
+			return nil, err
+		}
+		logger.Infow("Incident opened", "service", serviceId, "incident", incident.Id)
+	case status == STATUS_OK && incident != nil:
+		err = db.ResolveIncident(incident.Id, now)
+		if err != nil {
+			return nil, err
+		}
+		logger.Infow("Incident resolved", "service", serviceId, "incident", incident.Id, "duration", incident.Duration(now))
+		incident = nil
+	}
+	return incident, nil
+}
@@ -0,0 +1,46 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/davidmasek/beacon/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateIncident(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// not opened during maintenance
+	incident, err := UpdateIncident(db, "api", STATUS_FAIL, true, now)
+	require.NoError(t, err)
+	require.Nil(t, incident)
+
+	incident, err = UpdateIncident(db, "api", STATUS_FAIL, false, now)
+	require.NoError(t, err)
+	require.NotNil(t, incident)
+	opened := incident.Id
+
+	// kept while failing or unknown, including maintenance started later
+	for _, status := range []ServiceStatus{STATUS_FAIL, STATUS_OTHER} {
+		incident, err = UpdateIncident(db, "api", status, true, now.Add(time.Minute))
+		require.NoError(t, err)
+		require.NotNil(t, incident)
+		assert.Equal(t, opened, incident.Id)
+	}
+
+	incident, err = UpdateIncident(db, "api", STATUS_OK, false, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Nil(t, incident)
+	resolved, err := db.GetIncident(opened)
+	require.NoError(t, err)
+	assert.False(t, resolved.IsOpen())
+	assert.Equal(t, time.Hour, resolved.Duration(now.Add(2*time.Hour)))
+
+	count, err := db.CountIncidents(storage.IncidentQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
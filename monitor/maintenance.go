@@ -0,0 +1,129 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/storage"
+)
+
+// Maintenance window defined by a window stored in DB
+func maintenanceWindowConfig(stored *storage.MaintenanceWindow) (conf.MaintenanceWindow, error) {
+	window := conf.MaintenanceWindow{
+		Id:       stored.Id,
+		Reason:   stored.Reason,
+		Services: stored.Services,
+		Tags:     stored.Tags,
+		Start:    stored.Start,
+		End:      stored.End,
+		At:       stored.At,
+		Duration: stored.Duration,
+	}
+	if stored.Days != "" {
+		err := window.Days.ParseString(stored.Days)
+		if err != nil {
+			return window, err
+		}
+	}
+	return window, nil
+}
+
+// All maintenance windows: windows from config, followed by windows
+// stored in DB (created using CLI or API).
+func MaintenanceWindows(db storage.Storage, config *conf.Config) ([]conf.MaintenanceWindow, error) {
+	windows := append([]conf.MaintenanceWindow{}, config.Maintenance...)
+	stored, err := db.ListMaintenanceWindows()
+	if err != nil {
+		return nil, err
+	}
+	for _, storedWindow := range stored {
+		window, err := maintenanceWindowConfig(storedWindow)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// Validate and store new maintenance window. Returns the stored window, including Id.
+func CreateMaintenanceWindow(db storage.Storage, window conf.MaintenanceWindow, now time.Time) (*conf.MaintenanceWindow, error) {
+	err := window.Validate()
+	if err != nil {
+		return nil, err
+	}
+	days := ""
+	if !window.Days.IsEmpty() {
+		days = window.Days.String()
+	}
+	stored, err := db.CreateMaintenanceWindow(&storage.MaintenanceWindow{
+		Reason:   window.Reason,
+		Services: window.Services,
+		Tags:     window.Tags,
+		Start:    window.Start,
+		End:      window.End,
+		Days:     days,
+		At:       window.At,
+		Duration: window.Duration,
+	}, now)
+	if err != nil {
+		return nil, err
+	}
+	created, err := maintenanceWindowConfig(stored)
+	return &created, err
+}
+
+// Windows selecting the service
+func ServiceMaintenance(windows []conf.MaintenanceWindow, service *conf.ServiceConfig) []conf.MaintenanceWindow {
+	selected := []conf.MaintenanceWindow{}
+	for _, window := range windows {
+		if window.Matches(service) {
+			selected = append(selected, window)
+		}
+	}
+	return selected
+}
+
+// First window in progress at given time, nil if none
+func ActiveMaintenance(windows []conf.MaintenanceWindow, now time.Time, loc *time.Location) *conf.MaintenanceWindow {
+	for i := range windows {
+		if windows[i].IsActive(now, loc) {
+			return &windows[i]
+		}
+	}
+	return nil
+}
+
+// Set status of intervals fully covered by the windows to STATUS_MAINTENANCE.
+// Such intervals count as neither up nor down, see SummarizeIntervals.
+// Intervals only partially in maintenance keep their status.
+func MarkMaintenance(intervals []IntervalStatus, windows []conf.MaintenanceWindow, loc *time.Location) {
+	if len(windows) == 0 {
+		return
+	}
+	for i := range intervals {
+		if inMaintenance(intervals[i].Interval, windows, loc) {
+			intervals[i].Status = STATUS_MAINTENANCE
+		}
+	}
+}
+
+// Check if the whole interval is covered by the windows,
+// possibly by several windows following each other
+func inMaintenance(interval Interval, windows []conf.MaintenanceWindow, loc *time.Location) bool {
+	covered := interval.Start
+	for covered.Before(interval.End) {
+		next := covered
+		for _, window := range windows {
+			until, ok := window.ActiveUntil(covered, loc)
+			if ok && until.After(next) {
+				next = until
+			}
+		}
+		if next.Equal(covered) {
+			return false
+		}
+		covered = next
+	}
+	return true
+}
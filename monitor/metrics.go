@@ -0,0 +1,123 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/logging"
+	"github.com/davidmasek/beacon/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const METRICS_NAMESPACE = "beacon"
+
+// Service label of heartbeats for services that are neither configured nor adopted,
+// so that anyone sending heartbeats cannot create unlimited label values.
+const METRICS_UNCONFIGURED_SERVICE = "unconfigured"
+
+// Metrics updated as events happen. Service state is read from the DB on scrape, see ServiceCollector.
+var (
+	HeartbeatsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: METRICS_NAMESPACE,
+		Name:      "heartbeats_total",
+		Help:      "Number of heartbeats received.",
+	}, []string{"service"})
+	WebChecksTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: METRICS_NAMESPACE,
+		Name:      "web_checks_total",
+		Help:      "Number of website checks performed.",
+	}, []string{"service", "status"})
+	WebCheckDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: METRICS_NAMESPACE,
+		Name:      "web_check_duration_seconds",
+		Help:      "Latency of website checks.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, DEFAULT_TIMEOUT},
+	}, []string{"service"})
+)
+
+var (
+	serviceUpDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(METRICS_NAMESPACE, "service", "up"),
+		"Whether the service is healthy (1) or not (0).",
+		[]string{"service"}, nil,
+	)
+	serviceLastCheckDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(METRICS_NAMESPACE, "service", "last_check_timestamp_seconds"),
+		"Time of the latest health check of the service.",
+		[]string{"service"}, nil,
+	)
+	taskRunsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(METRICS_NAMESPACE, "task", "runs_total"),
+		"Number of task runs by outcome. Decreases when old task logs are pruned.",
+		[]string{"task", "status"}, nil,
+	)
+	taskLastRunDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(METRICS_NAMESPACE, "task", "last_run_timestamp_seconds"),
+		"Time of the latest task run by outcome.",
+		[]string{"task", "status"}, nil,
+	)
+)
+
+// Collect service status and task outcomes from the DB on each scrape
+type ServiceCollector struct {
+	db     storage.Storage
+	config *conf.Config
+}
+
+func NewServiceCollector(db storage.Storage, config *conf.Config) *ServiceCollector {
+	return &ServiceCollector{db: db, config: config}
+}
+
+func (c *ServiceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- serviceUpDesc
+	ch <- serviceLastCheckDesc
+	ch <- taskRunsDesc
+	ch <- taskLastRunDesc
+}
+
+func (c *ServiceCollector) Collect(ch chan<- prometheus.Metric) {
+	logger := logging.Get()
+	now := time.Now()
+	for _, serviceCfg := range c.config.AllServices() {
+		if !serviceCfg.Enabled {
+			continue
+		}
+		checks, err := c.db.HealthChecksSince(serviceCfg.Id, now.Add(-serviceCfg.Timeout))
+		if err != nil {
+			logger.Errorw("Failed to load health checks", "service", serviceCfg.Id, zap.Error(err))
+			ch <- prometheus.NewInvalidMetric(serviceUpDesc, err)
+			continue
+		}
+		up := 0.0
+		if GetServiceStatus(serviceCfg, checks) == STATUS_OK {
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(serviceUpDesc, prometheus.GaugeValue, up, serviceCfg.Id)
+
+		latest, err := c.db.LatestHealthCheck(serviceCfg.Id)
+		if err != nil {
+			logger.Errorw("Failed to load latest health check", "service", serviceCfg.Id, zap.Error(err))
+			ch <- prometheus.NewInvalidMetric(serviceLastCheckDesc, err)
+			continue
+		}
+		// no sample rather than misleading zero timestamp
+		if latest != nil {
+			ch <- prometheus.MustNewConstMetric(serviceLastCheckDesc, prometheus.GaugeValue,
+				float64(latest.Timestamp.Unix()), serviceCfg.Id)
+		}
+	}
+
+	stats, err := c.db.TaskLogStats()
+	if err != nil {
+		logger.Errorw("Failed to load task stats", zap.Error(err))
+		ch <- prometheus.NewInvalidMetric(taskRunsDesc, err)
+		return
+	}
+	for _, stat := range stats {
+		ch <- prometheus.MustNewConstMetric(taskRunsDesc, prometheus.CounterValue,
+			float64(stat.Count), stat.TaskName, stat.Status)
+		ch <- prometheus.MustNewConstMetric(taskLastRunDesc, prometheus.GaugeValue,
+			float64(stat.LatestTimestamp.Unix()), stat.TaskName, stat.Status)
+	}
+}
@@ -0,0 +1,216 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/logging"
+	"github.com/davidmasek/beacon/storage"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Idle limiters are dropped after this long to keep memory bounded
+const RATE_LIMIT_CLEANUP_INTERVAL = 10 * time.Minute
+
+// Reasons for rejecting heartbeat requests, used in logs and metrics
+const (
+	REJECT_IP_RATE_LIMIT       = "ip_rate_limit"
+	REJECT_SERVICE_RATE_LIMIT  = "service_rate_limit"
+	REJECT_UNKNOWN_SERVICE_CAP = "unknown_service_cap"
+)
+
+var HeartbeatsRejectedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: METRICS_NAMESPACE,
+	Name:      "heartbeats_rejected_total",
+	Help:      "Number of rejected heartbeat requests by reason.",
+}, []string{"reason"})
+
+// Token bucket, refilled continuously at `rate` tokens per second up to `burst` tokens.
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	// true while requests are being rejected, used to log only the first rejection
+	limited bool
+}
+
+// Token bucket rate limiter for each key (such as IP address or service ID).
+// Nil limiter allows everything.
+type KeyedLimiter struct {
+	rate  float64
+	burst float64
+
+	mu          sync.Mutex
+	buckets     map[string]*tokenBucket
+	lastCleanup time.Time
+}
+
+// Create limiter allowing `perSecond` requests for each key on average,
+// with bursts up to `burst` requests. Returns nil (no limit) if perSecond is not positive.
+func NewKeyedLimiter(perSecond float64, burst int) *KeyedLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &KeyedLimiter{
+		rate:    perSecond,
+		burst:   math.Max(float64(burst), 1),
+		buckets: map[string]*tokenBucket{},
+	}
+}
+
+func (l *KeyedLimiter) refill(bucket *tokenBucket, now time.Time) {
+	elapsed := now.Sub(bucket.updated).Seconds()
+	if elapsed > 0 {
+		bucket.tokens = math.Min(l.burst, bucket.tokens+elapsed*l.rate)
+		bucket.updated = now
+	}
+}
+
+// Take a token for key. Returns if the request is allowed, and if not,
+// how long until the next one would be. `first` is true for the first
+// rejection after a period of allowed requests.
+func (l *KeyedLimiter) Allow(key string, now time.Time) (allowed bool, retryAfter time.Duration, first bool) {
+	if l == nil {
+		return true, 0, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cleanup(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets[key] = bucket
+	}
+	l.refill(bucket, now)
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		bucket.limited = false
+		return true, 0, false
+	}
+	first = !bucket.limited
+	bucket.limited = true
+	retryAfter = time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	return false, retryAfter, first
+}
+
+// Drop buckets that are full again, they behave the same as new ones.
+// Expects l.mu to be held.
+func (l *KeyedLimiter) cleanup(now time.Time) {
+	if now.Sub(l.lastCleanup) < RATE_LIMIT_CLEANUP_INTERVAL {
+		return
+	}
+	l.lastCleanup = now
+	for key, bucket := range l.buckets {
+		l.refill(bucket, now)
+		if bucket.tokens >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Address of the client, taken from proxy headers if trusted
+func ClientIp(r *http.Request, trustProxyHeaders bool) string {
+	if trustProxyHeaders {
+		if realIp := r.Header.Get("X-Real-IP"); realIp != "" {
+			return strings.TrimSpace(realIp)
+		}
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			// proxy appends the address it got the request from,
+			// anything before it was sent by the client and can be faked
+			addresses := strings.Split(forwarded, ",")
+			return strings.TrimSpace(addresses[len(addresses)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Rate limits shared by heartbeat endpoints
+type heartbeatLimits struct {
+	perIp             *KeyedLimiter
+	perService        *KeyedLimiter
+	trustProxyHeaders bool
+}
+
+func newHeartbeatLimits(config *conf.Config) *heartbeatLimits {
+	limits := config.RateLimit
+	return &heartbeatLimits{
+		perIp:             NewKeyedLimiter(limits.PerIp, limits.PerIpBurst),
+		perService:        NewKeyedLimiter(limits.PerService, limits.PerServiceBurst),
+		trustProxyHeaders: limits.TrustProxyHeaders,
+	}
+}
+
+// Reject requests over the per-IP limit with 429 Too Many Requests.
+// The per-service limit is checked by handleBeat after auth, see allowService.
+func (limits *heartbeatLimits) wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip := ClientIp(r, limits.trustProxyHeaders)
+		allowed, retryAfter, first := limits.perIp.Allow(ip, time.Now())
+		if !allowed {
+			rejectHeartbeat(ip, r.PathValue("service_id"), REJECT_IP_RATE_LIMIT, first)
+			tooManyRequests(w, retryAfter)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Take a token from the per-service limit, responding with 429 Too Many Requests if over it.
+// Called only for authorized heartbeats, so that others cannot use up the limit of a service.
+func (limits *heartbeatLimits) allowService(w http.ResponseWriter, r *http.Request, serviceId string) bool {
+	allowed, retryAfter, first := limits.perService.Allow(serviceId, time.Now())
+	if !allowed {
+		rejectHeartbeat(ClientIp(r, limits.trustProxyHeaders), serviceId, REJECT_SERVICE_RATE_LIMIT, first)
+		tooManyRequests(w, retryAfter)
+	}
+	return allowed
+}
+
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(retryAfter.Seconds()))))
+	http.Error(w, "Too many requests", http.StatusTooManyRequests)
+}
+
+// Count rejected request. Logged only if `log` is set, to avoid flooding logs during abuse.
+func rejectHeartbeat(ip string, serviceId string, reason string, log bool) {
+	HeartbeatsRejectedTotal.WithLabelValues(reason).Inc()
+	if log {
+		logging.Get().Warnw("Rejecting heartbeat requests", "reason", reason, "ip", ip, "service", serviceId)
+	}
+}
+
+// Check if a heartbeat for a service that is not configured nor adopted can be stored
+// without going over config.RateLimit.MaxUnknownServices.
+// Services that already have health checks are always allowed.
+func allowUnknownService(db storage.Storage, config *conf.Config, serviceId string) (bool, error) {
+	maxUnknown := config.RateLimit.MaxUnknownServices
+	if maxUnknown <= 0 {
+		return true, nil
+	}
+	known, err := db.HasHealthChecks(serviceId)
+	if err != nil || known {
+		return known, err
+	}
+	// only for the first heartbeat of a new service
+	services, err := AllServices(db, config)
+	if err != nil {
+		return false, err
+	}
+	unknown := 0
+	for _, service := range services {
+		if service.Unconfigured {
+			unknown++
+		}
+	}
+	return unknown < maxUnknown, nil
+}
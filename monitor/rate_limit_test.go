@@ -0,0 +1,159 @@
+package monitor_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/logging"
+	"github.com/davidmasek/beacon/monitor"
+	"github.com/davidmasek/beacon/storage"
+)
+
+func TestKeyedLimiter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := monitor.NewKeyedLimiter(2, 3)
+
+	for range 3 {
+		allowed, _, _ := limiter.Allow("a", now)
+		require.True(t, allowed)
+	}
+	allowed, retryAfter, first := limiter.Allow("a", now)
+	require.False(t, allowed, "burst used up")
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+	assert.True(t, first)
+	allowed, _, first = limiter.Allow("a", now)
+	require.False(t, allowed)
+	assert.False(t, first, "only first rejection is reported")
+
+	allowed, _, _ = limiter.Allow("b", now)
+	require.True(t, allowed, "keys are limited independently")
+
+	// 2 per second -> one token after 500ms
+	allowed, _, _ = limiter.Allow("a", now.Add(500*time.Millisecond))
+	require.True(t, allowed)
+	allowed, _, _ = limiter.Allow("a", now.Add(500*time.Millisecond))
+	require.False(t, allowed)
+
+	// refill does not go over burst
+	later := now.Add(time.Hour)
+	for range 3 {
+		allowed, _, _ = limiter.Allow("a", later)
+		require.True(t, allowed)
+	}
+	allowed, _, _ = limiter.Allow("a", later)
+	require.False(t, allowed)
+
+	var disabled *monitor.KeyedLimiter = monitor.NewKeyedLimiter(0, 10)
+	require.Nil(t, disabled)
+	allowed, _, _ = disabled.Allow("a", now)
+	require.True(t, allowed)
+}
+
+func TestClientIp(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/services/x/beat", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	// client sent the first address, proxy appended the second
+	req.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7")
+	assert.Equal(t, "10.0.0.1", monitor.ClientIp(req, false))
+	assert.Equal(t, "203.0.113.7", monitor.ClientIp(req, true))
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	assert.Equal(t, "203.0.113.9", monitor.ClientIp(req, true))
+	req.Header.Set("X-Real-IP", "203.0.113.8")
+	assert.Equal(t, "203.0.113.8", monitor.ClientIp(req, true))
+}
+
+func TestHeartbeatRateLimit(t *testing.T) {
+	logging.InitTest(t)
+	db := storage.NewTestDb(t)
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	config.RateLimit = conf.RateLimitConfig{
+		PerIp:           0.001,
+		PerIpBurst:      5,
+		PerService:      0.001,
+		PerServiceBurst: 2,
+	}
+	mux := http.NewServeMux()
+	monitor.RegisterHeartbeatHandlers(db, mux, config)
+
+	sendWithToken := func(ip string, serviceId string, action string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/services/%s/%s", serviceId, action), nil)
+		req.RemoteAddr = ip + ":5555"
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	send := func(ip string, serviceId string, action string) *httptest.ResponseRecorder {
+		return sendWithToken(ip, serviceId, action, "")
+	}
+	rejectedIp := testutil.ToFloat64(monitor.HeartbeatsRejectedTotal.WithLabelValues(monitor.REJECT_IP_RATE_LIMIT))
+	rejectedService := testutil.ToFloat64(monitor.HeartbeatsRejectedTotal.WithLabelValues(monitor.REJECT_SERVICE_RATE_LIMIT))
+
+	require.Equal(t, http.StatusOK, send("10.0.0.1", "banana", "status").Code)
+	require.Equal(t, http.StatusOK, send("10.0.0.2", "banana", "beat").Code, "status does not count towards service limit")
+	require.Equal(t, http.StatusOK, send("10.0.0.3", "banana", "beat").Code)
+	rr := send("10.0.0.3", "banana", "beat")
+	require.Equal(t, http.StatusTooManyRequests, rr.Code, "per service limit")
+	require.NotEmpty(t, rr.Header().Get("Retry-After"))
+
+	// requests with bad token do not use up the service limit
+	for i := range 5 {
+		ip := fmt.Sprintf("10.0.1.%d", i)
+		require.Equal(t, http.StatusUnauthorized, sendWithToken(ip, "orange", "beat", "guess").Code)
+		require.Equal(t, http.StatusUnauthorized, sendWithToken(ip, "orange", "beat", "").Code)
+	}
+	require.Equal(t, http.StatusOK, sendWithToken("10.0.0.4", "orange", "beat", "juiceM8").Code)
+
+	// uses up the remaining IP burst, first request above counts as well
+	for i := range 4 {
+		require.Equal(t, http.StatusOK, send("10.0.0.1", fmt.Sprintf("svc-%d", i), "beat").Code)
+	}
+	require.Equal(t, http.StatusTooManyRequests, send("10.0.0.1", "other", "beat").Code, "per IP limit")
+	require.Equal(t, http.StatusOK, send("10.0.0.2", "other", "beat").Code)
+
+	assert.Equal(t, rejectedIp+1, testutil.ToFloat64(monitor.HeartbeatsRejectedTotal.WithLabelValues(monitor.REJECT_IP_RATE_LIMIT)))
+	assert.Equal(t, rejectedService+1, testutil.ToFloat64(monitor.HeartbeatsRejectedTotal.WithLabelValues(monitor.REJECT_SERVICE_RATE_LIMIT)))
+}
+
+func TestMaxUnknownServices(t *testing.T) {
+	logging.InitTest(t)
+	db := storage.NewTestDb(t)
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	config.RateLimit = conf.RateLimitConfig{MaxUnknownServices: 2}
+	mux := http.NewServeMux()
+	monitor.RegisterHeartbeatHandlers(db, mux, config)
+
+	beat := func(serviceId string) int {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/services/%s/beat", serviceId), nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	rejected := testutil.ToFloat64(monitor.HeartbeatsRejectedTotal.WithLabelValues(monitor.REJECT_UNKNOWN_SERVICE_CAP))
+
+	require.Equal(t, http.StatusOK, beat("unknown-1"))
+	require.Equal(t, http.StatusOK, beat("unknown-2"))
+	require.Equal(t, http.StatusForbidden, beat("unknown-3"))
+	// existing unknown services keep working
+	require.Equal(t, http.StatusOK, beat("unknown-1"))
+	// configured services do not count towards the limit
+	require.Equal(t, http.StatusOK, beat("banana"))
+	require.Equal(t, http.StatusForbidden, beat("unknown-4"))
+
+	assert.Equal(t, rejected+2, testutil.ToFloat64(monitor.HeartbeatsRejectedTotal.WithLabelValues(monitor.REJECT_UNKNOWN_SERVICE_CAP)))
+	services, err := db.ListServices()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"banana", "unknown-1", "unknown-2"}, services)
+}
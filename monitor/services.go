@@ -0,0 +1,75 @@
+package monitor
+
+import (
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/storage"
+)
+
+// Service defined by an adopted service stored in DB
+func adoptedServiceConfig(adopted *storage.AdoptedService) (*conf.ServiceConfig, error) {
+	service, err := conf.NewServiceConfig(adopted.ServiceId, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	service.Timeout = adopted.Timeout
+	service.Adopted = true
+	return service, nil
+}
+
+// All known services: services from config, followed by adopted services
+// and services that sent heartbeats without being defined anywhere (marked as Unconfigured).
+// Config takes precedence over adopted services with the same ID.
+func AllServices(db storage.Storage, config *conf.Config) ([]conf.ServiceConfig, error) {
+	services := append([]conf.ServiceConfig{}, config.AllServices()...)
+	known := map[string]bool{}
+	for _, service := range services {
+		known[service.Id] = true
+	}
+
+	adopted, err := db.ListAdoptedServices()
+	if err != nil {
+		return nil, err
+	}
+	for _, adoptedService := range adopted {
+		if known[adoptedService.ServiceId] {
+			continue
+		}
+		service, err := adoptedServiceConfig(adoptedService)
+		if err != nil {
+			return nil, err
+		}
+		known[service.Id] = true
+		services = append(services, *service)
+	}
+
+	seen, err := db.ListServices()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range seen {
+		if known[id] {
+			continue
+		}
+		service, err := conf.NewServiceConfig(id, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		service.Unconfigured = true
+		services = append(services, *service)
+	}
+	return services, nil
+}
+
+// Find service defined in config or adopted. Returns nil if not found.
+// Does not return unconfigured services, see AllServices.
+func FindService(db storage.Storage, config *conf.Config, serviceId string) (*conf.ServiceConfig, error) {
+	service := config.Services.Get(serviceId)
+	if service != nil {
+		return service, nil
+	}
+	adopted, err := db.GetAdoptedService(serviceId)
+	if err != nil || adopted == nil {
+		return nil, err
+	}
+	return adoptedServiceConfig(adopted)
+}
@@ -41,23 +41,51 @@ func BuildStatusIntervals(checks []*storage.HealthCheck, from, to time.Time, int
 	return intervals
 }
 
+// Percentage of intervals that were up and down.
+// Maintenance intervals count as neither, see MarkMaintenance.
 func SummarizeIntervals(intervals []IntervalStatus) (upPct, downPct float64) {
-	if len(intervals) == 0 {
-		return 0, 0
-	}
-
 	var up, down int
 	for _, interval := range intervals {
 		switch interval.Status {
-		case "OK":
+		case STATUS_OK:
 			up++
+		case STATUS_MAINTENANCE:
 		default:
 			down++
 		}
 	}
 
 	total := up + down
+	if total == 0 {
+		return 0, 0
+	}
 	upPct = float64(up) * 100 / float64(total)
 	downPct = 100 - upPct
 	return
 }
+
+// Merge consecutive intervals that are not OK into outages.
+// Maintenance is not an outage.
+// Intervals must be sorted and adjacent, as returned by BuildStatusIntervals.
+func FindOutages(intervals []IntervalStatus) []Interval {
+	outages := []Interval{}
+	var current *Interval
+	for _, interval := range intervals {
+		if interval.Status == STATUS_OK || interval.Status == STATUS_MAINTENANCE {
+			if current != nil {
+				outages = append(outages, *current)
+				current = nil
+			}
+			continue
+		}
+		if current == nil {
+			current = &Interval{Start: interval.Interval.Start, End: interval.Interval.End}
+		} else {
+			current.End = interval.Interval.End
+		}
+	}
+	if current != nil {
+		outages = append(outages, *current)
+	}
+	return outages
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/davidmasek/beacon/conf"
 	"github.com/davidmasek/beacon/monitor"
 	"github.com/davidmasek/beacon/storage"
 	"github.com/stretchr/testify/assert"
@@ -88,3 +89,73 @@ func TestBuildStatusIntervals(t *testing.T) {
 		})
 	}
 }
+
+func TestFindOutages(t *testing.T) {
+	start := mustParse("2025-01-01T00:00:00Z")
+	statuses := []monitor.ServiceStatus{
+		monitor.STATUS_FAIL, monitor.STATUS_OK, monitor.STATUS_OK,
+		monitor.STATUS_FAIL, monitor.STATUS_OTHER, monitor.STATUS_OK,
+		monitor.STATUS_FAIL,
+	}
+	intervals := []monitor.IntervalStatus{}
+	for i, status := range statuses {
+		ts := start.Add(time.Duration(i) * time.Hour)
+		intervals = append(intervals, monitor.IntervalStatus{
+			Interval: monitor.Interval{Start: ts, End: ts.Add(time.Hour)},
+			Status:   status,
+		})
+	}
+
+	outages := monitor.FindOutages(intervals)
+	assert.Equal(t, []monitor.Interval{
+		{Start: start, End: start.Add(time.Hour)},
+		{Start: start.Add(3 * time.Hour), End: start.Add(5 * time.Hour)},
+		{Start: start.Add(6 * time.Hour), End: start.Add(7 * time.Hour)},
+	}, outages)
+
+	assert.Empty(t, monitor.FindOutages(nil))
+}
+
+func TestMaintenanceIntervals(t *testing.T) {
+	start := mustParse("2025-01-01T00:00:00Z")
+	intervals := []monitor.IntervalStatus{}
+	for i, status := range []monitor.ServiceStatus{
+		monitor.STATUS_OK, monitor.STATUS_FAIL, monitor.STATUS_FAIL, monitor.STATUS_OK,
+	} {
+		ts := start.Add(time.Duration(i) * time.Hour)
+		intervals = append(intervals, monitor.IntervalStatus{
+			Interval: monitor.Interval{Start: ts, End: ts.Add(time.Hour)},
+			Status:   status,
+		})
+	}
+	up, down := monitor.SummarizeIntervals(intervals)
+	assert.Equal(t, 50.0, up)
+	assert.Equal(t, 50.0, down)
+
+	// covers the second interval, together with the recurring window,
+	// and the third interval only partially
+	windows := []conf.MaintenanceWindow{{
+		Services: []string{"app"},
+		Start:    start.Add(90 * time.Minute),
+		End:      start.Add(150 * time.Minute),
+	}, {
+		Services: []string{"app"},
+		At:       "01:00",
+		Duration: 30 * time.Minute,
+	}}
+	monitor.MarkMaintenance(intervals, windows, time.UTC)
+	assert.Equal(t, monitor.STATUS_OK, intervals[0].Status)
+	assert.Equal(t, monitor.STATUS_MAINTENANCE, intervals[1].Status)
+	assert.Equal(t, monitor.STATUS_FAIL, intervals[2].Status)
+
+	// maintenance counts as neither up nor down
+	up, down = monitor.SummarizeIntervals(intervals)
+	assert.InDelta(t, 66.67, up, 0.01)
+	assert.InDelta(t, 33.33, down, 0.01)
+	assert.Equal(t, []monitor.Interval{
+		{Start: start.Add(2 * time.Hour), End: start.Add(3 * time.Hour)},
+	}, monitor.FindOutages(intervals))
+
+	up, down = monitor.SummarizeIntervals(intervals[1:2])
+	assert.Equal(t, 0.0, up+down, "only maintenance, no data")
+}
@@ -15,6 +15,8 @@ const (
 	STATUS_FAIL ServiceStatus = "FAIL"
 	// e.g. unable to decide, not enough data, error in the check
 	STATUS_OTHER ServiceStatus = "OTHER"
+	// planned maintenance, see conf.MaintenanceWindow
+	STATUS_MAINTENANCE ServiceStatus = "MAINTENANCE"
 )
 
 func HealthCheckStatus(hc *storage.HealthCheck) ServiceStatus {
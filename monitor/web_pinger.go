@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/davidmasek/beacon/conf"
@@ -24,6 +25,9 @@ type webConfig struct {
 
 const DEFAULT_TIMEOUT = 5
 
+// Health check metadata key with website response time in milliseconds
+const LATENCY_METADATA_KEY = "latency_ms"
+
 // Check websites and save the resulting HealthChecks to storage
 func CheckWebServices(db storage.Storage, services []conf.ServiceConfig) error {
 	logger := logging.Get()
@@ -46,8 +50,12 @@ func CheckWebServices(db storage.Storage, services []conf.ServiceConfig) error {
 			HttpStatus:  service.HttpStatus,
 			BodyContent: service.BodyContent,
 		})
+		latency := time.Since(timestamp)
+		WebCheckDuration.WithLabelValues(service.Id).Observe(latency.Seconds())
+		WebChecksTotal.WithLabelValues(service.Id, string(serviceStatus)).Inc()
 		metadata := make(map[string]string)
 		metadata["status"] = string(serviceStatus)
+		metadata[LATENCY_METADATA_KEY] = strconv.FormatInt(latency.Milliseconds(), 10)
 		if err != nil {
 			logger.Error(err)
 			metadata["error"] = err.Error()
@@ -65,6 +73,7 @@ func CheckWebServices(db storage.Storage, services []conf.ServiceConfig) error {
 			logger.Errorw("Unable to save HealthCheck", zap.Error(err), "healthCheck", healthCheck, "service", service)
 			return err
 		}
+		Events.PublishHealthCheck(healthCheck)
 	}
 	return nil
 }
@@ -6,21 +6,49 @@ import (
 	"html/template"
 	"io"
 	"os"
+	textTemplate "text/template"
 
+	"github.com/davidmasek/beacon/conf"
 	"github.com/davidmasek/beacon/logging"
 )
 
-//go:embed report.template.html
+//go:embed report.template.html report.template.txt
 var templateFs embed.FS
 
-// Write HTML Hearbeat report to `wr`
+// Reports of services in the same group, see conf.ServiceConfig.Group
+type ReportGroup struct {
+	// Empty for ungrouped services
+	Name    string
+	Reports []ServiceReport
+}
+
+// Split reports by service group, in the order of conf.ServiceGroups
+func GroupReports(reports []ServiceReport) []ReportGroup {
+	services := []conf.ServiceConfig{}
+	for _, report := range reports {
+		services = append(services, report.ServiceCfg)
+	}
+	groups := []ReportGroup{}
+	for _, name := range conf.ServiceGroups(services) {
+		group := ReportGroup{Name: name}
+		for _, report := range reports {
+			if report.ServiceCfg.Group == name {
+				group.Reports = append(group.Reports, report)
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// Write HTML Hearbeat report to `wr`, with a section for each service group
 func WriteReport(reports []ServiceReport, wr io.Writer) error {
 	t, err := template.ParseFS(templateFs, "report.template.html")
 	if err != nil {
 		return err
 	}
 
-	err = t.Execute(wr, reports)
+	err = t.Execute(wr, GroupReports(reports))
 	if err != nil {
 		return err
 	}
@@ -28,6 +56,15 @@ func WriteReport(reports []ServiceReport, wr io.Writer) error {
 	return nil
 }
 
+// Write plain text version of the report to `wr`, see WriteReport
+func WriteTextReport(reports []ServiceReport, wr io.Writer) error {
+	t, err := textTemplate.ParseFS(templateFs, "report.template.txt")
+	if err != nil {
+		return err
+	}
+	return t.Execute(wr, GroupReports(reports))
+}
+
 func WriteReportToFile(reports []ServiceReport, filename string) error {
 	logger := logging.Get()
 	logger.Infow("Writing report to file", "path", filename)
@@ -21,13 +21,17 @@ type ServiceReport struct {
 }
 
 func sendReport(reports []ServiceReport, emailConfig *conf.EmailConfig) error {
-	var buffer bytes.Buffer
+	var buffer, textBuffer bytes.Buffer
 	logger := logging.Get()
 	logger.Info("Generating report")
 	err := WriteReport(reports, &buffer)
 	if err != nil {
 		return err
 	}
+	err = WriteTextReport(reports, &textBuffer)
+	if err != nil {
+		return err
+	}
 
 	prefix := emailConfig.Prefix
 	// add whitespace after prefix if it exists and is not included already
@@ -55,27 +59,55 @@ func sendReport(reports []ServiceReport, emailConfig *conf.EmailConfig) error {
 		emailConfig,
 		subject,
 		buffer.String(),
+		textBuffer.String(),
 	)
 	return err
 }
 
-func SendMail(emailConfig *conf.EmailConfig, subject string, body string) error {
+// Build email with plain text body and HTML alternative
+func newMessage(emailConfig *conf.EmailConfig, subject string, htmlBody string, textBody string) (*mail.Msg, error) {
+	message := mail.NewMsg()
+	if err := message.From(emailConfig.Sender); err != nil {
+		return nil, err
+	}
+	if err := message.To(emailConfig.SendTo...); err != nil {
+		return nil, err
+	}
+	if len(emailConfig.Cc) > 0 {
+		if err := message.Cc(emailConfig.Cc...); err != nil {
+			return nil, err
+		}
+	}
+	if len(emailConfig.Bcc) > 0 {
+		if err := message.Bcc(emailConfig.Bcc...); err != nil {
+			return nil, err
+		}
+	}
+	if emailConfig.ReplyTo != "" {
+		if err := message.ReplyTo(emailConfig.ReplyTo); err != nil {
+			return nil, err
+		}
+	}
+	message.Subject(subject)
+	// clients show the last alternative they support, so HTML goes last
+	message.SetBodyString(mail.TypeTextPlain, textBody)
+	message.AddAlternativeString(mail.TypeTextHTML, htmlBody)
+	return message, nil
+}
+
+// Send email with HTML body and its plain text version
+func SendMail(emailConfig *conf.EmailConfig, subject string, htmlBody string, textBody string) error {
 	logger := logging.Get()
-	logger.Infow("Sending email", "subject", subject, "to", emailConfig.SendTo)
-	if emailConfig.SendTo == "DEBUG" {
+	logger.Infow("Sending email", "subject", subject, "to", emailConfig.SendTo, "cc", emailConfig.Cc)
+	if emailConfig.IsDebug() {
 		logger.Warn("Not sending email because send_to is set to DEBUG")
 		return nil
 	}
 
-	message := mail.NewMsg()
-	if err := message.From(emailConfig.Sender); err != nil {
-		return err
-	}
-	if err := message.To(emailConfig.SendTo); err != nil {
+	message, err := newMessage(emailConfig, subject, htmlBody, textBody)
+	if err != nil {
 		return err
 	}
-	message.Subject(subject)
-	message.SetBodyString(mail.TypeTextHTML, body)
 
 	client, err := mail.NewClient(emailConfig.SmtpServer, mail.WithSMTPAuth(mail.SMTPAuthPlain),
 		mail.WithUsername(emailConfig.SmtpUsername), mail.WithPassword(emailConfig.SmtpPassword.Get()),
@@ -0,0 +1,290 @@
+package reporting
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Email received by testSmtpServer
+type testSmtpMessage struct {
+	From       string
+	Recipients []string
+	Data       []byte
+}
+
+// Minimal SMTP server (STARTTLS, AUTH PLAIN) recording received emails.
+// Recipients at invalid.example.com are rejected.
+type testSmtpServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+	mu        sync.Mutex
+	messages  []testSmtpMessage
+}
+
+func selfSignedCert(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func startTestSmtpServer(t *testing.T) *testSmtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &testSmtpServer{
+		listener:  listener,
+		tlsConfig: &tls.Config{Certificates: []tls.Certificate{selfSignedCert(t)}},
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+// Email settings for sending to this server
+func (s *testSmtpServer) emailConfig() conf.EmailConfig {
+	return conf.EmailConfig{
+		SmtpServer:   "127.0.0.1",
+		SmtpPort:     s.listener.Addr().(*net.TCPAddr).Port,
+		SmtpUsername: "beacon",
+		SmtpPassword: conf.Secret{Value: "password"},
+		Sender:       "beacon@example.com",
+		SendTo:       conf.Addresses{"ops@example.com"},
+		TlsInsecure:  "always",
+	}
+}
+
+func (s *testSmtpServer) Messages() []testSmtpMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]testSmtpMessage{}, s.messages...)
+}
+
+func (s *testSmtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	message := testSmtpMessage{}
+	isTls := false
+	text.PrintfLine("220 localhost ESMTP test")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		command, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(command) {
+		case "EHLO":
+			if isTls {
+				text.PrintfLine("250-localhost\r\n250 AUTH PLAIN")
+			} else {
+				text.PrintfLine("250-localhost\r\n250 STARTTLS")
+			}
+		case "STARTTLS":
+			text.PrintfLine("220 ready")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			conn = tlsConn
+			text = textproto.NewConn(conn)
+			isTls = true
+		case "AUTH":
+			text.PrintfLine("235 authenticated")
+		case "MAIL":
+			message = testSmtpMessage{From: addressArg(arg)}
+			text.PrintfLine("250 OK")
+		case "RCPT":
+			if strings.HasSuffix(addressArg(arg), "@invalid.example.com") {
+				text.PrintfLine("550 no such user")
+				continue
+			}
+			message.Recipients = append(message.Recipients, addressArg(arg))
+			text.PrintfLine("250 OK")
+		case "DATA":
+			text.PrintfLine("354 send data")
+			message.Data, err = text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, message)
+			s.mu.Unlock()
+			text.PrintfLine("250 OK")
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("250 OK")
+		}
+	}
+}
+
+// "TO:<user@example.com>" -> "user@example.com"
+func addressArg(arg string) string {
+	_, address, _ := strings.Cut(arg, ":")
+	address, _, _ = strings.Cut(address, " ")
+	return strings.Trim(address, "<>")
+}
+
+// Parts of multipart/alternative email by content type
+func alternativeParts(t *testing.T, msg *mail.Message) map[string]string {
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	parts := map[string]string{}
+	order := []string{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		partType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		parts[partType] = string(body)
+		order = append(order, partType)
+	}
+	require.Equal(t, []string{"text/plain", "text/html"}, order, "HTML should be the preferred (last) alternative")
+	return parts
+}
+
+func TestSendMail(t *testing.T) {
+	server := startTestSmtpServer(t)
+	emailConfig := server.emailConfig()
+	emailConfig.SendTo = conf.Addresses{"ops@example.com", "Dev Team <dev@example.com>"}
+	emailConfig.Cc = conf.Addresses{"boss@example.com"}
+	emailConfig.Bcc = conf.Addresses{"archive@example.com"}
+	emailConfig.ReplyTo = "support@example.com"
+
+	err := SendMail(&emailConfig, "Test subject", "<p>Hello <b>HTML</b></p>", "Hello text")
+	require.NoError(t, err)
+
+	messages := server.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "beacon@example.com", messages[0].From)
+	assert.ElementsMatch(t, []string{"ops@example.com", "dev@example.com", "boss@example.com", "archive@example.com"}, messages[0].Recipients)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(messages[0].Data))
+	require.NoError(t, err)
+	assert.Equal(t, "Test subject", msg.Header.Get("Subject"))
+	to, err := msg.Header.AddressList("To")
+	require.NoError(t, err)
+	require.Len(t, to, 2)
+	assert.Equal(t, "Dev Team", to[1].Name)
+	assert.Contains(t, msg.Header.Get("Cc"), "boss@example.com")
+	assert.Empty(t, msg.Header.Get("Bcc"), "bcc must not be visible to recipients")
+	assert.Contains(t, msg.Header.Get("Reply-To"), "support@example.com")
+
+	parts := alternativeParts(t, msg)
+	assert.Equal(t, "Hello text", strings.TrimSpace(parts["text/plain"]))
+	assert.Contains(t, parts["text/html"], "<b>HTML</b>")
+}
+
+func TestSendReportsToTargets(t *testing.T) {
+	server := startTestSmtpServer(t)
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes([]byte(`
+notification_targets:
+  dba:
+    send_to: [dba@example.com, dba-lead@example.com]
+    cc: cto@example.com
+alert_routes:
+  - tags: [db]
+    targets: [dba]
+services:
+  postgres:
+    tags: [db]
+    group: Databases
+  website:
+`))
+	require.NoError(t, err)
+	config.EmailConf = server.emailConfig()
+	config.EmailConf.Cc = conf.Addresses{"cc@example.com"}
+	now := time.Now()
+
+	reports, err := GenerateReport(db, config)
+	require.NoError(t, err)
+	err = FailsReportJob(reports, db, config, now)
+	require.NoError(t, err)
+
+	messages := server.Messages()
+	require.Len(t, messages, 2)
+	recipients := map[string][]string{}
+	bodies := map[string]map[string]string{}
+	for _, message := range messages {
+		msg, err := mail.ReadMessage(bytes.NewReader(message.Data))
+		require.NoError(t, err)
+		subject := msg.Header.Get("Subject")
+		recipients[subject] = message.Recipients
+		bodies[subject] = alternativeParts(t, msg)
+	}
+	postgresSubject := `Beacon: Service "postgres" failed!`
+	assert.ElementsMatch(t, []string{"dba@example.com", "dba-lead@example.com", "cto@example.com"}, recipients[postgresSubject])
+	assert.ElementsMatch(t, []string{"ops@example.com", "cc@example.com"}, recipients[`Beacon: Service "website" failed!`])
+	assert.Equal(t, postgresSubject, strings.TrimSpace(bodies[postgresSubject]["text/plain"]))
+	assert.Contains(t, bodies[postgresSubject]["text/html"], "&#34;postgres&#34;")
+
+	// summary report, split by target
+	config.ReportName = filepath.Join(t.TempDir(), "report")
+	err = SaveSendReport(reports, db, config, now)
+	require.NoError(t, err)
+	messages = server.Messages()[2:]
+	require.Len(t, messages, 2)
+	msg, err := mail.ReadMessage(bytes.NewReader(messages[0].Data))
+	require.NoError(t, err)
+	assert.Equal(t, "Beacon: Service(s) Failed [0/1]", msg.Header.Get("Subject"))
+	parts := alternativeParts(t, msg)
+	assert.Contains(t, parts["text/plain"], "Databases\n\n- postgres: FAIL")
+	assert.NotContains(t, parts["text/plain"], "website")
+	assert.Contains(t, parts["text/html"], "<h1>Beacon: Status Report</h1>")
+
+	// subject depends on severity
+	err = ReportFailedService(db, config, config.Services.Get("website"), conf.SEVERITY_WARNING, now)
+	require.NoError(t, err)
+	messages = server.Messages()[4:]
+	require.Len(t, messages, 1)
+	msg, err = mail.ReadMessage(bytes.NewReader(messages[0].Data))
+	require.NoError(t, err)
+	assert.Equal(t, `Beacon: Service "website" is not OK`, msg.Header.Get("Subject"))
+}
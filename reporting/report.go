@@ -3,6 +3,8 @@ package reporting
 import (
 	"errors"
 	"fmt"
+	"html"
+	"slices"
 	"strings"
 	"time"
 
@@ -18,7 +20,10 @@ func GenerateReport(db storage.Storage, config *conf.Config) ([]ServiceReport, e
 	logger := logging.Get()
 	reports := make([]ServiceReport, 0)
 
-	services := config.AllServices()
+	services, err := monitor.AllServices(db, config)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, service := range services {
 
@@ -65,8 +70,11 @@ func SaveSendReport(reports []ServiceReport, db storage.Storage, config *conf.Co
 
 	shouldSendEmail := config.EmailConf.IsEnabled()
 	if shouldSendEmail {
-		emailErr := sendReport(reports, &config.EmailConf)
-		err = errors.Join(err, emailErr)
+		for _, targetReports := range SplitReports(reports, config) {
+			emailConf := targetReports.Target.EmailConfig(config.EmailConf)
+			emailErr := sendReport(targetReports.Reports, &emailConf)
+			err = errors.Join(err, emailErr)
+		}
 	}
 
 	status := storage.TASK_OK
@@ -81,7 +89,47 @@ func SaveSendReport(reports []ServiceReport, db storage.Storage, config *conf.Co
 	return errors.Join(err, dbErr)
 }
 
-func ReportFailedService(db storage.Storage, config *conf.Config, serviceCfg *conf.ServiceConfig, now time.Time) error {
+// Reports of services routed to a notification target, see SplitReports
+type TargetReports struct {
+	Target  conf.NotificationTarget
+	Reports []ServiceReport
+}
+
+// Split summary reports by notification target, see conf.Config.AlertTargets.
+// Each target gets reports of services routed to it with SEVERITY_INFO.
+func SplitReports(reports []ServiceReport, config *conf.Config) []TargetReports {
+	split := []TargetReports{}
+	for _, report := range reports {
+		for _, target := range config.AlertTargets(&report.ServiceCfg, conf.SEVERITY_INFO) {
+			idx := slices.IndexFunc(split, func(t TargetReports) bool {
+				return t.Target.Name == target.Name
+			})
+			if idx < 0 {
+				split = append(split, TargetReports{Target: target})
+				idx = len(split) - 1
+			}
+			split[idx].Reports = append(split[idx].Reports, report)
+		}
+	}
+	return split
+}
+
+// Severity of alert about a service with given status
+func AlertSeverity(status monitor.ServiceStatus) string {
+	if status == monitor.STATUS_FAIL {
+		return conf.SEVERITY_CRITICAL
+	}
+	return conf.SEVERITY_WARNING
+}
+
+// Send alert about failed service to targets from alert routes
+func ReportFailedService(db storage.Storage, config *conf.Config, serviceCfg *conf.ServiceConfig, severity string, now time.Time) error {
+	return notifyFailedService(db, config, serviceCfg, severity, config.AlertTargets(serviceCfg, severity), now)
+}
+
+// Send alert about failed service to given targets and log the "report_fail" task
+func notifyFailedService(db storage.Storage, config *conf.Config, serviceCfg *conf.ServiceConfig, severity string, targets []conf.NotificationTarget, now time.Time) error {
+	logger := logging.Get()
 	var err error
 	shouldSendEmail := config.EmailConf.IsEnabled()
 	prefix := config.EmailConf.Prefix
@@ -91,9 +139,19 @@ func ReportFailedService(db storage.Storage, config *conf.Config, serviceCfg *co
 	}
 
 	msg := fmt.Sprintf(`%sBeacon: Service "%s" failed!`, prefix, serviceCfg.Id)
+	if severity != conf.SEVERITY_CRITICAL {
+		msg = fmt.Sprintf(`%sBeacon: Service "%s" is not OK`, prefix, serviceCfg.Id)
+	}
 
 	if shouldSendEmail {
-		err = SendMail(&config.EmailConf, msg, msg)
+		for _, target := range targets {
+			emailConf := target.EmailConfig(config.EmailConf)
+			sendErr := SendMail(&emailConf, msg, "<p>"+html.EscapeString(msg)+"</p>", msg)
+			if sendErr != nil {
+				logger.Errorw("Failed to notify target", "service", serviceCfg.Id, "target", target.Name, zap.Error(sendErr))
+			}
+			err = errors.Join(err, sendErr)
+		}
 	}
 
 	status := storage.TASK_OK
@@ -122,20 +180,97 @@ func SummaryReportJob(reports []ServiceReport, db storage.Storage, config *conf.
 	return nil
 }
 
+// Open and resolve incidents based on service status, see monitor.UpdateIncident.
+// Unconfigured services are skipped.
+func IncidentsJob(reports []ServiceReport, db storage.Storage, config *conf.Config, now time.Time) error {
+	windows, err := monitor.MaintenanceWindows(db, config)
+	if err != nil {
+		return err
+	}
+	for _, report := range reports {
+		if report.ServiceCfg.Unconfigured {
+			continue
+		}
+		serviceWindows := monitor.ServiceMaintenance(windows, &report.ServiceCfg)
+		inMaintenance := monitor.ActiveMaintenance(serviceWindows, now, config.Timezone.Location) != nil
+		_, err = monitor.UpdateIncident(db, report.ServiceCfg.Id, report.ServiceStatus, inMaintenance, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Notify targets of escalation steps that became due since the last run.
+// Progress is stored in DB, so that steps are not notified again after restart.
+// Steps are retried on the next run if sending fails.
+func EscalateIncident(db storage.Storage, config *conf.Config, serviceCfg *conf.ServiceConfig, policy *conf.EscalationPolicy, incident *storage.Incident, now time.Time) error {
+	logger := logging.Get()
+	notified, err := db.EscalationSteps(incident.Id)
+	if err != nil {
+		return err
+	}
+	due := policy.DueSteps(incident.OpenedAt, now)
+	if due <= notified {
+		return nil
+	}
+	targets := config.EscalationTargets(policy.Steps[notified:due])
+	logger.Infow("Escalating incident", "service", serviceCfg.Id, "incident", incident.Id, "step", due, "policy", serviceCfg.Escalation)
+	// incidents are open only while the service fails
+	notifyErr := notifyFailedService(db, config, serviceCfg, conf.SEVERITY_CRITICAL, targets, now)
+	// step is stored even if some targets failed (those are logged),
+	// so that the other targets are not notified again on the next run
+	err = db.SetEscalationSteps(incident.Id, due, now)
+	return errors.Join(notifyErr, err)
+}
+
+// Send alerts about services that are not OK.
+// Services in maintenance (see conf.MaintenanceWindow) and services
+// with acknowledged incident are skipped.
+// Incidents of services with escalation policy are escalated, see EscalateIncident.
+// Other alerts are repeated after conf.Config.AlertRepeatInterval.
 func FailsReportJob(reports []ServiceReport, db storage.Storage, config *conf.Config, now time.Time) error {
 	logger := logging.Get()
+	windows, err := monitor.MaintenanceWindows(db, config)
+	if err != nil {
+		return err
+	}
 	for _, report := range reports {
 		if report.ServiceStatus == monitor.STATUS_OK {
 			continue
 		}
+		// shown in the summary report, but not worth an alert until adopted
+		if report.ServiceCfg.Unconfigured {
+			continue
+		}
+		serviceWindows := monitor.ServiceMaintenance(windows, &report.ServiceCfg)
+		if window := monitor.ActiveMaintenance(serviceWindows, now, config.Timezone.Location); window != nil {
+			logger.Infow("Service not OK during maintenance, not reporting", "service", report.ServiceCfg.Id, "reason", window.Reason)
+			continue
+		}
+		incident, err := db.OpenServiceIncident(report.ServiceCfg.Id)
+		if err != nil {
+			return err
+		}
+		if incident != nil && incident.IsAcknowledged() {
+			logger.Debugw("Incident acknowledged, not reporting", "service", report.ServiceCfg.Id, "incident", incident.Id)
+			continue
+		}
 		logger.Debugw("Service not OK", "service", report.ServiceCfg.Id)
-		doReport, err := scheduler.ShouldReportFailedService(db, &report.ServiceCfg, now)
+		if policy := config.ServiceEscalation(&report.ServiceCfg); policy != nil && incident != nil {
+			err = EscalateIncident(db, config, &report.ServiceCfg, policy, incident, now)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		doReport, err := scheduler.ShouldReportFailedService(db, config, &report.ServiceCfg, now)
 		if err != nil {
 			return err
 		}
 		if doReport {
 			logger.Infow("Reporting failed service", "service", report.ServiceCfg.Id)
-			err = ReportFailedService(db, config, &report.ServiceCfg, now)
+			err = ReportFailedService(db, config, &report.ServiceCfg, AlertSeverity(report.ServiceStatus), now)
 			if err != nil {
 				return err
 			}
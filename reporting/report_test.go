@@ -137,3 +137,325 @@ func TestNextReportTimeWeekends(t *testing.T) {
 	next = scheduler.NextReportTime(config, monday)
 	require.Equal(t, "Monday", next.Weekday().String())
 }
+
+func TestUnconfiguredServicesReport(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes([]byte("services:\n  configured:\n"))
+	require.NoError(t, err)
+	now := time.Now()
+
+	old := now.Add(-48 * time.Hour)
+	_, err = db.RecordHeartbeat("configured", old)
+	require.NoError(t, err)
+	_, err = db.RecordHeartbeat("stray", old)
+	require.NoError(t, err)
+	_, err = db.RecordHeartbeat("adopted", old)
+	require.NoError(t, err)
+	err = db.AdoptService("adopted", time.Hour, now)
+	require.NoError(t, err)
+
+	reports, err := GenerateReport(db, config)
+	require.NoError(t, err)
+	require.Len(t, reports, 3)
+	byId := map[string]ServiceReport{}
+	for _, report := range reports {
+		byId[report.ServiceCfg.Id] = report
+		assert.Equal(t, monitor.STATUS_FAIL, report.ServiceStatus, report.ServiceCfg.Id)
+	}
+	assert.False(t, byId["configured"].ServiceCfg.Unconfigured)
+	assert.True(t, byId["adopted"].ServiceCfg.Adopted)
+	assert.Equal(t, time.Hour, byId["adopted"].ServiceCfg.Timeout)
+	assert.True(t, byId["stray"].ServiceCfg.Unconfigured)
+
+	var buffer strings.Builder
+	err = WriteReport(reports, &buffer)
+	require.NoError(t, err)
+	assert.Contains(t, buffer.String(), "(unconfigured)")
+
+	err = FailsReportJob(reports, db, config, now)
+	require.NoError(t, err)
+	for _, id := range []string{"configured", "adopted"} {
+		task, err := db.LatestServiceFailedLog(id)
+		require.NoError(t, err)
+		assert.NotNil(t, task, id)
+	}
+	task, err := db.LatestServiceFailedLog("stray")
+	require.NoError(t, err)
+	assert.Nil(t, task, "unconfigured services are not alerted")
+}
+
+func TestGroupedReport(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes([]byte(`
+services:
+  checkout:
+    group: Shop
+    tags: [payments]
+  postgres:
+    group: Infrastructure
+  homepage:
+`))
+	require.NoError(t, err)
+
+	reports, err := GenerateReport(db, config)
+	require.NoError(t, err)
+	groups := GroupReports(reports)
+	require.Len(t, groups, 3)
+	assert.Equal(t, "Infrastructure", groups[0].Name)
+	assert.Equal(t, "postgres", groups[0].Reports[0].ServiceCfg.Id)
+	assert.Equal(t, "Shop", groups[1].Name)
+	assert.Equal(t, "", groups[2].Name)
+	assert.Equal(t, "homepage", groups[2].Reports[0].ServiceCfg.Id)
+
+	var buffer strings.Builder
+	err = WriteReport(reports, &buffer)
+	require.NoError(t, err)
+	body := buffer.String()
+	assert.Contains(t, body, "<h2>Shop</h2>")
+	assert.Contains(t, body, "<h2>Other services</h2>")
+	assert.Contains(t, body, "#payments")
+	assert.Less(t, strings.Index(body, "Infrastructure"), strings.Index(body, "Shop"))
+}
+
+func TestSplitReports(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes([]byte(`
+email:
+  send_to: ops@example.com
+notification_targets:
+  dba:
+    send_to: dba@example.com
+  pager:
+    send_to: pager@example.com
+alert_routes:
+  - groups: [Databases]
+    targets: [dba]
+  - severities: [critical]
+    targets: [pager]
+services:
+  postgres:
+    group: Databases
+  website:
+  backup-job:
+`))
+	require.NoError(t, err)
+
+	reports, err := GenerateReport(db, config)
+	require.NoError(t, err)
+	split := SplitReports(reports, config)
+	require.Len(t, split, 2)
+	assert.Equal(t, "dba", split[0].Target.Name)
+	assert.Equal(t, conf.Addresses{"dba@example.com"}, split[0].Target.SendTo)
+	require.Len(t, split[0].Reports, 1)
+	assert.Equal(t, "postgres", split[0].Reports[0].ServiceCfg.Id)
+	assert.Equal(t, conf.Addresses{"ops@example.com"}, split[1].Target.SendTo)
+	require.Len(t, split[1].Reports, 2)
+
+	assert.Equal(t, conf.SEVERITY_CRITICAL, AlertSeverity(monitor.STATUS_FAIL))
+	assert.Equal(t, conf.SEVERITY_WARNING, AlertSeverity(monitor.STATUS_OTHER))
+}
+
+func TestFailsReportJobMaintenance(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes([]byte(`
+services:
+  api:
+  postgres:
+    tags: [db]
+  website:
+maintenance:
+  - tags: [db]
+    at: "00:00"
+    duration: 168h
+`))
+	require.NoError(t, err)
+	now := time.Now()
+	_, err = monitor.CreateMaintenanceWindow(db, conf.MaintenanceWindow{
+		Services: []string{"api"},
+		Start:    now.Add(-time.Hour),
+		End:      now.Add(time.Hour),
+	}, now)
+	require.NoError(t, err)
+
+	reports, err := GenerateReport(db, config)
+	require.NoError(t, err)
+	err = FailsReportJob(reports, db, config, now)
+	require.NoError(t, err)
+	for id, reported := range map[string]bool{"api": false, "postgres": false, "website": true} {
+		task, err := db.LatestServiceFailedLog(id)
+		require.NoError(t, err)
+		assert.Equal(t, reported, task != nil, id)
+	}
+
+	// reported after the maintenance ends
+	err = FailsReportJob(reports, db, config, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	task, err := db.LatestServiceFailedLog("api")
+	require.NoError(t, err)
+	assert.NotNil(t, task)
+}
+
+func TestIncidentsJob(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes([]byte(`
+services:
+  api:
+  website:
+`))
+	require.NoError(t, err)
+	// stored with second precision
+	now := time.Now().Truncate(time.Second)
+
+	// no health checks, both fail
+	reports, err := GenerateReport(db, config)
+	require.NoError(t, err)
+	err = IncidentsJob(reports, db, config, now)
+	require.NoError(t, err)
+	apiIncident, err := db.OpenServiceIncident("api")
+	require.NoError(t, err)
+	require.NotNil(t, apiIncident)
+	// running again keeps the incident
+	err = IncidentsJob(reports, db, config, now.Add(time.Minute))
+	require.NoError(t, err)
+	count, err := db.CountIncidents(storage.IncidentQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	err = FailsReportJob(reports, db, config, now)
+	require.NoError(t, err)
+	err = db.AcknowledgeIncident(apiIncident.Id, "admin@example.com", now)
+	require.NoError(t, err)
+	// acknowledged incident is not reported again
+	later := now.Add(2 * config.AlertRepeatInterval)
+	err = FailsReportJob(reports, db, config, later)
+	require.NoError(t, err)
+	task, err := db.LatestServiceFailedLog("api")
+	require.NoError(t, err)
+	assert.True(t, now.Equal(task.Timestamp), task.Timestamp)
+	task, err = db.LatestServiceFailedLog("website")
+	require.NoError(t, err)
+	assert.True(t, later.Equal(task.Timestamp), task.Timestamp)
+
+	// resolved when OK again
+	_, err = db.RecordHeartbeat("api", later)
+	require.NoError(t, err)
+	reports, err = GenerateReport(db, config)
+	require.NoError(t, err)
+	err = IncidentsJob(reports, db, config, later)
+	require.NoError(t, err)
+	apiIncident, err = db.GetIncident(apiIncident.Id)
+	require.NoError(t, err)
+	assert.False(t, apiIncident.IsOpen())
+	assert.Equal(t, later.Sub(now), apiIncident.Duration(time.Now()))
+}
+
+func TestFailsReportJobEscalation(t *testing.T) {
+	server := startTestSmtpServer(t)
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes([]byte(`
+notification_targets:
+  oncall:
+    send_to: oncall@example.com
+  lead:
+    send_to: lead@example.com
+  cto:
+    send_to: cto@example.com
+escalation_policies:
+  backend:
+    steps:
+      - targets: [oncall]
+      - after: 15m
+        targets: [lead]
+      - after: 1h
+        targets: [cto]
+services:
+  api:
+    escalation: backend
+  worker:
+    escalation: backend
+`))
+	require.NoError(t, err)
+	config.EmailConf = server.emailConfig()
+	now := time.Now().Truncate(time.Second)
+
+	reports, err := GenerateReport(db, config)
+	require.NoError(t, err)
+	// recipients of alerts sent since the last call
+	sent := 0
+	run := func(at time.Time) []string {
+		err := IncidentsJob(reports, db, config, at)
+		require.NoError(t, err)
+		err = FailsReportJob(reports, db, config, at)
+		require.NoError(t, err)
+		recipients := []string{}
+		for _, message := range server.Messages()[sent:] {
+			recipients = append(recipients, message.Recipients...)
+		}
+		sent = len(server.Messages())
+		slices.Sort(recipients)
+		return recipients
+	}
+
+	assert.Equal(t, []string{"oncall@example.com", "oncall@example.com"}, run(now))
+	worker, err := db.OpenServiceIncident("worker")
+	require.NoError(t, err)
+	err = db.AcknowledgeIncident(worker.Id, "oncall@example.com", now.Add(5*time.Minute))
+	require.NoError(t, err)
+
+	assert.Empty(t, run(now.Add(10*time.Minute)))
+	assert.Equal(t, []string{"lead@example.com"}, run(now.Add(20*time.Minute)))
+	// each step is notified once, progress is kept in DB
+	assert.Empty(t, run(now.Add(30*time.Minute)))
+	// not repeated after the last step
+	assert.Equal(t, []string{"cto@example.com"}, run(now.Add(2*time.Hour)))
+	assert.Empty(t, run(now.Add(2*config.AlertRepeatInterval)))
+}
+
+func TestEscalationTargetFails(t *testing.T) {
+	server := startTestSmtpServer(t)
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes([]byte(`
+notification_targets:
+  oncall:
+    send_to: oncall@example.com
+  broken:
+    send_to: nobody@invalid.example.com
+escalation_policies:
+  backend:
+    steps:
+      - targets: [oncall, broken]
+      - after: 1h
+        targets: [oncall]
+services:
+  api:
+    escalation: backend
+`))
+	require.NoError(t, err)
+	config.EmailConf = server.emailConfig()
+	now := time.Now().Truncate(time.Second)
+
+	reports, err := GenerateReport(db, config)
+	require.NoError(t, err)
+	err = IncidentsJob(reports, db, config, now)
+	require.NoError(t, err)
+	err = FailsReportJob(reports, db, config, now)
+	require.Error(t, err)
+	require.Len(t, server.Messages(), 1)
+	assert.Equal(t, []string{"oncall@example.com"}, server.Messages()[0].Recipients)
+
+	// step is not retried, working targets are not notified again
+	err = FailsReportJob(reports, db, config, now.Add(10*time.Minute))
+	require.NoError(t, err)
+	assert.Len(t, server.Messages(), 1)
+
+	err = FailsReportJob(reports, db, config, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, server.Messages(), 2)
+}
@@ -10,9 +10,6 @@ import (
 	"go.uber.org/zap"
 )
 
-// report daily (might be made configurable later)
-const FailedServiceReportInterval = 24 * time.Hour
-
 func ShouldCheckWebServices(db storage.Storage, config *conf.Config, now time.Time) (bool, error) {
 	task, err := db.LatestTaskLog("web_check")
 	if err != nil {
@@ -76,8 +73,9 @@ func ShouldReport(db storage.Storage, config *conf.Config, query time.Time) (boo
 	return isAfter, nil
 }
 
-// Decide if extra report should be generated for a failed service
-func ShouldReportFailedService(db storage.Storage, cfg *conf.ServiceConfig, query time.Time) (bool, error) {
+// Decide if extra report should be generated for a failed service.
+// Reports are repeated after config.AlertRepeatInterval.
+func ShouldReportFailedService(db storage.Storage, config *conf.Config, cfg *conf.ServiceConfig, query time.Time) (bool, error) {
 	task, err := db.LatestServiceFailedLog(cfg.Id)
 	if err != nil {
 		return false, err
@@ -88,7 +86,7 @@ func ShouldReportFailedService(db storage.Storage, cfg *conf.ServiceConfig, quer
 	if task.Status == string(storage.TASK_ERROR) {
 		return true, nil
 	}
-	nextReportTime := task.Timestamp.Add(FailedServiceReportInterval)
+	nextReportTime := task.Timestamp.Add(config.AlertRepeatInterval)
 	isAfter := query.After(nextReportTime)
 	return isAfter, nil
 }
@@ -125,6 +125,30 @@ report_time: 10
 	assert.Equal(t, true, got)
 }
 
+func TestShouldReportFailedService(t *testing.T) {
+	logging.InitTest(t)
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config := conf.NewConfig()
+	config.AlertRepeatInterval = time.Hour
+	service := &conf.ServiceConfig{Id: "api"}
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	doReport, err := ShouldReportFailedService(db, config, service, now)
+	require.NoError(t, err)
+	assert.True(t, doReport, "never reported before")
+
+	err = db.CreateTaskLog(storage.TaskInput{
+		TaskName: "report_fail", Status: string(storage.TASK_OK), Timestamp: now, Details: "api"})
+	require.NoError(t, err)
+	doReport, err = ShouldReportFailedService(db, config, service, now.Add(59*time.Minute))
+	require.NoError(t, err)
+	assert.False(t, doReport)
+	doReport, err = ShouldReportFailedService(db, config, service, now.Add(61*time.Minute))
+	require.NoError(t, err)
+	assert.True(t, doReport)
+}
+
 func TestStart(t *testing.T) {
 	logging.InitTest(t)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -0,0 +1,237 @@
+// Beacon-managed API tokens.
+//
+// Tokens are random values shown to the user only once. Only their SHA-256 hash
+// is stored. Slow password hashing (argon2) is not needed since the tokens
+// have high entropy, and the fast hash allows direct lookup.
+package storage
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	API_TOKEN_PREFIX = "bcn_"
+	// Full access to the API, including changes such as maintenance windows.
+	// Tokens themselves are managed only by logged-in users and the CLI.
+	SCOPE_ADMIN = "admin"
+	// Read access to all services
+	SCOPE_READ_ALL = "read:all"
+	// Send heartbeats for (and read status of) a single service, use with ScopeBeat
+	SCOPE_BEAT_PREFIX = "beat:"
+)
+
+var ErrTokenNotFound = errors.New("token not found")
+
+type ApiToken struct {
+	Id     int
+	Name   string
+	Scopes []string
+	// zero if not set
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+	RevokedAt  time.Time
+}
+
+// Scope allowing heartbeats for given service
+func ScopeBeat(serviceId string) string {
+	return SCOPE_BEAT_PREFIX + serviceId
+}
+
+// Validate scope format
+func ValidateScope(scope string) error {
+	if scope == SCOPE_ADMIN || scope == SCOPE_READ_ALL {
+		return nil
+	}
+	if strings.HasPrefix(scope, SCOPE_BEAT_PREFIX) && len(scope) > len(SCOPE_BEAT_PREFIX) {
+		return nil
+	}
+	return fmt.Errorf("invalid scope %q, expected %q, %q or \"%s<service-id>\"", scope, SCOPE_ADMIN, SCOPE_READ_ALL, SCOPE_BEAT_PREFIX)
+}
+
+// Token is not revoked and not expired
+func (token *ApiToken) IsActive(now time.Time) bool {
+	if !token.RevokedAt.IsZero() {
+		return false
+	}
+	if !token.ExpiresAt.IsZero() && !now.Before(token.ExpiresAt) {
+		return false
+	}
+	return true
+}
+
+// Token is active and has at least one of the given scopes (or admin scope)
+func (token *ApiToken) Allows(now time.Time, scopes ...string) bool {
+	if !token.IsActive(now) {
+		return false
+	}
+	if slices.Contains(token.Scopes, SCOPE_ADMIN) {
+		return true
+	}
+	for _, scope := range scopes {
+		if slices.Contains(token.Scopes, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+func hashApiToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// Generate new token value
+func generateApiToken() (string, error) {
+	b, err := generateRandomBytes(32)
+	if err != nil {
+		return "", err
+	}
+	return API_TOKEN_PREFIX + hex.EncodeToString(b), nil
+}
+
+func formatOptionalTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(TIME_FORMAT)
+}
+
+func parseOptionalTime(value sql.NullString) (time.Time, error) {
+	if !value.Valid || value.String == "" {
+		return time.Time{}, nil
+	}
+	return parseSqliteTimestamp(value.String)
+}
+
+func (s *SQLStorage) CreateApiToken(name string, scopes []string, expiresAt time.Time) (string, *ApiToken, error) {
+	if len(scopes) == 0 {
+		return "", nil, fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if err := ValidateScope(scope); err != nil {
+			return "", nil, err
+		}
+	}
+	token, err := generateApiToken()
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now().UTC()
+	res, err := s.db.Exec(
+		`INSERT INTO api_tokens (name, token_hash, scopes, created_at, expires_at) VALUES (?, ?, ?, ?, ?)`,
+		name,
+		hashApiToken(token),
+		strings.Join(scopes, " "),
+		now.Format(TIME_FORMAT),
+		formatOptionalTime(expiresAt),
+	)
+	if err != nil {
+		return "", nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return "", nil, err
+	}
+	apiToken, err := s.getApiToken(`WHERE id = ?`, id)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, apiToken, nil
+}
+
+// Return (nil, nil) if not found
+func (s *SQLStorage) getApiToken(where string, args ...any) (*ApiToken, error) {
+	tokens, err := s.queryApiTokens(where, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	return tokens[0], nil
+}
+
+func (s *SQLStorage) queryApiTokens(where string, args ...any) (tokens []*ApiToken, err error) {
+	rows, err := s.db.Query(`
+	SELECT id, name, scopes, created_at, expires_at, last_used_at, revoked_at
+	FROM api_tokens
+	`+where+`
+	ORDER BY id ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		closeErr := rows.Close()
+		err = errors.Join(err, closeErr)
+	}()
+	tokens = make([]*ApiToken, 0)
+	for rows.Next() {
+		token := &ApiToken{}
+		var scopes string
+		var createdAt, expiresAt, lastUsedAt, revokedAt sql.NullString
+		err := rows.Scan(&token.Id, &token.Name, &scopes, &createdAt, &expiresAt, &lastUsedAt, &revokedAt)
+		if err != nil {
+			return nil, err
+		}
+		token.Scopes = strings.Fields(scopes)
+		for _, field := range []struct {
+			value  sql.NullString
+			target *time.Time
+		}{
+			{createdAt, &token.CreatedAt},
+			{expiresAt, &token.ExpiresAt},
+			{lastUsedAt, &token.LastUsedAt},
+			{revokedAt, &token.RevokedAt},
+		} {
+			*field.target, err = parseOptionalTime(field.value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// Find token by its value. Returns (nil, nil) if not found.
+// Returned token might be expired or revoked, see ApiToken.IsActive.
+func (s *SQLStorage) FindApiToken(token string) (*ApiToken, error) {
+	if !strings.HasPrefix(token, API_TOKEN_PREFIX) {
+		return nil, nil
+	}
+	return s.getApiToken(`WHERE token_hash = ?`, hashApiToken(token))
+}
+
+func (s *SQLStorage) ListApiTokens() ([]*ApiToken, error) {
+	return s.queryApiTokens("")
+}
+
+func (s *SQLStorage) RevokeApiToken(id int, now time.Time) error {
+	res, err := s.db.Exec(`UPDATE api_tokens SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`,
+		now.UTC().Format(TIME_FORMAT), id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrTokenNotFound
+	}
+	return nil
+}
+
+func (s *SQLStorage) TouchApiToken(id int, now time.Time) error {
+	_, err := s.db.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`,
+		now.UTC().Format(TIME_FORMAT), id)
+	return err
+}
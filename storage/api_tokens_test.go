@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiTokenFlow(t *testing.T) {
+	db := NewTestDb(t)
+	defer db.Close()
+	now := time.Now()
+
+	_, _, err := db.CreateApiToken("no-scopes", nil, time.Time{})
+	require.Error(t, err)
+	_, _, err = db.CreateApiToken("bad-scope", []string{"write:everything"}, time.Time{})
+	require.Error(t, err)
+
+	value, token, err := db.CreateApiToken("ci", []string{ScopeBeat("nightly-backup"), SCOPE_READ_ALL}, time.Time{})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(value, API_TOKEN_PREFIX))
+	require.Equal(t, "ci", token.Name)
+	require.True(t, token.ExpiresAt.IsZero())
+	require.False(t, token.CreatedAt.IsZero())
+
+	found, err := db.FindApiToken(value)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	require.Equal(t, token.Id, found.Id)
+	require.True(t, found.Allows(now, ScopeBeat("nightly-backup")))
+	require.True(t, found.Allows(now, SCOPE_READ_ALL))
+	require.False(t, found.Allows(now, ScopeBeat("other")))
+	require.False(t, found.Allows(now, SCOPE_ADMIN))
+
+	missing, err := db.FindApiToken(value + "x")
+	require.NoError(t, err)
+	require.Nil(t, missing)
+
+	err = db.TouchApiToken(token.Id, now)
+	require.NoError(t, err)
+	found, err = db.FindApiToken(value)
+	require.NoError(t, err)
+	require.WithinDuration(t, now, found.LastUsedAt, time.Second)
+
+	err = db.RevokeApiToken(token.Id, now)
+	require.NoError(t, err)
+	err = db.RevokeApiToken(token.Id, now)
+	require.ErrorIs(t, err, ErrTokenNotFound)
+	found, err = db.FindApiToken(value)
+	require.NoError(t, err)
+	require.False(t, found.IsActive(now))
+	require.False(t, found.Allows(now, SCOPE_READ_ALL))
+
+	tokens, err := db.ListApiTokens()
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+}
+
+func TestApiTokenExpiry(t *testing.T) {
+	db := NewTestDb(t)
+	defer db.Close()
+	now := time.Now()
+
+	value, _, err := db.CreateApiToken("admin", []string{SCOPE_ADMIN}, now.Add(time.Hour))
+	require.NoError(t, err)
+	token, err := db.FindApiToken(value)
+	require.NoError(t, err)
+	require.True(t, token.Allows(now, ScopeBeat("anything")), "admin allows everything")
+	require.False(t, token.Allows(now.Add(2*time.Hour), ScopeBeat("anything")), "expired")
+}
@@ -8,6 +8,8 @@ CREATE TABLE IF NOT EXISTS health_checks (
 );
 CREATE INDEX IF NOT EXISTS idx_health_checks_timestamp
 ON health_checks(timestamp);
+CREATE INDEX IF NOT EXISTS idx_health_checks_service_id
+ON health_checks(service_id);
 
 CREATE TABLE IF NOT EXISTS users (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -39,6 +41,70 @@ CREATE TABLE IF NOT EXISTS service_state (
 );
 CREATE UNIQUE INDEX IF NOT EXISTS idx_service_state_service_id ON service_state(service_id);
 
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    scopes TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME,
+    last_used_at DATETIME,
+    revoked_at DATETIME
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_tokens_token_hash ON api_tokens(token_hash);
+
+CREATE TABLE IF NOT EXISTS adopted_services (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    service_id TEXT UNIQUE NOT NULL,
+    timeout_seconds INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reason TEXT NOT NULL,
+    -- JSON lists
+    services TEXT NOT NULL,
+    tags TEXT NOT NULL,
+    -- one-off window
+    start_at DATETIME,
+    end_at DATETIME,
+    -- recurring window
+    days TEXT NOT NULL,
+    at TEXT NOT NULL,
+    duration_seconds INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS incidents (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    service_id TEXT NOT NULL,
+    opened_at DATETIME NOT NULL,
+    resolved_at DATETIME,
+    acknowledged_at DATETIME,
+    acknowledged_by TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_incidents_service_id ON incidents(service_id);
+
+CREATE TABLE IF NOT EXISTS incident_notes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    incident_id INTEGER NOT NULL,
+    author TEXT NOT NULL,
+    text TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(incident_id) REFERENCES incidents(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_incident_notes_incident_id ON incident_notes(incident_id);
+
+-- progress of escalation policy for an incident
+CREATE TABLE IF NOT EXISTS escalations (
+    incident_id INTEGER PRIMARY KEY,
+    -- number of notified steps
+    steps INTEGER NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(incident_id) REFERENCES incidents(id) ON DELETE CASCADE
+);
+
 CREATE TABLE IF NOT EXISTS schema_version (
     version INTEGER NOT NULL,
     applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
@@ -0,0 +1,284 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrIncidentNotFound = errors.New("incident not found")
+var ErrAuthorRequired = errors.New("author is required")
+
+// Period during which a service failed, from entering FAIL until recovery.
+type Incident struct {
+	Id        int
+	ServiceId string
+	OpenedAt  time.Time
+	// zero while the incident is open
+	ResolvedAt time.Time
+	// zero if not acknowledged
+	AcknowledgedAt time.Time
+	// user or API token that acknowledged the incident, empty if not acknowledged
+	AcknowledgedBy string
+}
+
+func (incident *Incident) IsOpen() bool {
+	return incident.ResolvedAt.IsZero()
+}
+
+func (incident *Incident) IsAcknowledged() bool {
+	return !incident.AcknowledgedAt.IsZero()
+}
+
+// Duration of the incident, until now if it is still open
+func (incident *Incident) Duration(now time.Time) time.Duration {
+	if incident.IsOpen() {
+		return now.Sub(incident.OpenedAt)
+	}
+	return incident.ResolvedAt.Sub(incident.OpenedAt)
+}
+
+type IncidentNote struct {
+	Id         int
+	IncidentId int
+	Author     string
+	Text       string
+	CreatedAt  time.Time
+}
+
+// Filter for listing incidents.
+// Zero values are not used for filtering.
+type IncidentQuery struct {
+	ServiceId string
+	// only unresolved incidents
+	Open   bool
+	Limit  int
+	Offset int
+}
+
+func (query *IncidentQuery) where() (string, []any) {
+	conditions := []string{}
+	args := []any{}
+	if query.ServiceId != "" {
+		conditions = append(conditions, "service_id = ?")
+		args = append(args, query.ServiceId)
+	}
+	if query.Open {
+		conditions = append(conditions, "resolved_at IS NULL")
+	}
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func (s *SQLStorage) OpenIncident(serviceId string, now time.Time) (*Incident, error) {
+	res, err := s.db.Exec(`INSERT INTO incidents (service_id, opened_at) VALUES (?, ?)`,
+		serviceId, now.UTC().Format(TIME_FORMAT))
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return s.GetIncident(int(id))
+}
+
+func (s *SQLStorage) OpenServiceIncident(serviceId string) (*Incident, error) {
+	incidents, err := s.ListIncidents(IncidentQuery{ServiceId: serviceId, Open: true, Limit: 1})
+	if err != nil || len(incidents) == 0 {
+		return nil, err
+	}
+	return incidents[0], nil
+}
+
+func (s *SQLStorage) GetIncident(id int) (*Incident, error) {
+	incidents, err := s.queryIncidents(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(incidents) == 0 {
+		return nil, ErrIncidentNotFound
+	}
+	return incidents[0], nil
+}
+
+func (s *SQLStorage) ListIncidents(query IncidentQuery) ([]*Incident, error) {
+	where, args := query.where()
+	limit := query.Limit
+	if limit == 0 {
+		limit = NO_LIMIT
+	}
+	args = append(args, limit, query.Offset)
+	return s.queryIncidents(where+` ORDER BY opened_at DESC, id DESC LIMIT ? OFFSET ?`, args...)
+}
+
+func (s *SQLStorage) CountIncidents(query IncidentQuery) (int, error) {
+	where, args := query.where()
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM incidents `+where, args...).Scan(&count)
+	return count, err
+}
+
+// Query incidents, rest is appended after FROM clause
+func (s *SQLStorage) queryIncidents(rest string, args ...any) (incidents []*Incident, err error) {
+	rows, err := s.db.Query(`
+	SELECT id, service_id, opened_at, resolved_at, acknowledged_at, acknowledged_by
+	FROM incidents
+	`+rest, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		closeErr := rows.Close()
+		err = errors.Join(err, closeErr)
+	}()
+	incidents = make([]*Incident, 0)
+	for rows.Next() {
+		incident := &Incident{}
+		var openedAt string
+		var resolvedAt, acknowledgedAt, acknowledgedBy sql.NullString
+		err := rows.Scan(&incident.Id, &incident.ServiceId, &openedAt, &resolvedAt, &acknowledgedAt, &acknowledgedBy)
+		if err != nil {
+			return nil, err
+		}
+		incident.OpenedAt, err = parseSqliteTimestamp(openedAt)
+		if err != nil {
+			return nil, err
+		}
+		incident.ResolvedAt, err = parseOptionalTime(resolvedAt)
+		if err != nil {
+			return nil, err
+		}
+		incident.AcknowledgedAt, err = parseOptionalTime(acknowledgedAt)
+		if err != nil {
+			return nil, err
+		}
+		incident.AcknowledgedBy = acknowledgedBy.String
+		incidents = append(incidents, incident)
+	}
+	return incidents, rows.Err()
+}
+
+// Check the incident exists, for updates that did not affect any row
+func (s *SQLStorage) checkIncidentExists(id int) error {
+	_, err := s.GetIncident(id)
+	return err
+}
+
+func (s *SQLStorage) ResolveIncident(id int, now time.Time) error {
+	res, err := s.db.Exec(`UPDATE incidents SET resolved_at = ? WHERE id = ? AND resolved_at IS NULL`,
+		now.UTC().Format(TIME_FORMAT), id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return s.checkIncidentExists(id)
+	}
+	return nil
+}
+
+func (s *SQLStorage) AcknowledgeIncident(id int, by string, now time.Time) error {
+	if by == "" {
+		return ErrAuthorRequired
+	}
+	res, err := s.db.Exec(`
+		UPDATE incidents SET acknowledged_at = ?, acknowledged_by = ?
+		WHERE id = ? AND acknowledged_at IS NULL`,
+		now.UTC().Format(TIME_FORMAT), by, id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return s.checkIncidentExists(id)
+	}
+	return nil
+}
+
+func (s *SQLStorage) AddIncidentNote(incidentId int, author string, text string, now time.Time) (*IncidentNote, error) {
+	if author == "" {
+		return nil, ErrAuthorRequired
+	}
+	err := s.checkIncidentExists(incidentId)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.db.Exec(`INSERT INTO incident_notes (incident_id, author, text, created_at) VALUES (?, ?, ?, ?)`,
+		incidentId, author, text, now.UTC().Format(TIME_FORMAT))
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	notes, err := s.queryIncidentNotes(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(notes) == 0 {
+		return nil, ErrIncidentNotFound
+	}
+	return notes[0], nil
+}
+
+func (s *SQLStorage) ListIncidentNotes(incidentId int) ([]*IncidentNote, error) {
+	return s.queryIncidentNotes(`WHERE incident_id = ?`, incidentId)
+}
+
+func (s *SQLStorage) queryIncidentNotes(where string, args ...any) (notes []*IncidentNote, err error) {
+	rows, err := s.db.Query(`
+	SELECT id, incident_id, author, text, created_at
+	FROM incident_notes
+	`+where+`
+	ORDER BY created_at ASC, id ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		closeErr := rows.Close()
+		err = errors.Join(err, closeErr)
+	}()
+	notes = make([]*IncidentNote, 0)
+	for rows.Next() {
+		note := &IncidentNote{}
+		var createdAt string
+		err := rows.Scan(&note.Id, &note.IncidentId, &note.Author, &note.Text, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+		note.CreatedAt, err = parseSqliteTimestamp(createdAt)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+func (s *SQLStorage) EscalationSteps(incidentId int) (int, error) {
+	var steps int
+	err := s.db.QueryRow(`SELECT steps FROM escalations WHERE incident_id = ?`, incidentId).Scan(&steps)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return steps, err
+}
+
+func (s *SQLStorage) SetEscalationSteps(incidentId int, steps int, now time.Time) error {
+	_, err := s.db.Exec(`
+		INSERT INTO escalations (incident_id, steps, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(incident_id) DO UPDATE SET steps = excluded.steps, updated_at = excluded.updated_at`,
+		incidentId, steps, now.UTC().Format(TIME_FORMAT))
+	return err
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncidents(t *testing.T) {
+	db := NewTestDb(t)
+	defer db.Close()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	incident, err := db.OpenServiceIncident("api")
+	require.NoError(t, err)
+	require.Nil(t, incident)
+
+	first, err := db.OpenIncident("api", now)
+	require.NoError(t, err)
+	assert.Equal(t, "api", first.ServiceId)
+	assert.True(t, now.Equal(first.OpenedAt))
+	assert.True(t, first.IsOpen())
+	assert.False(t, first.IsAcknowledged())
+	assert.Equal(t, time.Hour, first.Duration(now.Add(time.Hour)))
+
+	err = db.AcknowledgeIncident(first.Id, "", now)
+	require.ErrorIs(t, err, ErrAuthorRequired)
+	err = db.AcknowledgeIncident(first.Id, "admin@example.com", now.Add(time.Minute))
+	require.NoError(t, err)
+	// keeps the original acknowledgement
+	err = db.AcknowledgeIncident(first.Id, "other@example.com", now.Add(2*time.Minute))
+	require.NoError(t, err)
+	err = db.ResolveIncident(first.Id, now.Add(30*time.Minute))
+	require.NoError(t, err)
+	first, err = db.GetIncident(first.Id)
+	require.NoError(t, err)
+	assert.False(t, first.IsOpen())
+	assert.True(t, first.IsAcknowledged())
+	assert.Equal(t, "admin@example.com", first.AcknowledgedBy)
+	assert.Equal(t, 30*time.Minute, first.Duration(now.Add(time.Hour)))
+
+	second, err := db.OpenIncident("api", now.Add(time.Hour))
+	require.NoError(t, err)
+	_, err = db.OpenIncident("worker", now.Add(2*time.Hour))
+	require.NoError(t, err)
+
+	incident, err = db.OpenServiceIncident("api")
+	require.NoError(t, err)
+	require.NotNil(t, incident)
+	assert.Equal(t, second.Id, incident.Id)
+
+	incidents, err := db.ListIncidents(IncidentQuery{ServiceId: "api"})
+	require.NoError(t, err)
+	require.Len(t, incidents, 2)
+	assert.Equal(t, second.Id, incidents[0].Id)
+	assert.Equal(t, first.Id, incidents[1].Id)
+	count, err := db.CountIncidents(IncidentQuery{Open: true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	incidents, err = db.ListIncidents(IncidentQuery{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, incidents, 1)
+	assert.Equal(t, second.Id, incidents[0].Id)
+
+	note, err := db.AddIncidentNote(second.Id, "admin@example.com", "Restarted the server", now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, second.Id, note.IncidentId)
+	_, err = db.AddIncidentNote(second.Id, "", "Anonymous", now.Add(time.Hour))
+	require.ErrorIs(t, err, ErrAuthorRequired)
+	_, err = db.AddIncidentNote(second.Id, "token ci", "Looks good", now.Add(2*time.Hour))
+	require.NoError(t, err)
+	notes, err := db.ListIncidentNotes(second.Id)
+	require.NoError(t, err)
+	require.Len(t, notes, 2)
+	assert.Equal(t, note, notes[0])
+	assert.Equal(t, "Looks good", notes[1].Text)
+
+	_, err = db.GetIncident(100)
+	require.ErrorIs(t, err, ErrIncidentNotFound)
+	require.ErrorIs(t, db.ResolveIncident(100, now), ErrIncidentNotFound)
+	require.ErrorIs(t, db.AcknowledgeIncident(100, "admin@example.com", now), ErrIncidentNotFound)
+	_, err = db.AddIncidentNote(100, "admin@example.com", "note", now)
+	require.ErrorIs(t, err, ErrIncidentNotFound)
+
+	_, err = db.PurgeService("api")
+	require.NoError(t, err)
+	incidents, err = db.ListIncidents(IncidentQuery{})
+	require.NoError(t, err)
+	require.Len(t, incidents, 1)
+	assert.Equal(t, "worker", incidents[0].ServiceId)
+	notes, err = db.ListIncidentNotes(second.Id)
+	require.NoError(t, err)
+	require.Empty(t, notes)
+}
+
+func TestEscalationSteps(t *testing.T) {
+	db := NewTestDb(t)
+	defer db.Close()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	incident, err := db.OpenIncident("api", now)
+	require.NoError(t, err)
+	steps, err := db.EscalationSteps(incident.Id)
+	require.NoError(t, err)
+	assert.Zero(t, steps)
+
+	require.NoError(t, db.SetEscalationSteps(incident.Id, 1, now))
+	require.NoError(t, db.SetEscalationSteps(incident.Id, 3, now.Add(time.Hour)))
+	steps, err = db.EscalationSteps(incident.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 3, steps)
+
+	_, err = db.PurgeService("api")
+	require.NoError(t, err)
+	steps, err = db.EscalationSteps(incident.Id)
+	require.NoError(t, err)
+	assert.Zero(t, steps)
+}
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrMaintenanceWindowNotFound = errors.New("maintenance window not found")
+
+// Maintenance window created using CLI or API.
+// See conf.MaintenanceWindow for meaning of the fields.
+type MaintenanceWindow struct {
+	Id       int
+	Reason   string
+	Services []string
+	Tags     []string
+	// one-off window, zero for recurring windows
+	Start time.Time
+	End   time.Time
+	// recurring window
+	Days      string
+	At        string
+	Duration  time.Duration
+	CreatedAt time.Time
+}
+
+func (s *SQLStorage) CreateMaintenanceWindow(window *MaintenanceWindow, now time.Time) (*MaintenanceWindow, error) {
+	services, err := json.Marshal(window.Services)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := json.Marshal(window.Tags)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.db.Exec(`
+		INSERT INTO maintenance_windows (reason, services, tags, start_at, end_at, days, at, duration_seconds, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		window.Reason,
+		string(services),
+		string(tags),
+		formatOptionalTime(window.Start),
+		formatOptionalTime(window.End),
+		window.Days,
+		window.At,
+		int64(window.Duration.Seconds()),
+		now.UTC().Format(TIME_FORMAT),
+	)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	windows, err := s.queryMaintenanceWindows(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(windows) == 0 {
+		return nil, ErrMaintenanceWindowNotFound
+	}
+	return windows[0], nil
+}
+
+func (s *SQLStorage) ListMaintenanceWindows() ([]*MaintenanceWindow, error) {
+	return s.queryMaintenanceWindows("")
+}
+
+func (s *SQLStorage) queryMaintenanceWindows(where string, args ...any) (windows []*MaintenanceWindow, err error) {
+	rows, err := s.db.Query(`
+	SELECT id, reason, services, tags, start_at, end_at, days, at, duration_seconds, created_at
+	FROM maintenance_windows
+	`+where+`
+	ORDER BY id ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		closeErr := rows.Close()
+		err = errors.Join(err, closeErr)
+	}()
+	windows = make([]*MaintenanceWindow, 0)
+	for rows.Next() {
+		window := &MaintenanceWindow{}
+		var services, tags string
+		var start, end sql.NullString
+		var durationSeconds int64
+		var createdAt string
+		err := rows.Scan(&window.Id, &window.Reason, &services, &tags, &start, &end,
+			&window.Days, &window.At, &durationSeconds, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal([]byte(services), &window.Services)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal([]byte(tags), &window.Tags)
+		if err != nil {
+			return nil, err
+		}
+		window.Start, err = parseOptionalTime(start)
+		if err != nil {
+			return nil, err
+		}
+		window.End, err = parseOptionalTime(end)
+		if err != nil {
+			return nil, err
+		}
+		window.Duration = time.Duration(durationSeconds) * time.Second
+		window.CreatedAt, err = parseSqliteTimestamp(createdAt)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	return windows, rows.Err()
+}
+
+func (s *SQLStorage) DeleteMaintenanceWindow(id int) error {
+	res, err := s.db.Exec(`DELETE FROM maintenance_windows WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrMaintenanceWindowNotFound
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceWindows(t *testing.T) {
+	db := NewTestDb(t)
+	defer db.Close()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	windows, err := db.ListMaintenanceWindows()
+	require.NoError(t, err)
+	require.Empty(t, windows)
+
+	oneOff, err := db.CreateMaintenanceWindow(&MaintenanceWindow{
+		Reason:   "Deploy",
+		Services: []string{"api", "worker"},
+		Start:    now,
+		End:      now.Add(2 * time.Hour),
+	}, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, oneOff.Id)
+	assert.Equal(t, []string{"api", "worker"}, oneOff.Services)
+	assert.Empty(t, oneOff.Tags)
+	assert.True(t, now.Equal(oneOff.Start))
+	assert.True(t, now.Add(2*time.Hour).Equal(oneOff.End))
+	assert.True(t, now.Equal(oneOff.CreatedAt))
+
+	recurring, err := db.CreateMaintenanceWindow(&MaintenanceWindow{
+		Tags:     []string{"db"},
+		Days:     "Sunday",
+		At:       "02:00",
+		Duration: 90 * time.Minute,
+	}, now)
+	require.NoError(t, err)
+	assert.True(t, recurring.Start.IsZero())
+	assert.Equal(t, 90*time.Minute, recurring.Duration)
+
+	windows, err = db.ListMaintenanceWindows()
+	require.NoError(t, err)
+	require.Len(t, windows, 2)
+	assert.Equal(t, oneOff, windows[0])
+	assert.Equal(t, recurring, windows[1])
+
+	err = db.DeleteMaintenanceWindow(oneOff.Id)
+	require.NoError(t, err)
+	err = db.DeleteMaintenanceWindow(oneOff.Id)
+	require.ErrorIs(t, err, ErrMaintenanceWindowNotFound)
+	windows, err = db.ListMaintenanceWindows()
+	require.NoError(t, err)
+	require.Len(t, windows, 1)
+	assert.Equal(t, "02:00", windows[0].At)
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+// Service that was not in config but has been adopted from the web GUI,
+// after sending heartbeats as an unknown service.
+type AdoptedService struct {
+	ServiceId string
+	Timeout   time.Duration
+	CreatedAt time.Time
+}
+
+// Create or update adopted service definition
+func (s *SQLStorage) AdoptService(serviceId string, timeout time.Duration, now time.Time) error {
+	_, err := s.db.Exec(`
+		INSERT INTO adopted_services (service_id, timeout_seconds, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(service_id) DO UPDATE SET timeout_seconds = excluded.timeout_seconds`,
+		serviceId, int64(timeout.Seconds()), now.UTC().Format(TIME_FORMAT))
+	return err
+}
+
+// List adopted services, sorted by ID
+func (s *SQLStorage) ListAdoptedServices() ([]*AdoptedService, error) {
+	return s.queryAdoptedServices("")
+}
+
+// Return (nil, nil) if not found
+func (s *SQLStorage) GetAdoptedService(serviceId string) (*AdoptedService, error) {
+	services, err := s.queryAdoptedServices(`WHERE service_id = ?`, serviceId)
+	if err != nil {
+		return nil, err
+	}
+	if len(services) == 0 {
+		return nil, nil
+	}
+	return services[0], nil
+}
+
+func (s *SQLStorage) queryAdoptedServices(where string, args ...any) (services []*AdoptedService, err error) {
+	rows, err := s.db.Query(`
+		SELECT service_id, timeout_seconds, created_at
+		FROM adopted_services
+		`+where+`
+		ORDER BY service_id ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		closeErr := rows.Close()
+		err = errors.Join(err, closeErr)
+	}()
+	services = make([]*AdoptedService, 0)
+	for rows.Next() {
+		service := &AdoptedService{}
+		var timeoutSeconds int64
+		var createdAt string
+		err = rows.Scan(&service.ServiceId, &timeoutSeconds, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+		service.Timeout = time.Duration(timeoutSeconds) * time.Second
+		service.CreatedAt, err = parseSqliteTimestamp(createdAt)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, service)
+	}
+	return services, rows.Err()
+}
+
+func (s *SQLStorage) HasHealthChecks(serviceId string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM health_checks WHERE service_id = ?)`, serviceId).Scan(&exists)
+	return exists, err
+}
+
+// Delete all health checks and incidents of a service and its adopted definition (if any).
+// Returns number of deleted health checks.
+func (s *SQLStorage) PurgeService(serviceId string) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(`DELETE FROM health_checks WHERE service_id = ?`, serviceId)
+	if err != nil {
+		return 0, errors.Join(err, tx.Rollback())
+	}
+	_, err = tx.Exec(`DELETE FROM adopted_services WHERE service_id = ?`, serviceId)
+	if err != nil {
+		return 0, errors.Join(err, tx.Rollback())
+	}
+	for _, table := range []string{"incident_notes", "escalations"} {
+		_, err = tx.Exec(`DELETE FROM `+table+` WHERE incident_id IN (SELECT id FROM incidents WHERE service_id = ?)`, serviceId)
+		if err != nil {
+			return 0, errors.Join(err, tx.Rollback())
+		}
+	}
+	_, err = tx.Exec(`DELETE FROM incidents WHERE service_id = ?`, serviceId)
+	if err != nil {
+		return 0, errors.Join(err, tx.Rollback())
+	}
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
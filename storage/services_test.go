@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdoptPurgeService(t *testing.T) {
+	db := NewTestDb(t)
+	defer db.Close()
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	services, err := db.ListAdoptedServices()
+	require.NoError(t, err)
+	require.Empty(t, services)
+
+	for i := range 3 {
+		_, err = db.RecordHeartbeat("stray", now.Add(time.Duration(i)*time.Minute))
+		require.NoError(t, err)
+	}
+	_, err = db.RecordHeartbeat("other", now)
+	require.NoError(t, err)
+
+	err = db.AdoptService("stray", time.Hour, now)
+	require.NoError(t, err)
+	// adopting again updates timeout
+	err = db.AdoptService("stray", 2*time.Hour, now.Add(time.Hour))
+	require.NoError(t, err)
+	services, err = db.ListAdoptedServices()
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	require.Equal(t, "stray", services[0].ServiceId)
+	require.Equal(t, 2*time.Hour, services[0].Timeout)
+	require.True(t, now.Equal(services[0].CreatedAt))
+	adopted, err := db.GetAdoptedService("stray")
+	require.NoError(t, err)
+	require.Equal(t, services[0], adopted)
+	adopted, err = db.GetAdoptedService("other")
+	require.NoError(t, err)
+	require.Nil(t, adopted)
+	hasChecks, err := db.HasHealthChecks("stray")
+	require.NoError(t, err)
+	require.True(t, hasChecks)
+
+	deleted, err := db.PurgeService("stray")
+	require.NoError(t, err)
+	require.Equal(t, int64(3), deleted)
+	services, err = db.ListAdoptedServices()
+	require.NoError(t, err)
+	require.Empty(t, services)
+	ids, err := db.ListServices()
+	require.NoError(t, err)
+	require.Equal(t, []string{"other"}, ids)
+	hasChecks, err = db.HasHealthChecks("stray")
+	require.NoError(t, err)
+	require.False(t, hasChecks)
+
+	deleted, err = db.PurgeService("missing")
+	require.NoError(t, err)
+	require.Zero(t, deleted)
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -36,8 +37,29 @@ type HealthCheck struct {
 	Metadata  map[string]string
 }
 
+// Filter for listing health checks.
+// Zero values are not used for filtering.
+type HealthCheckQuery struct {
+	ServiceId string
+	// inclusive
+	From time.Time
+	// exclusive
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
 type User struct {
-	email string
+	email     string
+	createdAt time.Time
+}
+
+func (u *User) Email() string {
+	return u.email
+}
+
+func (u *User) CreatedAt() time.Time {
+	return u.createdAt
 }
 
 type TaskInput struct {
@@ -54,6 +76,14 @@ type Task struct {
 	Details   string
 }
 
+// Aggregated task runs with given name and status
+type TaskStats struct {
+	TaskName        string
+	Status          string
+	Count           int
+	LatestTimestamp time.Time
+}
+
 // DB Versions
 type SchemaVersion struct {
 	Version   int
@@ -70,6 +100,10 @@ type Storage interface {
 	LatestHealthChecks(serviceID string, limit int) ([]*HealthCheck, error)
 	// List health checks after given time
 	HealthChecksSince(serviceID string, since time.Time) ([]*HealthCheck, error)
+	// List health checks matching the query, newest first
+	ListHealthChecks(query HealthCheckQuery) ([]*HealthCheck, error)
+	// Count health checks matching the query, ignoring limit and offset
+	CountHealthChecks(query HealthCheckQuery) (int, error)
 	// Convenience method to get return healthcheck (possibly nil)
 	LatestHealthCheck(serviceID string) (*HealthCheck, error)
 	// Store heartbeat and return the stored value or error
@@ -80,14 +114,78 @@ type Storage interface {
 	CreateUser(email string, password string) error
 	// Get user if email and password match
 	ValidateUser(email string, password string) (*User, error)
+	// Get user by email, nil if not found
+	GetUser(email string) (*User, error)
+	// List all users, sorted by email
+	ListUsers() ([]*User, error)
+	// Delete user, ErrUserNotFound if not found
+	DeleteUser(email string) error
+	// Set new password for existing user, ErrUserNotFound if not found
+	UpdatePassword(email string, password string) error
+	// Create new API token. Returns the token value, which is not stored and cannot be retrieved later.
+	// Zero expiresAt means no expiration.
+	CreateApiToken(name string, scopes []string, expiresAt time.Time) (string, *ApiToken, error)
+	// Find API token by its value, nil if not found
+	FindApiToken(token string) (*ApiToken, error)
+	// List all API tokens, including revoked and expired
+	ListApiTokens() ([]*ApiToken, error)
+	// Revoke API token, ErrTokenNotFound if not found or already revoked
+	RevokeApiToken(id int, now time.Time) error
+	// Update last used time of API token
+	TouchApiToken(id int, now time.Time) error
 	// Log new task run
 	CreateTaskLog(taskInput TaskInput) error
 	// Get latest task log.
 	LatestTaskLog(taskName string) (*Task, error)
+	// List task logs, newest first. Empty taskName matches all tasks.
+	ListTaskLogs(taskName string, limit int, offset int) ([]*Task, error)
+	// Number of task runs and latest run time for each task name and status
+	TaskLogStats() ([]*TaskStats, error)
 	// Latest report of a failed service
 	LatestServiceFailedLog(serviceName string) (*Task, error)
 	// Get latest task log with given status and/or details.
 	LatestTaskLogWithStatus(taskName string, status string, detailsQuery string) (*Task, error)
+	// Create or update definition of a service adopted from the web GUI
+	AdoptService(serviceId string, timeout time.Duration, now time.Time) error
+	// List adopted services, sorted by ID
+	ListAdoptedServices() ([]*AdoptedService, error)
+	// Get adopted service, nil if not adopted
+	GetAdoptedService(serviceId string) (*AdoptedService, error)
+	// Check if the service has any health checks (heartbeats or web checks)
+	HasHealthChecks(serviceId string) (bool, error)
+	// Delete health checks, incidents and adopted definition of a service.
+	// Returns number of deleted health checks.
+	PurgeService(serviceId string) (int64, error)
+	// Store new maintenance window. Returns the stored window, including Id.
+	CreateMaintenanceWindow(window *MaintenanceWindow, now time.Time) (*MaintenanceWindow, error)
+	// List maintenance windows, oldest first
+	ListMaintenanceWindows() ([]*MaintenanceWindow, error)
+	// Delete maintenance window, ErrMaintenanceWindowNotFound if not found
+	DeleteMaintenanceWindow(id int) error
+	// Open new incident of a service
+	OpenIncident(serviceId string, now time.Time) (*Incident, error)
+	// Latest unresolved incident of a service, nil if none
+	OpenServiceIncident(serviceId string) (*Incident, error)
+	// Get incident, ErrIncidentNotFound if not found
+	GetIncident(id int) (*Incident, error)
+	// List incidents matching the query, newest first
+	ListIncidents(query IncidentQuery) ([]*Incident, error)
+	// Count incidents matching the query, ignoring limit and offset
+	CountIncidents(query IncidentQuery) (int, error)
+	// Mark incident as resolved, ErrIncidentNotFound if not found
+	ResolveIncident(id int, now time.Time) error
+	// Mark incident as acknowledged by given user, ErrIncidentNotFound if not found,
+	// ErrAuthorRequired if user is empty.
+	// Acknowledging incident again keeps the original acknowledgement.
+	AcknowledgeIncident(id int, by string, now time.Time) error
+	// Add note to incident, ErrIncidentNotFound if not found, ErrAuthorRequired if author is empty
+	AddIncidentNote(incidentId int, author string, text string, now time.Time) (*IncidentNote, error)
+	// List notes of incident, oldest first
+	ListIncidentNotes(incidentId int) ([]*IncidentNote, error)
+	// Number of escalation steps notified for incident, 0 if none
+	EscalationSteps(incidentId int) (int, error)
+	// Store number of escalation steps notified for incident
+	SetEscalationSteps(incidentId int, steps int, now time.Time) error
 	// List all schema versions present
 	ListSchemaVersions() ([]SchemaVersion, error)
 
@@ -101,6 +199,7 @@ const NO_LIMIT int = -1
 const TIME_FORMAT = time.RFC3339
 
 var ErrEmailAlreadyUsed = errors.New("email already used")
+var ErrUserNotFound = errors.New("user not found")
 
 func NewTestDb(t *testing.T) Storage {
 	db, err := NewSQLStorage(":memory:")
@@ -170,6 +269,70 @@ func (s *SQLStorage) LatestTaskLog(taskName string) (*Task, error) {
 	return s.LatestTaskLogWithStatus(taskName, "", "")
 }
 
+func (s *SQLStorage) ListTaskLogs(taskName string, limit int, offset int) (tasks []*Task, err error) {
+	query := `SELECT timestamp, status, details, task_name FROM task_logs`
+	args := []any{}
+	if taskName != "" {
+		query += ` WHERE task_name = ?`
+		args = append(args, taskName)
+	}
+	query += ` ORDER BY timestamp DESC, id DESC LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		closeErr := rows.Close()
+		err = errors.Join(err, closeErr)
+	}()
+	tasks = make([]*Task, 0)
+	for rows.Next() {
+		var timestampStr string
+		task := &Task{}
+		err := rows.Scan(&timestampStr, &task.Status, &task.Details, &task.TaskName)
+		if err != nil {
+			return nil, err
+		}
+		task.Timestamp, err = time.Parse(TIME_FORMAT, timestampStr)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+func (s *SQLStorage) TaskLogStats() (stats []*TaskStats, err error) {
+	rows, err := s.db.Query(`SELECT task_name, status, COUNT(*), MAX(timestamp)
+		FROM task_logs
+		GROUP BY task_name, status
+		ORDER BY task_name, status`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		closeErr := rows.Close()
+		err = errors.Join(err, closeErr)
+	}()
+	stats = make([]*TaskStats, 0)
+	for rows.Next() {
+		var timestampStr string
+		stat := &TaskStats{}
+		err := rows.Scan(&stat.TaskName, &stat.Status, &stat.Count, &timestampStr)
+		if err != nil {
+			return nil, err
+		}
+		stat.LatestTimestamp, err = time.Parse(TIME_FORMAT, timestampStr)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
+
 func (s *SQLStorage) LatestServiceFailedLog(serviceName string) (*Task, error) {
 	return s.LatestTaskLogWithStatus("report_fail", "", serviceName)
 }
@@ -218,6 +381,90 @@ func (s *SQLStorage) ValidateUser(email string, inputPassword string) (*User, er
 	return &User{email: email}, nil
 }
 
+func (s *SQLStorage) GetUser(email string) (*User, error) {
+	var createdAtStr string
+	err := s.db.QueryRow(`SELECT created_at FROM users WHERE email = ?`, email).Scan(&createdAtStr)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	createdAt, err := parseSqliteTimestamp(createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	return &User{email: email, createdAt: createdAt}, nil
+}
+
+func (s *SQLStorage) ListUsers() (users []*User, err error) {
+	rows, err := s.db.Query(`SELECT email, created_at FROM users ORDER BY email ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		closeErr := rows.Close()
+		err = errors.Join(err, closeErr)
+	}()
+	users = make([]*User, 0)
+	for rows.Next() {
+		var email, createdAtStr string
+		if err := rows.Scan(&email, &createdAtStr); err != nil {
+			return nil, err
+		}
+		createdAt, err := parseSqliteTimestamp(createdAtStr)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, &User{email: email, createdAt: createdAt})
+	}
+	return users, nil
+}
+
+func (s *SQLStorage) DeleteUser(email string) error {
+	res, err := s.db.Exec(`DELETE FROM users WHERE email = ?`, email)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (s *SQLStorage) UpdatePassword(email string, password string) error {
+	if password == "" {
+		return fmt.Errorf("cannot set empty password")
+	}
+	hashedPassword, err := GenerateFromPassword(password)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.Exec(`UPDATE users SET password_hash = ? WHERE email = ?`, hashedPassword, email)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// Return ErrUserNotFound if no rows were affected
+func requireAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Parse timestamps created by SQLite CURRENT_TIMESTAMP default
+func parseSqliteTimestamp(value string) (time.Time, error) {
+	timestamp, err := time.Parse(time.DateTime, value)
+	if err == nil {
+		return timestamp, nil
+	}
+	return time.Parse(TIME_FORMAT, value)
+}
+
 // List all distinct services, sorted alphabetically
 func (s *SQLStorage) ListServices() (services []string, err error) {
 	rows, err := s.db.Query(`SELECT DISTINCT service_id FROM health_checks ORDER BY service_id ASC`)
@@ -351,6 +598,73 @@ func (s *SQLStorage) LatestHealthChecks(serviceId string, limit int) (healthChec
 	return healthChecks, nil
 }
 
+// Build WHERE clause and args for the query filters
+func (query *HealthCheckQuery) where() (string, []any) {
+	conditions := []string{}
+	args := []any{}
+	if query.ServiceId != "" {
+		conditions = append(conditions, "service_id = ?")
+		args = append(args, query.ServiceId)
+	}
+	if !query.From.IsZero() {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, query.From.UTC().Format(TIME_FORMAT))
+	}
+	if !query.To.IsZero() {
+		conditions = append(conditions, "timestamp < ?")
+		args = append(args, query.To.UTC().Format(TIME_FORMAT))
+	}
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func (s *SQLStorage) ListHealthChecks(query HealthCheckQuery) (healthChecks []*HealthCheck, err error) {
+	where, args := query.where()
+	limit := query.Limit
+	if limit == 0 {
+		limit = NO_LIMIT
+	}
+	args = append(args, limit, query.Offset)
+	rows, err := s.db.Query(`
+	SELECT
+		id,
+		service_id,
+		timestamp,
+		metadata
+	FROM
+		health_checks
+	`+where+`
+	ORDER BY
+		timestamp DESC, id DESC
+	LIMIT ? OFFSET ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		closeErr := rows.Close()
+		err = errors.Join(err, closeErr)
+	}()
+	healthChecks = make([]*HealthCheck, 0)
+	for rows.Next() {
+		healthCheck, err := rowToHealthCheck(rows)
+		if err != nil {
+			return nil, err
+		}
+		healthChecks = append(healthChecks, healthCheck)
+	}
+	return healthChecks, nil
+}
+
+func (s *SQLStorage) CountHealthChecks(query HealthCheckQuery) (int, error) {
+	where, args := query.where()
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM health_checks `+where, args...).Scan(&count)
+	return count, err
+}
+
 func (s *SQLStorage) AddHealthCheck(healthCheckInput *HealthCheckInput) error {
 	timestampStr := healthCheckInput.Timestamp.UTC().Format(TIME_FORMAT)
 	metadataStr, err := json.Marshal(healthCheckInput.Metadata)
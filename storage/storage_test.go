@@ -482,3 +482,103 @@ func TestPruneTasks(t *testing.T) {
 	require.NoError(t, err)
 	require.WithinDuration(t, latest, task.Timestamp, time.Second)
 }
+
+func TestListHealthChecks(t *testing.T) {
+	db := NewTestDb(t)
+	defer db.Close()
+
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 10 {
+		err := db.AddHealthCheck(&HealthCheckInput{
+			ServiceId: "foo", Timestamp: base.Add(time.Duration(i) * time.Hour),
+		})
+		require.NoError(t, err)
+	}
+	err := db.AddHealthCheck(&HealthCheckInput{ServiceId: "bar", Timestamp: base})
+	require.NoError(t, err)
+
+	query := HealthCheckQuery{ServiceId: "foo"}
+	count, err := db.CountHealthChecks(query)
+	require.NoError(t, err)
+	require.Equal(t, 10, count)
+
+	query.Limit = 3
+	query.Offset = 2
+	hc, err := db.ListHealthChecks(query)
+	require.NoError(t, err)
+	require.Len(t, hc, 3)
+	// newest first
+	require.Equal(t, base.Add(7*time.Hour), hc[0].Timestamp)
+	require.Equal(t, base.Add(5*time.Hour), hc[2].Timestamp)
+
+	// from inclusive, to exclusive
+	query = HealthCheckQuery{ServiceId: "foo", From: base.Add(2 * time.Hour), To: base.Add(5 * time.Hour)}
+	hc, err = db.ListHealthChecks(query)
+	require.NoError(t, err)
+	require.Len(t, hc, 3)
+	count, err = db.CountHealthChecks(query)
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+
+	// no filter
+	count, err = db.CountHealthChecks(HealthCheckQuery{})
+	require.NoError(t, err)
+	require.Equal(t, 11, count)
+}
+
+func TestListTaskLogs(t *testing.T) {
+	db := NewTestDb(t)
+	defer db.Close()
+
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		err := db.CreateTaskLog(TaskInput{
+			TaskName: "report", Status: string(TASK_OK), Timestamp: base.Add(time.Duration(i) * time.Hour),
+		})
+		require.NoError(t, err)
+	}
+	err := db.CreateTaskLog(TaskInput{TaskName: "web_check", Status: string(TASK_ERROR), Timestamp: base})
+	require.NoError(t, err)
+
+	tasks, err := db.ListTaskLogs("report", 2, 1)
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	require.Equal(t, base.Add(3*time.Hour), tasks[0].Timestamp)
+	require.Equal(t, "report", tasks[0].TaskName)
+
+	tasks, err = db.ListTaskLogs("", NO_LIMIT, 0)
+	require.NoError(t, err)
+	require.Len(t, tasks, 6)
+}
+
+func TestTaskLogStats(t *testing.T) {
+	db := NewTestDb(t)
+	defer db.Close()
+
+	stats, err := db.TaskLogStats()
+	require.NoError(t, err)
+	require.Empty(t, stats)
+
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		err := db.CreateTaskLog(TaskInput{
+			TaskName: "report", Status: string(TASK_OK), Timestamp: base.Add(time.Duration(i) * time.Hour),
+		})
+		require.NoError(t, err)
+	}
+	err = db.CreateTaskLog(TaskInput{TaskName: "report", Status: string(TASK_ERROR), Timestamp: base})
+	require.NoError(t, err)
+	err = db.CreateTaskLog(TaskInput{TaskName: "web_check", Status: string(TASK_OK), Timestamp: base})
+	require.NoError(t, err)
+
+	stats, err = db.TaskLogStats()
+	require.NoError(t, err)
+	require.Len(t, stats, 3)
+	require.Equal(t, "report", stats[0].TaskName)
+	require.Equal(t, string(TASK_ERROR), stats[0].Status)
+	require.Equal(t, 1, stats[0].Count)
+	require.Equal(t, string(TASK_OK), stats[1].Status)
+	require.Equal(t, 3, stats[1].Count)
+	require.Equal(t, base.Add(2*time.Hour), stats[1].LatestTimestamp)
+	require.Equal(t, "web_check", stats[2].TaskName)
+}
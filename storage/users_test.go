@@ -28,3 +28,54 @@ func TestPassword(t *testing.T) {
 	require.NoError(t, err)
 	require.False(t, match, "password should not match")
 }
+
+func TestUserManagement(t *testing.T) {
+	db := NewTestDb(t)
+	defer db.Close()
+
+	users, err := db.ListUsers()
+	require.NoError(t, err)
+	require.Empty(t, users)
+
+	err = db.CreateUser("zed@example.com", "first")
+	require.NoError(t, err)
+	err = db.CreateUser("amy@example.com", "second")
+	require.NoError(t, err)
+
+	users, err = db.ListUsers()
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	require.Equal(t, "amy@example.com", users[0].Email())
+	require.Equal(t, "zed@example.com", users[1].Email())
+	require.False(t, users[0].CreatedAt().IsZero())
+
+	user, err := db.GetUser("amy@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	user, err = db.GetUser("nobody@example.com")
+	require.NoError(t, err)
+	require.Nil(t, user)
+
+	// password change
+	err = db.UpdatePassword("amy@example.com", "changed")
+	require.NoError(t, err)
+	user, err = db.ValidateUser("amy@example.com", "second")
+	require.NoError(t, err)
+	require.Nil(t, user)
+	user, err = db.ValidateUser("amy@example.com", "changed")
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	err = db.UpdatePassword("amy@example.com", "")
+	require.Error(t, err)
+	err = db.UpdatePassword("nobody@example.com", "any")
+	require.ErrorIs(t, err, ErrUserNotFound)
+
+	// delete
+	err = db.DeleteUser("zed@example.com")
+	require.NoError(t, err)
+	err = db.DeleteUser("zed@example.com")
+	require.ErrorIs(t, err, ErrUserNotFound)
+	users, err = db.ListUsers()
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+}
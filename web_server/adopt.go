@@ -0,0 +1,94 @@
+package web_server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/logging"
+	"github.com/davidmasek/beacon/monitor"
+	"github.com/davidmasek/beacon/storage"
+	"go.uber.org/zap"
+)
+
+// Find service by ID, including adopted and unconfigured services. Returns nil if not found.
+func findAnyService(db storage.Storage, config *conf.Config, serviceId string) (*conf.ServiceConfig, error) {
+	services, err := monitor.AllServices(db, config)
+	if err != nil {
+		return nil, err
+	}
+	for _, service := range services {
+		if service.Id == serviceId {
+			return &service, nil
+		}
+	}
+	return nil, nil
+}
+
+// Find service from the request path that is not defined in config file,
+// or write error response and return nil
+func managedServiceFromPath(w http.ResponseWriter, r *http.Request, db storage.Storage, config *conf.Config) *conf.ServiceConfig {
+	logger := logging.Get()
+	serviceId := r.PathValue("service_id")
+	service, err := findAnyService(db, config, serviceId)
+	if err != nil {
+		logger.Errorw("Failed to load services", zap.Error(err))
+		http.Error(w, "Failed to load services", http.StatusInternalServerError)
+		return nil
+	}
+	if service == nil {
+		http.NotFound(w, r)
+		return nil
+	}
+	if !service.Adopted && !service.Unconfigured {
+		http.Error(w, fmt.Sprintf("Service %q is defined in config file, change it there", serviceId), http.StatusBadRequest)
+		return nil
+	}
+	return service
+}
+
+// Store definition of an unconfigured service, so that it is monitored with the given timeout.
+// Can also be used to change timeout of an adopted service.
+func handleServiceAdopt(db storage.Storage, config *conf.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		service := managedServiceFromPath(w, r, db, config)
+		if service == nil {
+			return
+		}
+		timeout, err := conf.ParseDuration(strings.TrimSpace(r.PostFormValue("timeout")))
+		if err != nil || timeout <= 0 {
+			http.Error(w, "Invalid timeout, use e.g. 24h or 7d", http.StatusBadRequest)
+			return
+		}
+		err = db.AdoptService(service.Id, timeout, time.Now())
+		if err != nil {
+			logger.Errorw("Failed to adopt service", "service", service.Id, zap.Error(err))
+			http.Error(w, "Failed to adopt service", http.StatusInternalServerError)
+			return
+		}
+		logger.Infow("Service adopted", "service", service.Id, "timeout", timeout, "user", CurrentUser(r))
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
+
+// Delete all data of a service that is not defined in config file
+func handleServicePurge(db storage.Storage, config *conf.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		service := managedServiceFromPath(w, r, db, config)
+		if service == nil {
+			return
+		}
+		deleted, err := db.PurgeService(service.Id)
+		if err != nil {
+			logger.Errorw("Failed to purge service", "service", service.Id, zap.Error(err))
+			http.Error(w, "Failed to purge service", http.StatusInternalServerError)
+			return
+		}
+		logger.Infow("Service purged", "service", service.Id, "health_checks", deleted, "user", CurrentUser(r))
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
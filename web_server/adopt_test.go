@@ -0,0 +1,97 @@
+package web_server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/storage"
+)
+
+func TestAdoptPurgeServices(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	err = db.CreateUser("cj@example.com", "h4xor")
+	require.NoError(t, err)
+	sessions := NewSessionStore()
+	mux := http.NewServeMux()
+	RegisterGuiHandlers(db, mux, config, sessions)
+	sessionId, err := sessions.Create("cj@example.com", time.Now())
+	require.NoError(t, err)
+	session := &http.Cookie{Name: SESSION_COOKIE, Value: sessionId}
+
+	now := time.Now()
+	_, err = db.RecordHeartbeat("stray-cron", now.Add(-time.Hour))
+	require.NoError(t, err)
+	_, err = db.RecordHeartbeat("beacon-periodic-checker", now.Add(-time.Hour))
+	require.NoError(t, err)
+
+	getIndex := func() string {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+		return rr.Body.String()
+	}
+	csrf := &http.Cookie{Name: CSRF_COOKIE, Value: "csrf-value"}
+	post := func(path string, timeout string) *httptest.ResponseRecorder {
+		form := url.Values{CSRF_FORM_FIELD: {csrf.Value}}
+		if timeout != "" {
+			form.Set("timeout", timeout)
+		}
+		return postForm(mux, path, form, []*http.Cookie{session, csrf})
+	}
+
+	body := getIndex()
+	require.Contains(t, body, `data-service-id="stray-cron"`)
+	require.Contains(t, body, `class="tag tag-unconfigured"`)
+	require.Contains(t, body, `action="/services/stray-cron/adopt"`)
+	require.NotContains(t, body, `action="/services/beacon-periodic-checker/adopt"`)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/services/stray-cron", nil))
+	require.Equal(t, http.StatusOK, rr.Code, "detail page works for unconfigured services")
+
+	form := url.Values{CSRF_FORM_FIELD: {csrf.Value}, "timeout": {"2h"}}
+	rr = postForm(mux, "/services/stray-cron/adopt", form, []*http.Cookie{csrf})
+	require.Equal(t, http.StatusSeeOther, rr.Code, "login always required")
+	require.Contains(t, rr.Header().Get("Location"), "/login")
+	rr = postForm(mux, "/services/stray-cron/adopt", url.Values{"timeout": {"2h"}}, []*http.Cookie{session})
+	require.Equal(t, http.StatusForbidden, rr.Code, "CSRF token required")
+	rr = post("/services/stray-cron/adopt", "soon")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = post("/services/beacon-periodic-checker/adopt", "2h")
+	require.Equal(t, http.StatusBadRequest, rr.Code, "configured services cannot be adopted")
+	rr = post("/services/nonexistent/adopt", "2h")
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = post("/services/stray-cron/adopt", "2d")
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+	adopted, err := db.ListAdoptedServices()
+	require.NoError(t, err)
+	require.Len(t, adopted, 1)
+	require.Equal(t, 48*time.Hour, adopted[0].Timeout)
+	body = getIndex()
+	require.Contains(t, body, ">adopted</span>")
+	require.Contains(t, body, `name="timeout" value="2d"`)
+	require.NotContains(t, body, `class="tag tag-unconfigured"`)
+
+	rr = post("/services/beacon-periodic-checker/purge", "")
+	require.Equal(t, http.StatusBadRequest, rr.Code, "configured services cannot be purged")
+	rr = post("/services/stray-cron/purge", "")
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+	body = getIndex()
+	require.NotContains(t, body, "stray-cron")
+	adopted, err = db.ListAdoptedServices()
+	require.NoError(t, err)
+	require.Empty(t, adopted)
+	checks, err := db.LatestHealthChecks("beacon-periodic-checker", 10)
+	require.NoError(t, err)
+	require.Len(t, checks, 1, "other services are not affected")
+}
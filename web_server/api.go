@@ -0,0 +1,503 @@
+package web_server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/logging"
+	"github.com/davidmasek/beacon/monitor"
+	"github.com/davidmasek/beacon/storage"
+	"go.uber.org/zap"
+)
+
+const (
+	API_PREFIX        = "/api/v1"
+	API_DEFAULT_LIMIT = 50
+	API_MAX_LIMIT     = 1000
+	// upper bound for number of intervals computed for a single uptime request
+	API_MAX_INTERVALS = 100_000
+)
+
+type ApiError struct {
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+type ApiHealthCheck struct {
+	Id        int                   `json:"id"`
+	ServiceId string                `json:"service_id"`
+	Timestamp string                `json:"timestamp"`
+	Status    monitor.ServiceStatus `json:"status"`
+	Metadata  map[string]string     `json:"metadata"`
+}
+
+type ApiService struct {
+	Id        string                `json:"id"`
+	Type      string                `json:"type"`
+	Enabled   bool                  `json:"enabled"`
+	Timeout   string                `json:"timeout"`
+	Url       string                `json:"url,omitempty"`
+	Tags      []string              `json:"tags"`
+	Group     string                `json:"group,omitempty"`
+	Status    monitor.ServiceStatus `json:"status"`
+	LastCheck *ApiHealthCheck       `json:"last_check"`
+}
+
+type ApiHealthChecksPage struct {
+	Items  []ApiHealthCheck `json:"items"`
+	Total  int              `json:"total"`
+	Limit  int              `json:"limit"`
+	Offset int              `json:"offset"`
+}
+
+type ApiInterval struct {
+	Start  string                `json:"start"`
+	End    string                `json:"end"`
+	Status monitor.ServiceStatus `json:"status"`
+}
+
+type ApiUptime struct {
+	ServiceId   string        `json:"service_id"`
+	From        string        `json:"from"`
+	To          string        `json:"to"`
+	Interval    string        `json:"interval"`
+	UpPercent   float64       `json:"up_percent"`
+	DownPercent float64       `json:"down_percent"`
+	Intervals   []ApiInterval `json:"intervals,omitempty"`
+}
+
+type ApiTask struct {
+	TaskName  string `json:"task_name"`
+	Status    string `json:"status"`
+	Timestamp string `json:"timestamp"`
+	Details   string `json:"details"`
+}
+
+type ApiTasksPage struct {
+	Items  []ApiTask `json:"items"`
+	Limit  int       `json:"limit"`
+	Offset int       `json:"offset"`
+}
+
+func RegisterApiHandlers(db storage.Storage, mux *http.ServeMux, config *conf.Config, sessions *SessionStore) {
+	read := func(next http.HandlerFunc) http.HandlerFunc {
+		return apiAuth(db, config, sessions, next, storage.SCOPE_READ_ALL)
+	}
+	// changes always require auth, regardless of config
+	admin := func(next http.HandlerFunc) http.HandlerFunc {
+		return authenticateApi(db, sessions, true, next, storage.SCOPE_ADMIN)
+	}
+	mux.HandleFunc("GET "+API_PREFIX+"/services", read(handleApiServices(db, config)))
+	mux.HandleFunc("GET "+API_PREFIX+"/services/{service_id}", read(handleApiService(db, config)))
+	mux.HandleFunc("GET "+API_PREFIX+"/services/{service_id}/checks", read(handleApiChecks(db, config)))
+	mux.HandleFunc("GET "+API_PREFIX+"/services/{service_id}/uptime", read(handleApiUptime(db, config)))
+	mux.HandleFunc("GET "+API_PREFIX+"/tasks", read(handleApiTasks(db)))
+	mux.HandleFunc("GET "+API_PREFIX+"/maintenance", read(handleApiMaintenance(db, config)))
+	mux.HandleFunc("POST "+API_PREFIX+"/maintenance", admin(handleApiMaintenanceCreate(db, config)))
+	mux.HandleFunc("DELETE "+API_PREFIX+"/maintenance/{window_id}", admin(handleApiMaintenanceDelete(db)))
+	mux.HandleFunc("GET "+API_PREFIX+"/incidents", read(handleApiIncidents(db)))
+	mux.HandleFunc("GET "+API_PREFIX+"/incidents/{incident_id}", read(handleApiIncident(db)))
+	mux.HandleFunc("POST "+API_PREFIX+"/incidents/{incident_id}/acknowledge", admin(handleApiIncidentAcknowledge(db)))
+	mux.HandleFunc("POST "+API_PREFIX+"/incidents/{incident_id}/notes", admin(handleApiIncidentNote(db)))
+	// everything else under the prefix, so that clients always get JSON back
+	mux.HandleFunc(API_PREFIX+"/", func(w http.ResponseWriter, r *http.Request) {
+		writeApiError(w, http.StatusNotFound, "Not found")
+	})
+}
+
+// Check request is authorized by API token with one of the given scopes
+// or by a logged-in GUI user. Auth is skipped if not required by config.
+func apiAuth(db storage.Storage, config *conf.Config, sessions *SessionStore, next http.HandlerFunc, scopes ...string) http.HandlerFunc {
+	required := config.RequireApiAuth || config.RequireGuiLogin
+	return authenticateApi(db, sessions, required, next, scopes...)
+}
+
+func authenticateApi(db storage.Storage, sessions *SessionStore, required bool, next http.HandlerFunc, scopes ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		if !required {
+			next(w, r)
+			return
+		}
+		apiToken, err := monitor.CheckApiToken(db, r, scopes...)
+		if errors.Is(err, monitor.ErrTokenNotAllowed) {
+			writeApiError(w, http.StatusForbidden, "Token does not allow this action")
+			return
+		}
+		if errors.Is(err, monitor.ErrTokenInactive) {
+			writeApiError(w, http.StatusUnauthorized, "Token expired or revoked")
+			return
+		}
+		if err != nil {
+			logger.Errorw("Failed to check API token", zap.Error(err))
+			writeApiError(w, http.StatusInternalServerError, "Failed to check token")
+			return
+		}
+		if apiToken != nil {
+			ctx := context.WithValue(r.Context(), tokenContextKey, apiToken.Name)
+			next(w, r.WithContext(ctx))
+			return
+		}
+		user, err := sessionUser(db, sessions, r)
+		if err != nil {
+			logger.Errorw("Failed to load user", zap.Error(err))
+			writeApiError(w, http.StatusInternalServerError, "Failed to check session")
+			return
+		}
+		if user != "" {
+			ctx := context.WithValue(r.Context(), userContextKey, user)
+			next(w, r.WithContext(ctx))
+			return
+		}
+		writeApiError(w, http.StatusUnauthorized, "Unauthorized")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	logger := logging.Get()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		logger.Errorw("Failed to encode JSON response", zap.Error(err))
+	}
+}
+
+func writeApiError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, ApiError{Status: status, Error: message})
+}
+
+// Parse optional time query parameter in RFC3339 format.
+// Returns zero time if not present.
+func parseTimeParam(r *http.Request, name string) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s, expected RFC3339 timestamp, got %q", name, value)
+	}
+	return parsed, nil
+}
+
+// Decode JSON request body into target, or write error response and return false.
+// JSON content type is required, which browsers do not allow cross-site without CORS.
+func readJSON(w http.ResponseWriter, r *http.Request, target any) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		writeApiError(w, http.StatusUnsupportedMediaType, "Expected application/json body")
+		return false
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(target)
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return false
+	}
+	return true
+}
+
+// Parse `limit` and `offset` query parameters
+func parsePagination(r *http.Request) (limit int, offset int, err error) {
+	limit = API_DEFAULT_LIMIT
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > API_MAX_LIMIT {
+			return 0, 0, fmt.Errorf("invalid limit, expected number between 1 and %d, got %q", API_MAX_LIMIT, value)
+		}
+	}
+	if value := r.URL.Query().Get("offset"); value != "" {
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid offset, expected non-negative number, got %q", value)
+		}
+	}
+	return limit, offset, nil
+}
+
+func toApiHealthCheck(hc *storage.HealthCheck) ApiHealthCheck {
+	return ApiHealthCheck{
+		Id:        hc.Id,
+		ServiceId: hc.ServiceId,
+		Timestamp: hc.Timestamp.UTC().Format(storage.TIME_FORMAT),
+		Status:    monitor.HealthCheckStatus(hc),
+		Metadata:  hc.Metadata,
+	}
+}
+
+func buildApiService(db storage.Storage, serviceCfg conf.ServiceConfig) (*ApiService, error) {
+	healthCheck, err := db.LatestHealthCheck(serviceCfg.Id)
+	if err != nil {
+		return nil, err
+	}
+	checks := []*storage.HealthCheck{}
+	var lastCheck *ApiHealthCheck
+	if healthCheck != nil {
+		checks = append(checks, healthCheck)
+		apiCheck := toApiHealthCheck(healthCheck)
+		lastCheck = &apiCheck
+	}
+	serviceType := "heartbeat"
+	if serviceCfg.IsWebService() {
+		serviceType = "web"
+	}
+	// empty list instead of null in JSON
+	tags := serviceCfg.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return &ApiService{
+		Id:        serviceCfg.Id,
+		Type:      serviceType,
+		Enabled:   serviceCfg.Enabled,
+		Timeout:   serviceCfg.Timeout.String(),
+		Url:       serviceCfg.Url,
+		Tags:      tags,
+		Group:     serviceCfg.Group,
+		Status:    monitor.GetServiceStatus(serviceCfg, checks),
+		LastCheck: lastCheck,
+	}, nil
+}
+
+// Find configured service from the request path or write error response and return nil
+func apiServiceFromPath(w http.ResponseWriter, r *http.Request, config *conf.Config) *conf.ServiceConfig {
+	serviceId := r.PathValue("service_id")
+	serviceCfg := config.Services.Get(serviceId)
+	if serviceCfg == nil {
+		writeApiError(w, http.StatusNotFound, fmt.Sprintf("Service %q not found", serviceId))
+		return nil
+	}
+	return serviceCfg
+}
+
+func handleApiServices(db storage.Storage, config *conf.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		services := []ApiService{}
+		for _, serviceCfg := range config.AllServices() {
+			service, err := buildApiService(db, serviceCfg)
+			if err != nil {
+				logger.Errorw("Failed to load service", "service", serviceCfg.Id, zap.Error(err))
+				writeApiError(w, http.StatusInternalServerError, "Failed to load services")
+				return
+			}
+			services = append(services, *service)
+		}
+		writeJSON(w, http.StatusOK, services)
+	}
+}
+
+func handleApiService(db storage.Storage, config *conf.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		serviceCfg := apiServiceFromPath(w, r, config)
+		if serviceCfg == nil {
+			return
+		}
+		service, err := buildApiService(db, *serviceCfg)
+		if err != nil {
+			logger.Errorw("Failed to load service", "service", serviceCfg.Id, zap.Error(err))
+			writeApiError(w, http.StatusInternalServerError, "Failed to load service")
+			return
+		}
+		writeJSON(w, http.StatusOK, service)
+	}
+}
+
+func handleApiChecks(db storage.Storage, config *conf.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		serviceCfg := apiServiceFromPath(w, r, config)
+		if serviceCfg == nil {
+			return
+		}
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		from, err := parseTimeParam(r, "from")
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		to, err := parseTimeParam(r, "to")
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		query := storage.HealthCheckQuery{
+			ServiceId: serviceCfg.Id,
+			From:      from,
+			To:        to,
+			Limit:     limit,
+			Offset:    offset,
+		}
+		checks, err := db.ListHealthChecks(query)
+		if err != nil {
+			logger.Errorw("Failed to load health checks", "service", serviceCfg.Id, zap.Error(err))
+			writeApiError(w, http.StatusInternalServerError, "Failed to load health checks")
+			return
+		}
+		total, err := db.CountHealthChecks(query)
+		if err != nil {
+			logger.Errorw("Failed to count health checks", "service", serviceCfg.Id, zap.Error(err))
+			writeApiError(w, http.StatusInternalServerError, "Failed to load health checks")
+			return
+		}
+
+		page := ApiHealthChecksPage{
+			Items:  make([]ApiHealthCheck, 0, len(checks)),
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		}
+		for _, check := range checks {
+			page.Items = append(page.Items, toApiHealthCheck(check))
+		}
+		writeJSON(w, http.StatusOK, page)
+	}
+}
+
+// Uptime over `[from, to)`. `from` can be specified directly or using `window` (e.g. `30d`)
+// relative to `to`. `to` defaults to now.
+func handleApiUptime(db storage.Storage, config *conf.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		serviceCfg := apiServiceFromPath(w, r, config)
+		if serviceCfg == nil {
+			return
+		}
+		to, err := parseTimeParam(r, "to")
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if to.IsZero() {
+			to = time.Now().UTC()
+		}
+		from, err := parseTimeParam(r, "from")
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if window := r.URL.Query().Get("window"); window != "" {
+			if !from.IsZero() {
+				writeApiError(w, http.StatusBadRequest, "specify either from or window, not both")
+				return
+			}
+			duration, err := conf.ParseDuration(window)
+			if err != nil {
+				writeApiError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			from = to.Add(-duration)
+		}
+		if from.IsZero() {
+			from = to.Add(SUMMARY_STATS_LOOKBACK)
+		}
+		if !from.Before(to) {
+			writeApiError(w, http.StatusBadRequest, "from must be before to")
+			return
+		}
+		interval := SUMMARY_STATS_INTERVAL
+		if value := r.URL.Query().Get("interval"); value != "" {
+			interval, err = conf.ParseDuration(value)
+			if err != nil || interval <= 0 {
+				writeApiError(w, http.StatusBadRequest, fmt.Sprintf("invalid interval %q", value))
+				return
+			}
+		}
+		if to.Sub(from)/interval > API_MAX_INTERVALS {
+			writeApiError(w, http.StatusBadRequest, "too many intervals, use larger interval or shorter time range")
+			return
+		}
+
+		// include checks from one interval before start, they are used
+		// to decide the status of the first interval
+		checks, err := db.ListHealthChecks(storage.HealthCheckQuery{
+			ServiceId: serviceCfg.Id,
+			From:      from.Add(-interval),
+			To:        to,
+		})
+		if err != nil {
+			logger.Errorw("Failed to load health checks", "service", serviceCfg.Id, zap.Error(err))
+			writeApiError(w, http.StatusInternalServerError, "Failed to load health checks")
+			return
+		}
+		// BuildStatusIntervals expects oldest first
+		slices.Reverse(checks)
+
+		windows, err := serviceMaintenance(db, config, serviceCfg)
+		if err != nil {
+			logger.Errorw("Failed to load maintenance windows", "service", serviceCfg.Id, zap.Error(err))
+			writeApiError(w, http.StatusInternalServerError, "Failed to load maintenance windows")
+			return
+		}
+		intervals := monitor.BuildStatusIntervals(checks, from, to, interval)
+		monitor.MarkMaintenance(intervals, windows, config.Timezone.Location)
+		up, down := monitor.SummarizeIntervals(intervals)
+		uptime := ApiUptime{
+			ServiceId:   serviceCfg.Id,
+			From:        from.UTC().Format(storage.TIME_FORMAT),
+			To:          to.UTC().Format(storage.TIME_FORMAT),
+			Interval:    interval.String(),
+			UpPercent:   up,
+			DownPercent: down,
+		}
+		if r.URL.Query().Get("details") == "true" {
+			uptime.Intervals = make([]ApiInterval, 0, len(intervals))
+			for _, interval := range intervals {
+				uptime.Intervals = append(uptime.Intervals, ApiInterval{
+					Start:  interval.Interval.Start.UTC().Format(storage.TIME_FORMAT),
+					End:    interval.Interval.End.UTC().Format(storage.TIME_FORMAT),
+					Status: interval.Status,
+				})
+			}
+		}
+		writeJSON(w, http.StatusOK, uptime)
+	}
+}
+
+func handleApiTasks(db storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		taskName := r.URL.Query().Get("name")
+		tasks, err := db.ListTaskLogs(taskName, limit, offset)
+		if err != nil {
+			logger.Errorw("Failed to load tasks", zap.Error(err))
+			writeApiError(w, http.StatusInternalServerError, "Failed to load tasks")
+			return
+		}
+		page := ApiTasksPage{
+			Items:  make([]ApiTask, 0, len(tasks)),
+			Limit:  limit,
+			Offset: offset,
+		}
+		for _, task := range tasks {
+			page.Items = append(page.Items, ApiTask{
+				TaskName:  task.TaskName,
+				Status:    task.Status,
+				Timestamp: task.Timestamp.UTC().Format(storage.TIME_FORMAT),
+				Details:   task.Details,
+			})
+		}
+		writeJSON(w, http.StatusOK, page)
+	}
+}
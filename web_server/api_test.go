@@ -0,0 +1,218 @@
+package web_server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/monitor"
+	"github.com/davidmasek/beacon/storage"
+)
+
+func setupApi(t *testing.T) (storage.Storage, *http.ServeMux) {
+	db := storage.NewTestDb(t)
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	RegisterApiHandlers(db, mux, config, NewSessionStore())
+	return db, mux
+}
+
+func apiGet(t *testing.T, mux *http.ServeMux, url string, out any) int {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	err := json.Unmarshal(rr.Body.Bytes(), out)
+	require.NoError(t, err, rr.Body.String())
+	return rr.Code
+}
+
+func TestApiServices(t *testing.T) {
+	db, mux := setupApi(t)
+	defer db.Close()
+
+	_, err := db.RecordHeartbeat("beacon-periodic-checker", time.Now())
+	require.NoError(t, err)
+
+	var services []ApiService
+	code := apiGet(t, mux, "/api/v1/services", &services)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, services, 2)
+	assert.Equal(t, "beacon-github", services[0].Id)
+	assert.Equal(t, "web", services[0].Type)
+	assert.Equal(t, monitor.STATUS_FAIL, services[0].Status)
+	assert.Nil(t, services[0].LastCheck)
+	assert.Equal(t, "beacon-periodic-checker", services[1].Id)
+	assert.Equal(t, "heartbeat", services[1].Type)
+	assert.Equal(t, monitor.STATUS_OK, services[1].Status)
+	require.NotNil(t, services[1].LastCheck)
+
+	var service ApiService
+	code = apiGet(t, mux, "/api/v1/services/beacon-github", &service)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "https://github.com/davidmasek/beacon", service.Url)
+}
+
+func TestApiErrors(t *testing.T) {
+	db, mux := setupApi(t)
+	defer db.Close()
+
+	var apiErr ApiError
+	code := apiGet(t, mux, "/api/v1/services/unknown", &apiErr)
+	require.Equal(t, http.StatusNotFound, code)
+	require.Equal(t, http.StatusNotFound, apiErr.Status)
+	require.Contains(t, apiErr.Error, "unknown")
+
+	code = apiGet(t, mux, "/api/v1/does-not-exist", &apiErr)
+	require.Equal(t, http.StatusNotFound, code)
+
+	code = apiGet(t, mux, "/api/v1/services/beacon-github/checks?limit=0", &apiErr)
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Contains(t, apiErr.Error, "limit")
+
+	code = apiGet(t, mux, "/api/v1/services/beacon-github/checks?from=yesterday", &apiErr)
+	require.Equal(t, http.StatusBadRequest, code)
+
+	code = apiGet(t, mux, "/api/v1/services/beacon-github/uptime?window=abc", &apiErr)
+	require.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestApiChecks(t *testing.T) {
+	db, mux := setupApi(t)
+	defer db.Close()
+
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		err := db.AddHealthCheck(&storage.HealthCheckInput{
+			ServiceId: "beacon-github",
+			Timestamp: base.Add(time.Duration(i) * time.Hour),
+			Metadata:  map[string]string{"status": "OK"},
+		})
+		require.NoError(t, err)
+	}
+
+	var page ApiHealthChecksPage
+	code := apiGet(t, mux, "/api/v1/services/beacon-github/checks?limit=2&offset=1", &page)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, 5, page.Total)
+	require.Len(t, page.Items, 2)
+	require.Equal(t, "2025-03-01T15:00:00Z", page.Items[0].Timestamp)
+	require.Equal(t, monitor.STATUS_OK, page.Items[0].Status)
+
+	code = apiGet(t, mux, "/api/v1/services/beacon-github/checks?from=2025-03-01T13:00:00Z&to=2025-03-01T15:00:00Z", &page)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, 2, page.Total)
+	require.Len(t, page.Items, 2)
+}
+
+func TestApiUptime(t *testing.T) {
+	db, mux := setupApi(t)
+	defer db.Close()
+
+	base := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	// OK for the first half of the day, one check per interval
+	for i := range 12 {
+		err := db.AddHealthCheck(&storage.HealthCheckInput{
+			ServiceId: "beacon-periodic-checker",
+			Timestamp: base.Add(time.Duration(i)*time.Hour + 30*time.Minute),
+		})
+		require.NoError(t, err)
+	}
+
+	var uptime ApiUptime
+	code := apiGet(t, mux, "/api/v1/services/beacon-periodic-checker/uptime?window=1d&interval=1h&to=2025-03-02T00:00:00Z&details=true", &uptime)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "2025-03-01T00:00:00Z", uptime.From)
+	require.InDelta(t, 50, uptime.UpPercent, 0.01)
+	require.InDelta(t, 50, uptime.DownPercent, 0.01)
+	require.Len(t, uptime.Intervals, 24)
+}
+
+func TestApiTasks(t *testing.T) {
+	db, mux := setupApi(t)
+	defer db.Close()
+
+	now := time.Now()
+	err := db.CreateTaskLog(storage.TaskInput{TaskName: "report", Status: string(storage.TASK_OK), Timestamp: now})
+	require.NoError(t, err)
+	err = db.CreateTaskLog(storage.TaskInput{TaskName: "web_check", Status: string(storage.TASK_OK), Timestamp: now})
+	require.NoError(t, err)
+
+	var page ApiTasksPage
+	code := apiGet(t, mux, "/api/v1/tasks?name=report", &page)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, page.Items, 1)
+	require.Equal(t, "report", page.Items[0].TaskName)
+
+	code = apiGet(t, mux, "/api/v1/tasks", &page)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, page.Items, 2)
+}
+
+func TestApiAuth(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	config.RequireApiAuth = true
+	mux := http.NewServeMux()
+	RegisterApiHandlers(db, mux, config, NewSessionStore())
+
+	readToken, _, err := db.CreateApiToken("reader", []string{storage.SCOPE_READ_ALL}, time.Time{})
+	require.NoError(t, err)
+	beatToken, _, err := db.CreateApiToken("beat", []string{storage.ScopeBeat("beacon-github")}, time.Time{})
+	require.NoError(t, err)
+	expiredToken, _, err := db.CreateApiToken("old", []string{storage.SCOPE_ADMIN}, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+
+	get := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/services", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, get(""))
+	assert.Equal(t, http.StatusUnauthorized, get("bcn_unknown"))
+	assert.Equal(t, http.StatusUnauthorized, get(expiredToken))
+	assert.Equal(t, http.StatusForbidden, get(beatToken))
+	assert.Equal(t, http.StatusOK, get(readToken))
+}
+
+func TestApiRequiresGuiLogin(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	config.RequireGuiLogin = true
+	require.False(t, config.RequireApiAuth)
+	err = db.CreateUser("cj@example.com", "h4xor")
+	require.NoError(t, err)
+	sessions := NewSessionStore()
+	mux := http.NewServeMux()
+	RegisterApiHandlers(db, mux, config, sessions)
+
+	// anonymous access to API would bypass the GUI login
+	var apiErr ApiError
+	code := apiGet(t, mux, "/api/v1/services", &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	sessionId, err := sessions.Create("cj@example.com", time.Now())
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/services", nil)
+	req.AddCookie(&http.Cookie{Name: SESSION_COOKIE, Value: sessionId})
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
@@ -0,0 +1,321 @@
+package web_server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/logging"
+	"github.com/davidmasek/beacon/storage"
+	"go.uber.org/zap"
+)
+
+const (
+	SESSION_COOKIE   = "beacon_session"
+	CSRF_COOKIE      = "beacon_csrf"
+	CSRF_FORM_FIELD  = "csrf_token"
+	SESSION_DURATION = 7 * 24 * time.Hour
+)
+
+type contextKey string
+
+const (
+	userContextKey contextKey = "user"
+	// name of the API token used for the request
+	tokenContextKey contextKey = "token"
+)
+
+type Session struct {
+	Email   string
+	Expires time.Time
+}
+
+// In-memory session store. Sessions do not survive restarts.
+type SessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+func NewSessionStore() *SessionStore {
+	return &SessionStore{sessions: map[string]Session{}}
+}
+
+// Create session for given user and return its ID
+func (store *SessionStore) Create(email string, now time.Time) (string, error) {
+	id, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	// drop expired sessions so the map does not grow forever
+	for key, session := range store.sessions {
+		if now.After(session.Expires) {
+			delete(store.sessions, key)
+		}
+	}
+	store.sessions[id] = Session{Email: email, Expires: now.Add(SESSION_DURATION)}
+	return id, nil
+}
+
+// Get session by ID. Returns nil if not found or expired.
+func (store *SessionStore) Get(id string, now time.Time) *Session {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	session, ok := store.sessions[id]
+	if !ok {
+		return nil
+	}
+	if now.After(session.Expires) {
+		delete(store.sessions, id)
+		return nil
+	}
+	return &session
+}
+
+func (store *SessionStore) Delete(id string) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.sessions, id)
+}
+
+// Delete all sessions of given user
+func (store *SessionStore) DeleteUser(email string) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for key, session := range store.sessions {
+		if session.Email == email {
+			delete(store.sessions, key)
+		}
+	}
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Mark cookies as Secure if the request came over HTTPS, either directly
+// or through a TLS-terminating reverse proxy.
+func isSecureRequest(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+// Email of the logged-in user, empty if not logged in.
+func CurrentUser(r *http.Request) string {
+	user, _ := r.Context().Value(userContextKey).(string)
+	return user
+}
+
+// Who made the request: email of the logged-in user or "token <name>" for API tokens.
+// Empty if neither is known, e.g. when login is not required.
+func RequestActor(r *http.Request) string {
+	if user := CurrentUser(r); user != "" {
+		return user
+	}
+	if token, _ := r.Context().Value(tokenContextKey).(string); token != "" {
+		return "token " + token
+	}
+	return ""
+}
+
+// Return CSRF token for the current client, setting the cookie if needed.
+//
+// Uses the double-submit cookie pattern: the same random value is sent
+// as a cookie and as a hidden form field, and both must match on POST.
+func csrfToken(w http.ResponseWriter, r *http.Request) string {
+	logger := logging.Get()
+	cookie, err := r.Cookie(CSRF_COOKIE)
+	if err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	token, err := randomToken()
+	if err != nil {
+		logger.Errorw("Failed to generate CSRF token", zap.Error(err))
+		return ""
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRF_COOKIE,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteStrictMode,
+	})
+	// make the token visible to subsequent calls within the same request
+	r.AddCookie(&http.Cookie{Name: CSRF_COOKIE, Value: token})
+	return token
+}
+
+// Check the CSRF form field matches the CSRF cookie.
+// Form must be parsed before calling.
+func verifyCsrf(r *http.Request) bool {
+	cookie, err := r.Cookie(CSRF_COOKIE)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	formToken := r.PostFormValue(CSRF_FORM_FIELD)
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(formToken)) == 1
+}
+
+// Reject POST requests without valid CSRF token
+func csrfProtect(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && !verifyCsrf(r) {
+			http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Return email of the user logged in with a valid session, empty if none
+func sessionUser(db storage.Storage, sessions *SessionStore, r *http.Request) (string, error) {
+	cookie, err := r.Cookie(SESSION_COOKIE)
+	if err != nil {
+		return "", nil
+	}
+	session := sessions.Get(cookie.Value, time.Now())
+	if session == nil {
+		return "", nil
+	}
+	// user might have been deleted (e.g. using CLI) since logging in
+	user, err := db.GetUser(session.Email)
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		sessions.DeleteUser(session.Email)
+		return "", nil
+	}
+	return user.Email(), nil
+}
+
+// Attach logged-in user (if any) to the request context and,
+// if login is required, redirect anonymous users to the login page.
+func authenticate(db storage.Storage, sessions *SessionStore, required bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		user, err := sessionUser(db, sessions, r)
+		if err != nil {
+			logger.Errorw("Failed to load user", zap.Error(err))
+			http.Error(w, "Server error, please try again later", http.StatusInternalServerError)
+			return
+		}
+		if user != "" {
+			ctx := context.WithValue(r.Context(), userContextKey, user)
+			next(w, r.WithContext(ctx))
+			return
+		}
+		if required {
+			target := "/login?next=" + url.QueryEscape(r.URL.RequestURI())
+			http.Redirect(w, r, target, http.StatusSeeOther)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Require login if configured (RequireGuiLogin)
+func requireLogin(db storage.Storage, config *conf.Config, sessions *SessionStore, next http.HandlerFunc) http.HandlerFunc {
+	return authenticate(db, sessions, config.RequireGuiLogin, next)
+}
+
+// Always require login, used for pages that manage Beacon
+func requireUser(db storage.Storage, sessions *SessionStore, next http.HandlerFunc) http.HandlerFunc {
+	return authenticate(db, sessions, true, next)
+}
+
+// Only allow local redirects to prevent open redirect attacks
+func safeRedirectTarget(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+func renderLogin(w http.ResponseWriter, r *http.Request, status int, errorMessage string) {
+	logger := logging.Get()
+	// cookie must be set before writing the header
+	token := csrfToken(w, r)
+	w.WriteHeader(status)
+	err := LOGIN_TEMPLATE.Execute(w, map[string]any{
+		"CurrentPage": "login",
+		"CsrfToken":   token,
+		"Next":        safeRedirectTarget(r.FormValue("next")),
+		"Error":       errorMessage,
+	})
+	if err != nil {
+		logger.Errorw("Failed to render", zap.Error(err))
+	}
+}
+
+func handleLogin(db storage.Storage, sessions *SessionStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		if r.Method != http.MethodPost {
+			renderLogin(w, r, http.StatusOK, "")
+			return
+		}
+		email := strings.TrimSpace(r.PostFormValue("email"))
+		password := r.PostFormValue("password")
+		user, err := db.ValidateUser(email, password)
+		if err != nil {
+			logger.Errorw("Failed to validate user", zap.Error(err))
+			renderLogin(w, r, http.StatusInternalServerError, "Server error, please try again later")
+			return
+		}
+		if user == nil {
+			logger.Infow("Failed login attempt", "email", email)
+			renderLogin(w, r, http.StatusUnauthorized, "Invalid email or password")
+			return
+		}
+		sessionId, err := sessions.Create(user.Email(), time.Now())
+		if err != nil {
+			logger.Errorw("Failed to create session", zap.Error(err))
+			renderLogin(w, r, http.StatusInternalServerError, "Server error, please try again later")
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     SESSION_COOKIE,
+			Value:    sessionId,
+			Path:     "/",
+			MaxAge:   int(SESSION_DURATION.Seconds()),
+			HttpOnly: true,
+			Secure:   isSecureRequest(r),
+			SameSite: http.SameSiteLaxMode,
+		})
+		logger.Infow("User logged in", "email", user.Email())
+		http.Redirect(w, r, safeRedirectTarget(r.PostFormValue("next")), http.StatusSeeOther)
+	}
+}
+
+func handleLogout(sessions *SessionStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(SESSION_COOKIE)
+		if err == nil {
+			sessions.Delete(cookie.Value)
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     SESSION_COOKIE,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   isSecureRequest(r),
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	}
+}
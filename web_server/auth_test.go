@@ -0,0 +1,234 @@
+package web_server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/storage"
+)
+
+func TestSessionStore(t *testing.T) {
+	store := NewSessionStore()
+	now := time.Now()
+
+	id, err := store.Create("cj@example.com", now)
+	require.NoError(t, err)
+	session := store.Get(id, now)
+	require.NotNil(t, session)
+	require.Equal(t, "cj@example.com", session.Email)
+
+	require.Nil(t, store.Get("other", now))
+	require.Nil(t, store.Get(id, now.Add(SESSION_DURATION+time.Second)), "session should expire")
+
+	id, err = store.Create("cj@example.com", now)
+	require.NoError(t, err)
+	store.Delete(id)
+	require.Nil(t, store.Get(id, now))
+}
+
+func TestSafeRedirectTarget(t *testing.T) {
+	require.Equal(t, "/about", safeRedirectTarget("/about"))
+	require.Equal(t, "/", safeRedirectTarget("https://evil.example.com"))
+	require.Equal(t, "/", safeRedirectTarget("//evil.example.com"))
+	require.Equal(t, "/", safeRedirectTarget(""))
+}
+
+func postForm(mux *http.ServeMux, path string, form url.Values, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	return rr
+}
+
+func findCookie(cookies []*http.Cookie, name string) *http.Cookie {
+	for _, cookie := range cookies {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestLoginFlow(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	config.RequireGuiLogin = true
+	err = db.CreateUser("cj@example.com", "h4xor")
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	RegisterGuiHandlers(db, mux, config, NewSessionStore())
+
+	// anonymous -> redirect
+	req := httptest.NewRequest(http.MethodGet, "/about", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+	require.Equal(t, "/login?next=%2Fabout", rr.Header().Get("Location"))
+
+	// login page sets CSRF cookie
+	req = httptest.NewRequest(http.MethodGet, "/login?next=/about", nil)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	csrf := findCookie(rr.Result().Cookies(), CSRF_COOKIE)
+	require.NotNil(t, csrf)
+	require.Contains(t, rr.Body.String(), csrf.Value)
+
+	// missing CSRF token
+	form := url.Values{"email": {"cj@example.com"}, "password": {"h4xor"}, "next": {"/about"}}
+	rr = postForm(mux, "/login", form, []*http.Cookie{csrf})
+	require.Equal(t, http.StatusForbidden, rr.Code)
+
+	// bad password
+	form.Set(CSRF_FORM_FIELD, csrf.Value)
+	form.Set("password", "idk")
+	rr = postForm(mux, "/login", form, []*http.Cookie{csrf})
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+	require.Nil(t, findCookie(rr.Result().Cookies(), SESSION_COOKIE))
+
+	// success
+	form.Set("password", "h4xor")
+	rr = postForm(mux, "/login", form, []*http.Cookie{csrf})
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+	require.Equal(t, "/about", rr.Header().Get("Location"))
+	session := findCookie(rr.Result().Cookies(), SESSION_COOKIE)
+	require.NotNil(t, session)
+	require.True(t, session.HttpOnly)
+
+	req = httptest.NewRequest(http.MethodGet, "/about", nil)
+	req.AddCookie(session)
+	req.AddCookie(csrf)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), "cj@example.com")
+
+	// logout
+	rr = postForm(mux, "/logout", url.Values{CSRF_FORM_FIELD: {csrf.Value}}, []*http.Cookie{session, csrf})
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/about", nil)
+	req.AddCookie(session)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusSeeOther, rr.Code, "session should be invalidated")
+}
+
+func TestLoginNotRequired(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	require.False(t, config.RequireGuiLogin)
+
+	mux := http.NewServeMux()
+	RegisterGuiHandlers(db, mux, config, NewSessionStore())
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestDeletedUserLoggedOut(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	config.RequireGuiLogin = true
+	err = db.CreateUser("cj@example.com", "h4xor")
+	require.NoError(t, err)
+
+	sessions := NewSessionStore()
+	mux := http.NewServeMux()
+	RegisterGuiHandlers(db, mux, config, sessions)
+	sessionId, err := sessions.Create("cj@example.com", time.Now())
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/about", nil)
+	req.AddCookie(&http.Cookie{Name: SESSION_COOKIE, Value: sessionId})
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	err = db.DeleteUser("cj@example.com")
+	require.NoError(t, err)
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+}
+
+func TestTokensPage(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	err = db.CreateUser("cj@example.com", "h4xor")
+	require.NoError(t, err)
+	sessions := NewSessionStore()
+	mux := http.NewServeMux()
+	RegisterGuiHandlers(db, mux, config, sessions)
+
+	// token management always requires login
+	req := httptest.NewRequest(http.MethodGet, "/tokens", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+
+	sessionId, err := sessions.Create("cj@example.com", time.Now())
+	require.NoError(t, err)
+	session := &http.Cookie{Name: SESSION_COOKIE, Value: sessionId}
+	csrf := &http.Cookie{Name: CSRF_COOKIE, Value: "csrf-value"}
+
+	form := url.Values{
+		CSRF_FORM_FIELD: {csrf.Value},
+		"name":          {"ci"},
+		"scopes":        {"beat:my-app read:all"},
+		"expires":       {"30d"},
+	}
+	rr = postForm(mux, "/tokens", form, []*http.Cookie{session})
+	require.Equal(t, http.StatusForbidden, rr.Code, "missing CSRF cookie")
+
+	rr = postForm(mux, "/tokens", form, []*http.Cookie{session, csrf})
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), storage.API_TOKEN_PREFIX)
+	tokens, err := db.ListApiTokens()
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	require.Equal(t, []string{"beat:my-app", "read:all"}, tokens[0].Scopes)
+	require.False(t, tokens[0].ExpiresAt.IsZero())
+
+	form.Set("scopes", "write:all")
+	rr = postForm(mux, "/tokens", form, []*http.Cookie{session, csrf})
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = postForm(mux, fmt.Sprintf("/tokens/%d/revoke", tokens[0].Id), url.Values{CSRF_FORM_FIELD: {csrf.Value}}, []*http.Cookie{session, csrf})
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+	tokens, err = db.ListApiTokens()
+	require.NoError(t, err)
+	require.False(t, tokens[0].RevokedAt.IsZero())
+
+	req = httptest.NewRequest(http.MethodGet, "/tokens", nil)
+	req.AddCookie(session)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), "revoked")
+	require.NotContains(t, rr.Body.String(), storage.API_TOKEN_PREFIX)
+}
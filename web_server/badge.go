@@ -0,0 +1,199 @@
+package web_server
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/logging"
+	"github.com/davidmasek/beacon/monitor"
+	"github.com/davidmasek/beacon/storage"
+	"go.uber.org/zap"
+)
+
+const (
+	// How long can clients (and proxies for public services) cache badges
+	BADGE_CACHE_MAX_AGE  = 5 * time.Minute
+	BADGE_DEFAULT_WINDOW = 30 * 24 * time.Hour
+
+	BADGE_COLOR_LABEL   = "#555"
+	BADGE_COLOR_GREEN   = "#4c1"
+	BADGE_COLOR_LIME    = "#97ca00"
+	BADGE_COLOR_YELLOW  = "#dfb317"
+	BADGE_COLOR_ORANGE  = "#fe7d37"
+	BADGE_COLOR_RED     = "#e05d44"
+	BADGE_COLOR_UNKNOWN = "#9f9f9f"
+)
+
+func RegisterBadgeHandlers(db storage.Storage, mux *http.ServeMux, config *conf.Config, sessions *SessionStore) {
+	mux.HandleFunc("GET /badge/{service_id}/status.svg", handleStatusBadge(db, config, sessions))
+	mux.HandleFunc("GET /badge/{service_id}/uptime.svg", handleUptimeBadge(db, config, sessions))
+}
+
+// Rough text width in pixels for 11px Verdana, good enough for short labels
+func badgeTextWidth(text string) int {
+	return utf8.RuneCountInString(text)*7 + 10
+}
+
+// Render flat shields-style badge
+func renderBadge(label, message, color string) []byte {
+	labelWidth := badgeTextWidth(label)
+	messageWidth := badgeTextWidth(message)
+	width := labelWidth + messageWidth
+	label = html.EscapeString(label)
+	message = html.EscapeString(message)
+	return fmt.Appendf(nil, `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[2]s: %[3]s">`+
+		`<title>%[2]s: %[3]s</title>`+
+		`<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`+
+		`<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>`+
+		`<g clip-path="url(#r)"><rect width="%[4]d" height="20" fill="%[6]s"/><rect x="%[4]d" width="%[5]d" height="20" fill="%[7]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[2]s</text><text x="%[8]d" y="14">%[2]s</text>`+
+		`<text x="%[9]d" y="15" fill="#010101" fill-opacity=".3">%[3]s</text><text x="%[9]d" y="14">%[3]s</text>`+
+		`</g></svg>`,
+		width, label, message, labelWidth, messageWidth, BADGE_COLOR_LABEL, color,
+		labelWidth/2, labelWidth+messageWidth/2,
+	)
+}
+
+func uptimeColor(up float64) string {
+	switch {
+	case up >= 99.9:
+		return BADGE_COLOR_GREEN
+	case up >= 99:
+		return BADGE_COLOR_LIME
+	case up >= 95:
+		return BADGE_COLOR_YELLOW
+	case up >= 90:
+		return BADGE_COLOR_ORANGE
+	default:
+		return BADGE_COLOR_RED
+	}
+}
+
+// Return service config if the badge can be shown for the requester.
+// Public services are visible to everyone. Others only with an API token
+// or GUI session, and look the same as unknown services otherwise
+// to avoid leaking which services exist.
+func badgeService(w http.ResponseWriter, r *http.Request, db storage.Storage, config *conf.Config, sessions *SessionStore) (serviceCfg *conf.ServiceConfig, public bool) {
+	logger := logging.Get()
+	serviceId := r.PathValue("service_id")
+	serviceCfg = config.Services.Get(serviceId)
+	// disabled services are hidden, same as on the public status page
+	if serviceCfg != nil && serviceCfg.Public && serviceCfg.Enabled {
+		return serviceCfg, true
+	}
+	if serviceCfg != nil {
+		apiToken, err := monitor.CheckApiToken(db, r, storage.ScopeBeat(serviceId), storage.SCOPE_READ_ALL)
+		if err == nil && apiToken != nil {
+			return serviceCfg, false
+		}
+		user, err := sessionUser(db, sessions, r)
+		if err != nil {
+			logger.Errorw("Failed to load user", zap.Error(err))
+		}
+		if user != "" {
+			return serviceCfg, false
+		}
+	}
+	http.NotFound(w, r)
+	return nil, false
+}
+
+func writeBadge(w http.ResponseWriter, badge []byte, public bool) {
+	logger := logging.Get()
+	visibility := "private"
+	if public {
+		visibility = "public"
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", visibility, int(BADGE_CACHE_MAX_AGE.Seconds())))
+	_, err := w.Write(badge)
+	if err != nil {
+		logger.Errorw("Failed to write badge", zap.Error(err))
+	}
+}
+
+// Label from query parameter, service name otherwise
+func badgeLabel(r *http.Request, serviceCfg *conf.ServiceConfig) string {
+	if label := r.URL.Query().Get("label"); label != "" {
+		return label
+	}
+	return serviceCfg.Name()
+}
+
+func handleStatusBadge(db storage.Storage, config *conf.Config, sessions *SessionStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		serviceCfg, public := badgeService(w, r, db, config, sessions)
+		if serviceCfg == nil {
+			return
+		}
+		checks, err := db.HealthChecksSince(serviceCfg.Id, time.Now().Add(-serviceCfg.Timeout))
+		if err != nil {
+			logger.Errorw("Failed to load health checks", "service", serviceCfg.Id, zap.Error(err))
+			http.Error(w, "Failed to load health checks", http.StatusInternalServerError)
+			return
+		}
+		message, color := "unknown", BADGE_COLOR_UNKNOWN
+		switch monitor.GetServiceStatus(*serviceCfg, checks) {
+		case monitor.STATUS_OK:
+			message, color = "up", BADGE_COLOR_GREEN
+		case monitor.STATUS_FAIL:
+			message, color = "down", BADGE_COLOR_RED
+		}
+		writeBadge(w, renderBadge(badgeLabel(r, serviceCfg), message, color), public)
+	}
+}
+
+func handleUptimeBadge(db storage.Storage, config *conf.Config, sessions *SessionStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		serviceCfg, public := badgeService(w, r, db, config, sessions)
+		if serviceCfg == nil {
+			return
+		}
+		window := BADGE_DEFAULT_WINDOW
+		if value := r.URL.Query().Get("window"); value != "" {
+			var err error
+			window, err = conf.ParseDuration(value)
+			if err != nil || window <= 0 {
+				http.Error(w, fmt.Sprintf("invalid window %q", value), http.StatusBadRequest)
+				return
+			}
+		}
+		if window/SUMMARY_STATS_INTERVAL > API_MAX_INTERVALS {
+			http.Error(w, "window too long", http.StatusBadRequest)
+			return
+		}
+		to := time.Now().UTC()
+		from := to.Add(-window)
+		checks, err := db.ListHealthChecks(storage.HealthCheckQuery{
+			ServiceId: serviceCfg.Id,
+			From:      from.Add(-SUMMARY_STATS_INTERVAL),
+			To:        to,
+		})
+		if err != nil {
+			logger.Errorw("Failed to load health checks", "service", serviceCfg.Id, zap.Error(err))
+			http.Error(w, "Failed to load health checks", http.StatusInternalServerError)
+			return
+		}
+		// BuildStatusIntervals expects oldest first
+		slices.Reverse(checks)
+		windows, err := serviceMaintenance(db, config, serviceCfg)
+		if err != nil {
+			logger.Errorw("Failed to load maintenance windows", "service", serviceCfg.Id, zap.Error(err))
+			http.Error(w, "Failed to load maintenance windows", http.StatusInternalServerError)
+			return
+		}
+		intervals := monitor.BuildStatusIntervals(checks, from, to, SUMMARY_STATS_INTERVAL)
+		monitor.MarkMaintenance(intervals, windows, config.Timezone.Location)
+		up, _ := monitor.SummarizeIntervals(intervals)
+		message := fmt.Sprintf("%.2f%%", up)
+		writeBadge(w, renderBadge(badgeLabel(r, serviceCfg), message, uptimeColor(up)), public)
+	}
+}
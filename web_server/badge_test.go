@@ -0,0 +1,114 @@
+package web_server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/storage"
+)
+
+var BADGE_CFG = []byte(`
+services:
+  public-api:
+    public: true
+    display_name: "Acme API"
+  private-job:
+  old-api:
+    public: true
+    enabled: false
+`)
+
+func setupBadges(t *testing.T) (storage.Storage, *http.ServeMux) {
+	db := storage.NewTestDb(t)
+	config, err := conf.ConfigFromBytes(BADGE_CFG)
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	RegisterBadgeHandlers(db, mux, config, NewSessionStore())
+	return db, mux
+}
+
+func getBadge(mux *http.ServeMux, url string, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestStatusBadge(t *testing.T) {
+	db, mux := setupBadges(t)
+	defer db.Close()
+
+	rr := getBadge(mux, "/badge/public-api/status.svg", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "image/svg+xml", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=300", rr.Header().Get("Cache-Control"))
+	assert.Contains(t, rr.Body.String(), "Acme API: down")
+
+	_, err := db.RecordHeartbeat("public-api", time.Now())
+	require.NoError(t, err)
+	rr = getBadge(mux, "/badge/public-api/status.svg?label=api", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "api: up")
+	assert.Contains(t, rr.Body.String(), BADGE_COLOR_GREEN)
+}
+
+func TestBadgeVisibility(t *testing.T) {
+	db, mux := setupBadges(t)
+	defer db.Close()
+
+	// private and unknown services look the same
+	rr := getBadge(mux, "/badge/private-job/status.svg", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = getBadge(mux, "/badge/private-job/uptime.svg", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = getBadge(mux, "/badge/unknown/status.svg", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = getBadge(mux, "/badge/old-api/status.svg", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code, "disabled services are not public")
+
+	token, _, err := db.CreateApiToken("wiki", []string{storage.SCOPE_READ_ALL}, time.Time{})
+	require.NoError(t, err)
+	rr = getBadge(mux, "/badge/private-job/status.svg", token)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "private, max-age=300", rr.Header().Get("Cache-Control"))
+	rr = getBadge(mux, "/badge/unknown/status.svg", token)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestUptimeBadge(t *testing.T) {
+	db, mux := setupBadges(t)
+	defer db.Close()
+
+	now := time.Now()
+	// heartbeats during the last day
+	for i := range 6 * 25 {
+		_, err := db.RecordHeartbeat("public-api", now.Add(-time.Duration(i)*10*time.Minute))
+		require.NoError(t, err)
+	}
+
+	rr := getBadge(mux, "/badge/public-api/uptime.svg?window=1d", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Acme API: 100.00%")
+
+	rr = getBadge(mux, "/badge/public-api/uptime.svg?window=2d", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), BADGE_COLOR_RED)
+
+	rr = getBadge(mux, "/badge/public-api/uptime.svg?window=abc", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestRenderBadgeEscapes(t *testing.T) {
+	badge := string(renderBadge(`<script>`, "up", BADGE_COLOR_GREEN))
+	assert.NotContains(t, badge, "<script>")
+	assert.Contains(t, badge, "&lt;script&gt;")
+}
@@ -0,0 +1,78 @@
+package web_server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/logging"
+	"github.com/davidmasek/beacon/monitor"
+	"github.com/davidmasek/beacon/storage"
+	"go.uber.org/zap"
+)
+
+// Comment sent periodically so that proxies do not close idle connections
+const EVENTS_KEEPALIVE_INTERVAL = 30 * time.Second
+
+func RegisterEventHandlers(db storage.Storage, mux *http.ServeMux, config *conf.Config, sessions *SessionStore, broker *monitor.Broker) {
+	mux.HandleFunc("GET /events", requireLogin(db, config, sessions, handleEvents(broker)))
+}
+
+// Stream health check and status change events using Server-Sent Events
+func handleEvents(broker *monitor.Broker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		rc := http.NewResponseController(w)
+		// subscribe before responding, so that no events are missed after the client connects
+		events, unsubscribe := broker.Subscribe()
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		// disable response buffering in NGINX
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		// client should reconnect after 5s if the connection drops
+		_, err := fmt.Fprint(w, "retry: 5000\n\n")
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			logger.Errorw("Failed to start event stream", zap.Error(err))
+			return
+		}
+
+		keepalive := time.NewTicker(EVENTS_KEEPALIVE_INTERVAL)
+		defer keepalive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepalive.C:
+				_, err = fmt.Fprint(w, ": keepalive\n\n")
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				var data []byte
+				data, err = json.Marshal(event)
+				if err != nil {
+					logger.Errorw("Failed to encode event", zap.Error(err))
+					continue
+				}
+				_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			}
+			if err == nil {
+				err = rc.Flush()
+			}
+			if err != nil {
+				logger.Debugw("Event stream closed", zap.Error(err))
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package web_server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/monitor"
+	"github.com/davidmasek/beacon/storage"
+)
+
+func TestEventStream(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	broker := monitor.NewBroker()
+	mux := http.NewServeMux()
+	RegisterEventHandlers(db, mux, config, NewSessionStore(), broker)
+	server := httptest.NewServer(instrumentHandler(mux))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	// read single event (lines until empty line)
+	readEvent := func() []string {
+		lines := []string{}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				line = strings.TrimRight(line, "\n")
+				if line == "" {
+					return
+				}
+				lines = append(lines, line)
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			require.FailNow(t, "timeout waiting for event")
+		}
+		return lines
+	}
+
+	require.Equal(t, []string{"retry: 5000"}, readEvent())
+
+	broker.PublishHealthCheck(&storage.HealthCheckInput{ServiceId: "beacon-github", Timestamp: time.Now()})
+	lines := readEvent()
+	require.Len(t, lines, 2)
+	assert.Equal(t, "event: health_check", lines[0])
+	var event monitor.Event
+	err = json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data: ")), &event)
+	require.NoError(t, err)
+	assert.Equal(t, "beacon-github", event.ServiceId)
+	assert.Equal(t, monitor.STATUS_OK, event.Status)
+
+	broker.SetStatus("beacon-github", monitor.STATUS_FAIL, time.Now())
+	lines = readEvent()
+	require.Len(t, lines, 2)
+	assert.Equal(t, "event: status_change", lines[0])
+	assert.Contains(t, lines[1], `"previous_status":"OK"`)
+}
+
+func TestEventStreamRequiresLogin(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	config.RequireGuiLogin = true
+	mux := http.NewServeMux()
+	RegisterEventHandlers(db, mux, config, NewSessionStore(), monitor.NewBroker())
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+}
@@ -0,0 +1,452 @@
+package web_server
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/logging"
+	"github.com/davidmasek/beacon/monitor"
+	"github.com/davidmasek/beacon/storage"
+	"go.uber.org/zap"
+)
+
+const (
+	INCIDENTS_PAGE_SIZE = 50
+	// number of incidents on the service detail page
+	SERVICE_INCIDENTS_LIMIT = 5
+)
+
+type IncidentView struct {
+	Id             int
+	ServiceId      string
+	OpenedAt       string
+	ResolvedAt     string
+	Duration       string
+	Open           bool
+	Acknowledged   bool
+	AcknowledgedAt string
+	AcknowledgedBy string
+}
+
+type IncidentNoteView struct {
+	Author    string
+	Text      string
+	CreatedAt string
+}
+
+func toIncidentView(incident *storage.Incident, now time.Time, loc *time.Location) IncidentView {
+	interval := monitor.Interval{Start: incident.OpenedAt, End: now}
+	view := IncidentView{
+		Id:             incident.Id,
+		ServiceId:      incident.ServiceId,
+		OpenedAt:       incident.OpenedAt.In(loc).Format(time.DateTime),
+		Open:           incident.IsOpen(),
+		Acknowledged:   incident.IsAcknowledged(),
+		AcknowledgedBy: incident.AcknowledgedBy,
+	}
+	if !incident.IsOpen() {
+		view.ResolvedAt = incident.ResolvedAt.In(loc).Format(time.DateTime)
+		interval.End = incident.ResolvedAt
+	}
+	if incident.IsAcknowledged() {
+		view.AcknowledgedAt = incident.AcknowledgedAt.In(loc).Format(time.DateTime)
+	}
+	view.Duration = interval.DurationHuman()
+	return view
+}
+
+// Parse incident id from the request path, see storage.ErrIncidentNotFound for missing incidents
+func incidentIdFromPath(r *http.Request) (int, error) {
+	value := r.PathValue("incident_id")
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid incident id %q", value)
+	}
+	return id, nil
+}
+
+// List incidents, newest first. Optionally filtered by `service`.
+func handleIncidents(db storage.Storage, config *conf.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		query := r.URL.Query()
+		serviceId := query.Get("service")
+		page := 1
+		if value := query.Get("page"); value != "" {
+			var err error
+			page, err = strconv.Atoi(value)
+			if err != nil || page < 1 {
+				http.Error(w, fmt.Sprintf("Invalid page %q", value), http.StatusBadRequest)
+				return
+			}
+		}
+
+		incidentQuery := storage.IncidentQuery{ServiceId: serviceId}
+		total, err := db.CountIncidents(incidentQuery)
+		if err != nil {
+			logger.Errorw("Failed to count incidents", zap.Error(err))
+			http.Error(w, "Failed to load incidents", http.StatusInternalServerError)
+			return
+		}
+		incidentQuery.Limit = INCIDENTS_PAGE_SIZE
+		incidentQuery.Offset = (page - 1) * INCIDENTS_PAGE_SIZE
+		incidents, err := db.ListIncidents(incidentQuery)
+		if err != nil {
+			logger.Errorw("Failed to load incidents", zap.Error(err))
+			http.Error(w, "Failed to load incidents", http.StatusInternalServerError)
+			return
+		}
+		now := time.Now()
+		views := make([]IncidentView, 0, len(incidents))
+		for _, incident := range incidents {
+			views = append(views, toIncidentView(incident, now, config.Timezone.Location))
+		}
+		pages := max(1, int(math.Ceil(float64(total)/INCIDENTS_PAGE_SIZE)))
+
+		pageUrl := func(p int) string {
+			values := url.Values{"page": {strconv.Itoa(p)}}
+			if serviceId != "" {
+				values.Set("service", serviceId)
+			}
+			return "/incidents?" + values.Encode()
+		}
+		prevUrl, nextUrl := "", ""
+		if page > 1 {
+			prevUrl = pageUrl(page - 1)
+		}
+		if page < pages {
+			nextUrl = pageUrl(page + 1)
+		}
+
+		data := pageData(w, r, "incidents")
+		data["ServiceId"] = serviceId
+		data["Incidents"] = views
+		data["Total"] = total
+		data["Page"] = page
+		data["Pages"] = pages
+		data["PrevUrl"] = prevUrl
+		data["NextUrl"] = nextUrl
+		err = INCIDENTS_TEMPLATE.Execute(w, data)
+		if err != nil {
+			logger.Errorw("Failed to render", zap.Error(err))
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		}
+	}
+}
+
+// Show incident with its notes
+func handleIncidentDetail(db storage.Storage, config *conf.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		id, err := incidentIdFromPath(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		incident, err := db.GetIncident(id)
+		if errors.Is(err, storage.ErrIncidentNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			logger.Errorw("Failed to load incident", "incident", id, zap.Error(err))
+			http.Error(w, "Failed to load incident", http.StatusInternalServerError)
+			return
+		}
+		notes, err := db.ListIncidentNotes(id)
+		if err != nil {
+			logger.Errorw("Failed to load incident notes", "incident", id, zap.Error(err))
+			http.Error(w, "Failed to load incident", http.StatusInternalServerError)
+			return
+		}
+		loc := config.Timezone.Location
+		noteViews := make([]IncidentNoteView, 0, len(notes))
+		for _, note := range notes {
+			noteViews = append(noteViews, IncidentNoteView{
+				Author:    note.Author,
+				Text:      note.Text,
+				CreatedAt: note.CreatedAt.In(loc).Format(time.DateTime),
+			})
+		}
+
+		data := pageData(w, r, "incidents")
+		data["Incident"] = toIncidentView(incident, time.Now(), loc)
+		data["Notes"] = noteViews
+		err = INCIDENT_TEMPLATE.Execute(w, data)
+		if err != nil {
+			logger.Errorw("Failed to render", zap.Error(err))
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		}
+	}
+}
+
+// Acknowledge incident, which stops repeated failure notifications until the service recovers
+func handleIncidentAcknowledge(db storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		id, err := incidentIdFromPath(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = db.AcknowledgeIncident(id, RequestActor(r), time.Now())
+		if errors.Is(err, storage.ErrIncidentNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if errors.Is(err, storage.ErrAuthorRequired) {
+			http.Error(w, "Login required", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			logger.Errorw("Failed to acknowledge incident", "incident", id, zap.Error(err))
+			http.Error(w, "Failed to acknowledge incident", http.StatusInternalServerError)
+			return
+		}
+		logger.Infow("Incident acknowledged", "incident", id, "user", CurrentUser(r))
+		http.Redirect(w, r, fmt.Sprintf("/incidents/%d", id), http.StatusSeeOther)
+	}
+}
+
+func handleIncidentNote(db storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		id, err := incidentIdFromPath(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		text := strings.TrimSpace(r.PostFormValue("text"))
+		if text == "" {
+			http.Error(w, "Note text is required", http.StatusBadRequest)
+			return
+		}
+		_, err = db.AddIncidentNote(id, RequestActor(r), text, time.Now())
+		if errors.Is(err, storage.ErrIncidentNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if errors.Is(err, storage.ErrAuthorRequired) {
+			http.Error(w, "Login required", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			logger.Errorw("Failed to add incident note", "incident", id, zap.Error(err))
+			http.Error(w, "Failed to add note", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/incidents/%d", id), http.StatusSeeOther)
+	}
+}
+
+type ApiIncidentNote struct {
+	Id        int    `json:"id"`
+	Author    string `json:"author"`
+	Text      string `json:"text"`
+	CreatedAt string `json:"created_at"`
+}
+
+type ApiIncident struct {
+	Id        int    `json:"id"`
+	ServiceId string `json:"service_id"`
+	OpenedAt  string `json:"opened_at"`
+	// empty while the incident is open
+	ResolvedAt string `json:"resolved_at,omitempty"`
+	// until now for open incidents
+	DurationSeconds int64  `json:"duration_seconds"`
+	AcknowledgedAt  string `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy  string `json:"acknowledged_by,omitempty"`
+	// only included for a single incident
+	Notes []ApiIncidentNote `json:"notes,omitempty"`
+}
+
+type ApiIncidentsPage struct {
+	Items  []ApiIncident `json:"items"`
+	Total  int           `json:"total"`
+	Limit  int           `json:"limit"`
+	Offset int           `json:"offset"`
+}
+
+// Request body for adding incident note
+type ApiIncidentNoteInput struct {
+	Text string `json:"text"`
+}
+
+func toApiIncident(incident *storage.Incident, now time.Time) ApiIncident {
+	apiIncident := ApiIncident{
+		Id:              incident.Id,
+		ServiceId:       incident.ServiceId,
+		OpenedAt:        incident.OpenedAt.UTC().Format(storage.TIME_FORMAT),
+		DurationSeconds: int64(incident.Duration(now).Seconds()),
+		AcknowledgedBy:  incident.AcknowledgedBy,
+	}
+	if !incident.IsOpen() {
+		apiIncident.ResolvedAt = incident.ResolvedAt.UTC().Format(storage.TIME_FORMAT)
+	}
+	if incident.IsAcknowledged() {
+		apiIncident.AcknowledgedAt = incident.AcknowledgedAt.UTC().Format(storage.TIME_FORMAT)
+	}
+	return apiIncident
+}
+
+func toApiIncidentNote(note *storage.IncidentNote) ApiIncidentNote {
+	return ApiIncidentNote{
+		Id:        note.Id,
+		Author:    note.Author,
+		Text:      note.Text,
+		CreatedAt: note.CreatedAt.UTC().Format(storage.TIME_FORMAT),
+	}
+}
+
+// Load incident from the request path, or write error response and return nil
+func apiIncidentFromPath(w http.ResponseWriter, r *http.Request, db storage.Storage) *storage.Incident {
+	logger := logging.Get()
+	id, err := incidentIdFromPath(r)
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, err.Error())
+		return nil
+	}
+	incident, err := db.GetIncident(id)
+	if errors.Is(err, storage.ErrIncidentNotFound) {
+		writeApiError(w, http.StatusNotFound, fmt.Sprintf("Incident %d not found", id))
+		return nil
+	}
+	if err != nil {
+		logger.Errorw("Failed to load incident", "incident", id, zap.Error(err))
+		writeApiError(w, http.StatusInternalServerError, "Failed to load incident")
+		return nil
+	}
+	return incident
+}
+
+// List incidents, newest first. Supports `service`, `open`, `limit` and `offset`.
+func handleApiIncidents(db storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		query := storage.IncidentQuery{ServiceId: r.URL.Query().Get("service")}
+		if value := r.URL.Query().Get("open"); value != "" {
+			query.Open, err = strconv.ParseBool(value)
+			if err != nil {
+				writeApiError(w, http.StatusBadRequest, fmt.Sprintf("invalid open, expected true or false, got %q", value))
+				return
+			}
+		}
+		total, err := db.CountIncidents(query)
+		if err != nil {
+			logger.Errorw("Failed to count incidents", zap.Error(err))
+			writeApiError(w, http.StatusInternalServerError, "Failed to load incidents")
+			return
+		}
+		query.Limit = limit
+		query.Offset = offset
+		incidents, err := db.ListIncidents(query)
+		if err != nil {
+			logger.Errorw("Failed to load incidents", zap.Error(err))
+			writeApiError(w, http.StatusInternalServerError, "Failed to load incidents")
+			return
+		}
+		now := time.Now()
+		page := ApiIncidentsPage{
+			Items:  make([]ApiIncident, 0, len(incidents)),
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		}
+		for _, incident := range incidents {
+			page.Items = append(page.Items, toApiIncident(incident, now))
+		}
+		writeJSON(w, http.StatusOK, page)
+	}
+}
+
+// Single incident, including notes
+func handleApiIncident(db storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		incident := apiIncidentFromPath(w, r, db)
+		if incident == nil {
+			return
+		}
+		notes, err := db.ListIncidentNotes(incident.Id)
+		if err != nil {
+			logger.Errorw("Failed to load incident notes", "incident", incident.Id, zap.Error(err))
+			writeApiError(w, http.StatusInternalServerError, "Failed to load incident")
+			return
+		}
+		apiIncident := toApiIncident(incident, time.Now())
+		apiIncident.Notes = make([]ApiIncidentNote, 0, len(notes))
+		for _, note := range notes {
+			apiIncident.Notes = append(apiIncident.Notes, toApiIncidentNote(note))
+		}
+		writeJSON(w, http.StatusOK, apiIncident)
+	}
+}
+
+func handleApiIncidentAcknowledge(db storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		incident := apiIncidentFromPath(w, r, db)
+		if incident == nil {
+			return
+		}
+		now := time.Now()
+		err := db.AcknowledgeIncident(incident.Id, RequestActor(r), now)
+		if errors.Is(err, storage.ErrAuthorRequired) {
+			writeApiError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if err == nil {
+			incident, err = db.GetIncident(incident.Id)
+		}
+		if err != nil {
+			logger.Errorw("Failed to acknowledge incident", "incident", incident.Id, zap.Error(err))
+			writeApiError(w, http.StatusInternalServerError, "Failed to acknowledge incident")
+			return
+		}
+		logger.Infow("Incident acknowledged", "incident", incident.Id, "by", incident.AcknowledgedBy)
+		writeJSON(w, http.StatusOK, toApiIncident(incident, now))
+	}
+}
+
+// Add note from JSON body
+func handleApiIncidentNote(db storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		incident := apiIncidentFromPath(w, r, db)
+		if incident == nil {
+			return
+		}
+		input := ApiIncidentNoteInput{}
+		if !readJSON(w, r, &input) {
+			return
+		}
+		text := strings.TrimSpace(input.Text)
+		if text == "" {
+			writeApiError(w, http.StatusBadRequest, "Note text is required")
+			return
+		}
+		note, err := db.AddIncidentNote(incident.Id, RequestActor(r), text, time.Now())
+		if errors.Is(err, storage.ErrAuthorRequired) {
+			writeApiError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if err != nil {
+			logger.Errorw("Failed to add incident note", "incident", incident.Id, zap.Error(err))
+			writeApiError(w, http.StatusInternalServerError, "Failed to add note")
+			return
+		}
+		writeJSON(w, http.StatusCreated, toApiIncidentNote(note))
+	}
+}
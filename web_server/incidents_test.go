@@ -0,0 +1,165 @@
+package web_server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/storage"
+)
+
+func TestApiIncidents(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	// changes require auth even when reading does not
+	config.RequireApiAuth = false
+	config.RequireGuiLogin = false
+	mux := http.NewServeMux()
+	RegisterApiHandlers(db, mux, config, NewSessionStore())
+	readToken, _, err := db.CreateApiToken("reader", []string{storage.SCOPE_READ_ALL}, time.Time{})
+	require.NoError(t, err)
+	adminToken, _, err := db.CreateApiToken("oncall", []string{storage.SCOPE_ADMIN}, time.Time{})
+	require.NoError(t, err)
+
+	now := time.Now()
+	resolved, err := db.OpenIncident("beacon-github", now.Add(-3*time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, db.ResolveIncident(resolved.Id, now.Add(-2*time.Hour)))
+	open, err := db.OpenIncident("beacon-periodic-checker", now.Add(-time.Hour))
+	require.NoError(t, err)
+
+	var page ApiIncidentsPage
+	code := apiRequest(t, mux, http.MethodGet, "/api/v1/incidents", "", readToken, &page)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, 2, page.Total)
+	require.Len(t, page.Items, 2)
+	assert.Equal(t, open.Id, page.Items[0].Id)
+	assert.Empty(t, page.Items[0].ResolvedAt)
+	assert.Equal(t, int64(3600), page.Items[1].DurationSeconds)
+	assert.NotEmpty(t, page.Items[1].ResolvedAt)
+
+	code = apiRequest(t, mux, http.MethodGet, "/api/v1/incidents?open=true", "", readToken, &page)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "beacon-periodic-checker", page.Items[0].ServiceId)
+	code = apiRequest(t, mux, http.MethodGet, "/api/v1/incidents?service=beacon-github", "", readToken, &page)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, resolved.Id, page.Items[0].Id)
+	var apiErr ApiError
+	code = apiRequest(t, mux, http.MethodGet, "/api/v1/incidents?open=maybe", "", readToken, &apiErr)
+	require.Equal(t, http.StatusBadRequest, code)
+
+	incidentUrl := fmt.Sprintf("/api/v1/incidents/%d", open.Id)
+	code = apiRequest(t, mux, http.MethodPost, incidentUrl+"/acknowledge", "", "", &apiErr)
+	require.Equal(t, http.StatusUnauthorized, code, "auth always required")
+	code = apiRequest(t, mux, http.MethodPost, incidentUrl+"/notes", `{"text": "anonymous"}`, "", &apiErr)
+	require.Equal(t, http.StatusUnauthorized, code, "auth always required")
+	code = apiRequest(t, mux, http.MethodPost, incidentUrl+"/acknowledge", "", readToken, &apiErr)
+	require.Equal(t, http.StatusForbidden, code, "admin scope required")
+	var incident ApiIncident
+	code = apiRequest(t, mux, http.MethodPost, incidentUrl+"/acknowledge", "", adminToken, &incident)
+	require.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, incident.AcknowledgedAt)
+	assert.Equal(t, "token oncall", incident.AcknowledgedBy)
+
+	var note ApiIncidentNote
+	code = apiRequest(t, mux, http.MethodPost, incidentUrl+"/notes", `{"text": "Disk full, cleaning up"}`, adminToken, &note)
+	require.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "token oncall", note.Author)
+	code = apiRequest(t, mux, http.MethodPost, incidentUrl+"/notes", `{"text": " "}`, adminToken, &apiErr)
+	require.Equal(t, http.StatusBadRequest, code)
+	code = apiRequest(t, mux, http.MethodPost, incidentUrl+"/notes", `{"note": "wrong field"}`, adminToken, &apiErr)
+	require.Equal(t, http.StatusBadRequest, code)
+
+	code = apiRequest(t, mux, http.MethodGet, incidentUrl, "", readToken, &incident)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, incident.Notes, 1)
+	assert.Equal(t, "Disk full, cleaning up", incident.Notes[0].Text)
+
+	code = apiRequest(t, mux, http.MethodGet, "/api/v1/incidents/100", "", readToken, &apiErr)
+	require.Equal(t, http.StatusNotFound, code)
+	code = apiRequest(t, mux, http.MethodPost, "/api/v1/incidents/100/acknowledge", "", adminToken, &apiErr)
+	require.Equal(t, http.StatusNotFound, code)
+	code = apiRequest(t, mux, http.MethodGet, "/api/v1/incidents/abc", "", readToken, &apiErr)
+	require.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestIncidentPages(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	err = db.CreateUser("cj@example.com", "h4xor")
+	require.NoError(t, err)
+	sessions := NewSessionStore()
+	mux := http.NewServeMux()
+	RegisterGuiHandlers(db, mux, config, sessions)
+	sessionId, err := sessions.Create("cj@example.com", time.Now())
+	require.NoError(t, err)
+	session := &http.Cookie{Name: SESSION_COOKIE, Value: sessionId}
+
+	now := time.Now()
+	incident, err := db.OpenIncident("beacon-github", now.Add(-2*time.Hour))
+	require.NoError(t, err)
+	incidentPath := fmt.Sprintf("/incidents/%d", incident.Id)
+
+	get := func(path string) string {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, rr.Code, path)
+		return rr.Body.String()
+	}
+	csrf := &http.Cookie{Name: CSRF_COOKIE, Value: "csrf-value"}
+	post := func(path string, form url.Values) *httptest.ResponseRecorder {
+		form.Set(CSRF_FORM_FIELD, csrf.Value)
+		return postForm(mux, path, form, []*http.Cookie{session, csrf})
+	}
+
+	body := get("/incidents")
+	require.Contains(t, body, `href="`+incidentPath+`"`)
+	require.Contains(t, body, "ongoing")
+	require.Contains(t, body, "2 hours")
+	body = get("/incidents?service=other")
+	require.Contains(t, body, "No incidents.")
+	body = get("/services/beacon-github")
+	require.Contains(t, body, `href="`+incidentPath+`"`)
+
+	body = get(incidentPath)
+	require.Contains(t, body, `action="`+incidentPath+`/acknowledge"`)
+	require.Contains(t, body, "No notes yet.")
+
+	form := url.Values{CSRF_FORM_FIELD: {csrf.Value}}
+	rr := postForm(mux, incidentPath+"/acknowledge", form, []*http.Cookie{csrf})
+	require.Equal(t, http.StatusSeeOther, rr.Code, "login always required")
+	require.Contains(t, rr.Header().Get("Location"), "/login")
+	rr = postForm(mux, incidentPath+"/acknowledge", url.Values{}, []*http.Cookie{session})
+	require.Equal(t, http.StatusForbidden, rr.Code, "CSRF token required")
+	rr = post(incidentPath+"/acknowledge", url.Values{})
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+	rr = post(incidentPath+"/notes", url.Values{"text": {""}})
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = post(incidentPath+"/notes", url.Values{"text": {"Restarted <the> server"}})
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+	rr = post("/incidents/100/notes", url.Values{"text": {"note"}})
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	body = get(incidentPath)
+	require.NotContains(t, body, `action="`+incidentPath+`/acknowledge"`)
+	require.Contains(t, body, "acknowledged")
+	require.Contains(t, body, "cj@example.com")
+	require.Contains(t, body, "Restarted &lt;the&gt; server")
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/incidents/100", nil))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,175 @@
+package web_server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/logging"
+	"github.com/davidmasek/beacon/monitor"
+	"github.com/davidmasek/beacon/storage"
+	"go.uber.org/zap"
+)
+
+// Maintenance window, see conf.MaintenanceWindow.
+// Used both for listing and creating windows.
+type ApiMaintenanceWindow struct {
+	// only windows created using CLI or API have id, windows from config cannot be deleted
+	Id       int      `json:"id,omitempty"`
+	Reason   string   `json:"reason"`
+	Services []string `json:"services"`
+	Tags     []string `json:"tags"`
+	// one-off window, RFC3339 timestamps
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+	// recurring window
+	Days     string `json:"days,omitempty"`
+	At       string `json:"at,omitempty"`
+	Duration string `json:"duration,omitempty"`
+	// in progress now, ignored when creating
+	Active bool `json:"active"`
+}
+
+func toApiMaintenanceWindow(window *conf.MaintenanceWindow, now time.Time, loc *time.Location) ApiMaintenanceWindow {
+	// empty list instead of null in JSON
+	services, tags := window.Services, window.Tags
+	if services == nil {
+		services = []string{}
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+	apiWindow := ApiMaintenanceWindow{
+		Id:       window.Id,
+		Reason:   window.Reason,
+		Services: services,
+		Tags:     tags,
+		At:       window.At,
+		Active:   window.IsActive(now, loc),
+	}
+	if !window.IsRecurring() {
+		apiWindow.Start = window.Start.UTC().Format(storage.TIME_FORMAT)
+		apiWindow.End = window.End.UTC().Format(storage.TIME_FORMAT)
+	}
+	if !window.Days.IsEmpty() {
+		apiWindow.Days = window.Days.String()
+	}
+	if window.Duration != 0 {
+		apiWindow.Duration = conf.FormatDuration(window.Duration)
+	}
+	return apiWindow
+}
+
+// Parse window from API request. The result should be validated, see conf.MaintenanceWindow.Validate.
+func (apiWindow *ApiMaintenanceWindow) toConfig() (conf.MaintenanceWindow, error) {
+	window := conf.MaintenanceWindow{
+		Reason:   apiWindow.Reason,
+		Services: apiWindow.Services,
+		Tags:     apiWindow.Tags,
+		At:       apiWindow.At,
+	}
+	var err error
+	for _, field := range []struct {
+		name   string
+		value  string
+		target *time.Time
+	}{
+		{"start", apiWindow.Start, &window.Start},
+		{"end", apiWindow.End, &window.End},
+	} {
+		if field.value == "" {
+			continue
+		}
+		*field.target, err = time.Parse(time.RFC3339, field.value)
+		if err != nil {
+			return window, fmt.Errorf("invalid %s, expected RFC3339 timestamp, got %q", field.name, field.value)
+		}
+	}
+	if apiWindow.Days != "" {
+		err = window.Days.ParseString(apiWindow.Days)
+		if err != nil {
+			return window, fmt.Errorf("invalid days: %w", err)
+		}
+	}
+	if apiWindow.Duration != "" {
+		window.Duration, err = conf.ParseDuration(apiWindow.Duration)
+		if err != nil {
+			return window, fmt.Errorf("invalid duration %q", apiWindow.Duration)
+		}
+	}
+	return window, nil
+}
+
+func handleApiMaintenance(db storage.Storage, config *conf.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		windows, err := monitor.MaintenanceWindows(db, config)
+		if err != nil {
+			logger.Errorw("Failed to load maintenance windows", zap.Error(err))
+			writeApiError(w, http.StatusInternalServerError, "Failed to load maintenance windows")
+			return
+		}
+		now := time.Now()
+		items := make([]ApiMaintenanceWindow, 0, len(windows))
+		for _, window := range windows {
+			items = append(items, toApiMaintenanceWindow(&window, now, config.Timezone.Location))
+		}
+		writeJSON(w, http.StatusOK, items)
+	}
+}
+
+// Create maintenance window from JSON body
+func handleApiMaintenanceCreate(db storage.Storage, config *conf.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		input := ApiMaintenanceWindow{}
+		if !readJSON(w, r, &input) {
+			return
+		}
+		window, err := input.toConfig()
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err = window.Validate(); err != nil {
+			writeApiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		now := time.Now()
+		created, err := monitor.CreateMaintenanceWindow(db, window, now)
+		if err != nil {
+			logger.Errorw("Failed to create maintenance window", zap.Error(err))
+			writeApiError(w, http.StatusInternalServerError, "Failed to create maintenance window")
+			return
+		}
+		logger.Infow("Maintenance window created", "id", created.Id, "services", created.Services, "tags", created.Tags)
+		writeJSON(w, http.StatusCreated, toApiMaintenanceWindow(created, now, config.Timezone.Location))
+	}
+}
+
+func handleApiMaintenanceDelete(db storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		value := r.PathValue("window_id")
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, fmt.Sprintf("Invalid maintenance window id %q", value))
+			return
+		}
+		err = db.DeleteMaintenanceWindow(id)
+		if errors.Is(err, storage.ErrMaintenanceWindowNotFound) {
+			writeApiError(w, http.StatusNotFound, fmt.Sprintf("Maintenance window %d not found", id))
+			return
+		}
+		if err != nil {
+			logger.Errorw("Failed to delete maintenance window", "id", id, zap.Error(err))
+			writeApiError(w, http.StatusInternalServerError, "Failed to delete maintenance window")
+			return
+		}
+		logger.Infow("Maintenance window deleted", "id", id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,147 @@
+package web_server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/monitor"
+	"github.com/davidmasek/beacon/storage"
+)
+
+func apiRequest(t *testing.T, mux *http.ServeMux, method string, url string, body string, token string, out any) int {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if out != nil {
+		err := json.Unmarshal(rr.Body.Bytes(), out)
+		require.NoError(t, err, rr.Body.String())
+	}
+	return rr.Code
+}
+
+func TestApiMaintenance(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes(append(TEST_CFG, []byte(`
+maintenance:
+  - services: [beacon-github]
+    days: Sun
+    at: "02:00"
+    duration: 2h
+`)...))
+	require.NoError(t, err)
+	// changes require auth even when reading does not
+	config.RequireApiAuth = false
+	config.RequireGuiLogin = false
+	mux := http.NewServeMux()
+	RegisterApiHandlers(db, mux, config, NewSessionStore())
+	readToken, _, err := db.CreateApiToken("reader", []string{storage.SCOPE_READ_ALL}, time.Time{})
+	require.NoError(t, err)
+	adminToken, _, err := db.CreateApiToken("admin", []string{storage.SCOPE_ADMIN}, time.Time{})
+	require.NoError(t, err)
+
+	body := `{"services": ["beacon-periodic-checker"], "reason": "Deploy", "start": "2025-03-01T02:00:00Z", "end": "2025-03-01T03:00:00Z"}`
+	var apiErr ApiError
+	code := apiRequest(t, mux, http.MethodPost, "/api/v1/maintenance", body, "", &apiErr)
+	require.Equal(t, http.StatusUnauthorized, code, "auth always required")
+	code = apiRequest(t, mux, http.MethodPost, "/api/v1/maintenance", body, readToken, &apiErr)
+	require.Equal(t, http.StatusForbidden, code, "admin scope required")
+
+	var created ApiMaintenanceWindow
+	code = apiRequest(t, mux, http.MethodPost, "/api/v1/maintenance", body, adminToken, &created)
+	require.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, 1, created.Id)
+	assert.Equal(t, "Deploy", created.Reason)
+	assert.Equal(t, "2025-03-01T03:00:00Z", created.End)
+	assert.False(t, created.Active)
+
+	code = apiRequest(t, mux, http.MethodPost, "/api/v1/maintenance", `{"tags": ["db"], "at": "02:00"}`, adminToken, &apiErr)
+	require.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, apiErr.Error, "duration must be positive")
+	code = apiRequest(t, mux, http.MethodPost, "/api/v1/maintenance", `{"tags": ["db"], "start": "tomorrow"}`, adminToken, &apiErr)
+	require.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, apiErr.Error, "invalid start")
+	code = apiRequest(t, mux, http.MethodPost, "/api/v1/maintenance", `{"service": "db"}`, adminToken, &apiErr)
+	require.Equal(t, http.StatusBadRequest, code, "unknown field")
+
+	var windows []ApiMaintenanceWindow
+	code = apiRequest(t, mux, http.MethodGet, "/api/v1/maintenance", "", "", &windows)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, windows, 2)
+	assert.Equal(t, 0, windows[0].Id, "window from config")
+	assert.Equal(t, "Sunday", windows[0].Days)
+	assert.Equal(t, "2h", windows[0].Duration)
+	assert.Equal(t, []string{}, windows[0].Tags)
+	assert.Equal(t, 1, windows[1].Id)
+
+	code = apiRequest(t, mux, http.MethodDelete, "/api/v1/maintenance/1", "", "", &apiErr)
+	require.Equal(t, http.StatusUnauthorized, code, "auth always required")
+	code = apiRequest(t, mux, http.MethodDelete, "/api/v1/maintenance/1", "", adminToken, nil)
+	require.Equal(t, http.StatusNoContent, code)
+	code = apiRequest(t, mux, http.MethodDelete, "/api/v1/maintenance/1", "", adminToken, &apiErr)
+	require.Equal(t, http.StatusNotFound, code)
+}
+
+func TestMaintenanceExcludedFromUptime(t *testing.T) {
+	db, mux := setupApi(t)
+	defer db.Close()
+
+	base := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	// OK for the first half of the day, one check per interval
+	for i := range 12 {
+		err := db.AddHealthCheck(&storage.HealthCheckInput{
+			ServiceId: "beacon-periodic-checker",
+			Timestamp: base.Add(time.Duration(i)*time.Hour + 30*time.Minute),
+		})
+		require.NoError(t, err)
+	}
+	// down for 12 hours, 6 of them in maintenance
+	_, err := monitor.CreateMaintenanceWindow(db, conf.MaintenanceWindow{
+		Services: []string{"beacon-periodic-checker"},
+		Start:    base.Add(12 * time.Hour),
+		End:      base.Add(18 * time.Hour),
+	}, time.Now())
+	require.NoError(t, err)
+
+	var uptime ApiUptime
+	code := apiGet(t, mux, "/api/v1/services/beacon-periodic-checker/uptime?window=1d&interval=1h&to=2025-03-02T00:00:00Z&details=true", &uptime)
+	require.Equal(t, http.StatusOK, code)
+	require.InDelta(t, 66.67, uptime.UpPercent, 0.01)
+	require.InDelta(t, 33.33, uptime.DownPercent, 0.01)
+	require.Equal(t, monitor.STATUS_MAINTENANCE, uptime.Intervals[12].Status)
+	require.Equal(t, monitor.STATUS_FAIL, uptime.Intervals[18].Status)
+}
+
+func TestHandleIndexMaintenanceBadge(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	now := time.Now()
+	_, err = monitor.CreateMaintenanceWindow(db, conf.MaintenanceWindow{
+		Reason:   "Server migration",
+		Services: []string{"beacon-github"},
+		Start:    now.Add(-time.Hour),
+		End:      now.Add(time.Hour),
+	}, now)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handleIndex(db, config).ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	body := rr.Body.String()
+	require.Equal(t, 1, strings.Count(body, `class="tag tag-maintenance"`))
+	require.Contains(t, body, `title="Server migration (`)
+}
@@ -0,0 +1,66 @@
+package web_server
+
+import (
+	"net/http"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/monitor"
+	"github.com/davidmasek/beacon/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// HTTP server metrics. Labeled only by method and code to keep cardinality low.
+var (
+	httpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: monitor.METRICS_NAMESPACE,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests handled.",
+	}, []string{"method", "code"})
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: monitor.METRICS_NAMESPACE,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+	httpRequestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: monitor.METRICS_NAMESPACE,
+		Name:      "http_requests_in_flight",
+		Help:      "Number of HTTP requests being handled.",
+	})
+)
+
+// Registry with all Beacon metrics
+func newMetricsRegistry(db storage.Storage, config *conf.Config) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewGoCollector(),
+		monitor.NewServiceCollector(db, config),
+		monitor.HeartbeatsTotal,
+		monitor.HeartbeatsRejectedTotal,
+		monitor.WebChecksTotal,
+		monitor.WebCheckDuration,
+		httpRequestsTotal,
+		httpRequestDuration,
+		httpRequestsInFlight,
+	)
+	return registry
+}
+
+// Serve metrics in Prometheus text format. Protected the same way as the JSON API.
+func RegisterMetricsHandlers(db storage.Storage, mux *http.ServeMux, config *conf.Config, sessions *SessionStore) {
+	registry := newMetricsRegistry(db, config)
+	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+	mux.HandleFunc("GET /metrics", apiAuth(db, config, sessions, handler.ServeHTTP, storage.SCOPE_READ_ALL))
+}
+
+// Record HTTP server metrics for all requests
+func instrumentHandler(next http.Handler) http.Handler {
+	return promhttp.InstrumentHandlerInFlight(httpRequestsInFlight,
+		promhttp.InstrumentHandlerDuration(httpRequestDuration,
+			promhttp.InstrumentHandlerCounter(httpRequestsTotal, next),
+		),
+	)
+}
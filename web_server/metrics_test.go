@@ -0,0 +1,76 @@
+package web_server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/monitor"
+	"github.com/davidmasek/beacon/storage"
+)
+
+func TestMetrics(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	RegisterMetricsHandlers(db, mux, config, NewSessionStore())
+	monitor.RegisterHeartbeatHandlers(db, mux, config)
+	handler := instrumentHandler(mux)
+
+	req := httptest.NewRequest(http.MethodPost, "/services/beacon-periodic-checker/beat", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	err = db.CreateTaskLog(storage.TaskInput{
+		TaskName: "report", Status: string(storage.TASK_OK), Timestamp: time.Unix(1700000000, 0),
+	})
+	require.NoError(t, err)
+
+	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Header().Get("Content-Type"), "text/plain")
+	body := rr.Body.String()
+
+	assert.Contains(t, body, `beacon_service_up{service="beacon-periodic-checker"} 1`)
+	assert.Contains(t, body, `beacon_service_up{service="beacon-github"} 0`)
+	assert.Contains(t, body, `beacon_service_last_check_timestamp_seconds{service="beacon-periodic-checker"}`)
+	assert.NotContains(t, body, `beacon_service_last_check_timestamp_seconds{service="beacon-github"}`)
+	assert.Contains(t, body, `beacon_heartbeats_total{service="beacon-periodic-checker"}`)
+	assert.Contains(t, body, `beacon_task_runs_total{status="OK",task="report"} 1`)
+	assert.Contains(t, body, `beacon_task_last_run_timestamp_seconds{status="OK",task="report"} 1.7e+09`)
+	assert.Contains(t, body, `beacon_http_requests_total{code="200",method="post"}`)
+	assert.Contains(t, body, "go_goroutines")
+}
+
+func TestMetricsAuth(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	config.RequireApiAuth = true
+	mux := http.NewServeMux()
+	RegisterMetricsHandlers(db, mux, config, NewSessionStore())
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	token, _, err := db.CreateApiToken("prometheus", []string{storage.SCOPE_READ_ALL}, time.Time{})
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+}
@@ -0,0 +1,117 @@
+package web_server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/logging"
+	"github.com/davidmasek/beacon/monitor"
+	"github.com/davidmasek/beacon/storage"
+	"go.uber.org/zap"
+)
+
+// Number of days shown in uptime bars on the public status page
+const PUBLIC_UPTIME_DAYS = 90
+
+type PublicServiceView struct {
+	Name      string
+	Status    monitor.ServiceStatus
+	Note      string
+	UpPercent float64
+	Days      []UptimeSegment
+}
+
+func RegisterPublicHandlers(db storage.Storage, mux *http.ServeMux, config *conf.Config) {
+	mux.HandleFunc("GET /status", handlePublicStatus(db, config))
+}
+
+// Compute uptime for each day (in the given location) from `from` until `now`.
+// Checks must be sorted ascending. Time in maintenance windows is excluded. Returns daily uptime and overall uptime percentage.
+func buildUptimeDays(checks []*storage.HealthCheck, hasOlderChecks bool, windows []conf.MaintenanceWindow, from, now time.Time, loc *time.Location) ([]UptimeSegment, float64) {
+	intervals := intervalsSinceFirstCheck(checks, hasOlderChecks, from, now)
+	monitor.MarkMaintenance(intervals, windows, loc)
+	boundaries := []time.Time{}
+	for day := from; day.Before(now); day = day.AddDate(0, 0, 1) {
+		boundaries = append(boundaries, day)
+	}
+	boundaries = append(boundaries, now)
+	days := summarizeSegments(intervals, boundaries, func(day time.Time) string {
+		return day.In(loc).Format(time.DateOnly)
+	})
+	up, _ := monitor.SummarizeIntervals(intervals)
+	return days, up
+}
+
+// Read-only status page for services marked as public.
+// Does not require login and only shows the public fields of a service.
+func handlePublicStatus(db storage.Storage, config *conf.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		loc := config.Timezone.Location
+		now := time.Now()
+		localNow := now.In(loc)
+		today := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, loc)
+		from := today.AddDate(0, 0, -(PUBLIC_UPTIME_DAYS - 1))
+
+		windows, err := monitor.MaintenanceWindows(db, config)
+		if err != nil {
+			logger.Errorw("Failed to load maintenance windows", zap.Error(err))
+			http.Error(w, "Server error, please try again later", http.StatusInternalServerError)
+			return
+		}
+		services := []PublicServiceView{}
+		allOk := true
+		for _, serviceCfg := range config.AllServices() {
+			if !serviceCfg.Public || !serviceCfg.Enabled {
+				continue
+			}
+			checks, err := db.HealthChecksSince(serviceCfg.Id, from.Add(-SUMMARY_STATS_INTERVAL))
+			if err != nil {
+				logger.Errorw("Failed to load health checks", "service", serviceCfg.Id, zap.Error(err))
+				http.Error(w, "Server error, please try again later", http.StatusInternalServerError)
+				return
+			}
+			hasOlderChecks, err := hasChecksBefore(db, serviceCfg.Id, from)
+			if err != nil {
+				logger.Errorw("Failed to count health checks", "service", serviceCfg.Id, zap.Error(err))
+				http.Error(w, "Server error, please try again later", http.StatusInternalServerError)
+				return
+			}
+			serviceWindows := monitor.ServiceMaintenance(windows, &serviceCfg)
+			status := monitor.GetServiceStatus(serviceCfg, checks)
+			// planned maintenance is not an issue
+			if status != monitor.STATUS_OK && monitor.ActiveMaintenance(serviceWindows, now, loc) != nil {
+				status = monitor.STATUS_MAINTENANCE
+			}
+			if status != monitor.STATUS_OK && status != monitor.STATUS_MAINTENANCE {
+				allOk = false
+			}
+			days, up := buildUptimeDays(checks, hasOlderChecks, serviceWindows, from, now, loc)
+			services = append(services, PublicServiceView{
+				Name:      serviceCfg.Name(),
+				Status:    status,
+				Note:      serviceCfg.Note,
+				UpPercent: up,
+				Days:      days,
+			})
+		}
+		if len(services) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+
+		err = PUBLIC_STATUS_TEMPLATE.Execute(w, map[string]any{
+			"Title":       config.StatusPage.Title,
+			"Notice":      config.StatusPage.Notice,
+			"Services":    services,
+			"AllOk":       allOk,
+			"Days":        PUBLIC_UPTIME_DAYS,
+			"LastUpdated": localNow.Format("2006-01-02 15:04 MST"),
+		})
+		if err != nil {
+			logger.Errorw("Failed to render", zap.Error(err))
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package web_server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/storage"
+)
+
+func TestPublicStatus(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes([]byte(`
+services:
+  public-api:
+    public: true
+    display_name: "Acme API"
+    note: "Investigating elevated error rates"
+  internal-job:
+status_page:
+  title: "Acme Status"
+  notice: "Scheduled maintenance on Saturday"
+`))
+	require.NoError(t, err)
+	config.RequireGuiLogin = true
+	mux := http.NewServeMux()
+	RegisterPublicHandlers(db, mux, config)
+
+	_, err = db.RecordHeartbeat("public-api", time.Now())
+	require.NoError(t, err)
+	_, err = db.RecordHeartbeat("internal-job", time.Now())
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, "public page should not require login")
+
+	body := rr.Body.String()
+	assert.Contains(t, body, "Acme Status")
+	assert.Contains(t, body, "Scheduled maintenance on Saturday")
+	assert.Contains(t, body, "Acme API")
+	assert.Contains(t, body, "Investigating elevated error rates")
+	assert.Contains(t, body, "All systems operational")
+	assert.NotContains(t, body, "public-api", "only display name should be shown")
+	assert.NotContains(t, body, "internal-job", "private services should not be shown")
+	assert.Equal(t, PUBLIC_UPTIME_DAYS, strings.Count(body, `class="bar bar-`))
+}
+
+func TestPublicStatusNoPublicServices(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	RegisterPublicHandlers(db, mux, config)
+
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestBuildUptimeDays(t *testing.T) {
+	loc := time.UTC
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, loc)
+	now := from.AddDate(0, 0, 3)
+	checks := []*storage.HealthCheck{}
+	// no data for the first day, fully up on the second day, half day up on the third day
+	for ts := from.AddDate(0, 0, 1); ts.Before(from.AddDate(0, 0, 2).Add(12 * time.Hour)); ts = ts.Add(10 * time.Minute) {
+		checks = append(checks, &storage.HealthCheck{Timestamp: ts, Metadata: map[string]string{}})
+	}
+
+	days, up := buildUptimeDays(checks, false, nil, from, now, loc)
+	require.Len(t, days, 3)
+	assert.Equal(t, "2025-01-01", days[0].Label)
+	assert.Equal(t, "none", days[0].Level())
+	assert.Equal(t, "up", days[1].Level())
+	assert.Equal(t, 100.0, days[1].UpPercent)
+	assert.Equal(t, "down", days[2].Level())
+	assert.InDelta(t, 50.0, days[2].UpPercent, 2.5)
+	// first day does not count as downtime
+	assert.InDelta(t, 75.0, up, 2.5)
+
+	// unless the service has older checks
+	days, up = buildUptimeDays(checks, true, nil, from, now, loc)
+	assert.Equal(t, "down", days[0].Level())
+	assert.InDelta(t, 50.0, up, 2.5)
+
+	// downtime during maintenance does not count
+	windows := []conf.MaintenanceWindow{{
+		Services: []string{"app"},
+		Start:    from.AddDate(0, 0, 2).Add(12 * time.Hour),
+		End:      now,
+	}}
+	days, up = buildUptimeDays(checks, false, windows, from, now, loc)
+	assert.Equal(t, "up", days[2].Level())
+	assert.Equal(t, 100.0, up)
+}
@@ -1,35 +1,269 @@
 package web_server
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
 
 	"github.com/davidmasek/beacon/conf"
 	"github.com/davidmasek/beacon/logging"
 	"github.com/davidmasek/beacon/monitor"
 	"github.com/davidmasek/beacon/storage"
+	"go.uber.org/zap"
 )
 
-func StartServer(db storage.Storage, config *conf.Config) (*http.Server, error) {
+// Running Beacon HTTP server, see StartServer.
+// Consists of one or more listeners (web GUI, heartbeats, HTTP redirect).
+type Server struct {
+	servers []*http.Server
+	db      storage.Storage
+	// kept across reloads so that users stay logged in
+	sessions          *SessionStore
+	separateHeartbeat bool
+	web               *swappableHandler
+	heartbeat         *swappableHandler
+	// closed when all listeners stop, after sending errors of those that stopped unexpectedly
+	errs chan error
+}
+
+// HTTP server for a single port, before it starts serving
+type serverListener struct {
+	name     string
+	server   *http.Server
+	listener net.Listener
+	useTls   bool
+	// cancelled when the server stops, see newHttpServer
+	cancelBaseCtx context.CancelFunc
+}
+
+// Start listening in the background.
+//
+// Serves HTTPS if TLS is configured and heartbeats on a separate
+// port if HeartbeatPort is configured.
+// Returns error if the server cannot listen on any of the configured ports.
+// Errors that happen later are available from Server.Errors.
+func StartServer(db storage.Storage, config *conf.Config) (*Server, error) {
 	logger := logging.Get()
-	mux := http.NewServeMux()
 
-	mux.HandleFunc("/{$}", handleIndex(db, config))
-	mux.HandleFunc("/about", handleAbout(db, config))
+	if config.RequireGuiLogin {
+		users, err := db.ListUsers()
+		if err != nil {
+			return nil, err
+		}
+		if len(users) == 0 {
+			logger.Warn("Login required for web GUI but no users exist. Create one with `beacon user add <email>`.")
+		}
+	}
+	sessions := NewSessionStore()
+	separateHeartbeat := config.HasSeparateHeartbeatPort()
+	webMux, heartbeatMux := buildHandlers(db, config, sessions, separateHeartbeat)
+	web := newSwappableHandler(webMux)
+	heartbeat := web
+	if separateHeartbeat {
+		heartbeat = newSwappableHandler(heartbeatMux)
+	}
 
-	monitor.RegisterHeartbeatHandlers(db, mux, config)
-	port := config.Port
+	var tlsConfig *tls.Config
+	if config.Tls.IsEnabled() {
+		certs, err := newCertReloader(config.Tls.CertFile, config.Tls.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig = &tls.Config{
+			GetCertificate: certs.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		}
+	} else if config.Tls.CertFile != "" || config.Tls.KeyFile != "" {
+		return nil, fmt.Errorf("TLS requires both cert_file and key_file")
+	}
 
-	server := &http.Server{
-		Addr:    fmt.Sprintf(":%d", port),
-		Handler: mux,
+	listeners := []*serverListener{}
+	listen := func(name string, port int, handler http.Handler, tlsConfig *tls.Config) error {
+		server, cancelBaseCtx := newHttpServer(port, handler, tlsConfig)
+		listener, err := net.Listen("tcp", server.Addr)
+		if err != nil {
+			cancelBaseCtx()
+			return fmt.Errorf("cannot listen on port %d (%s): %w", port, name, err)
+		}
+		listeners = append(listeners, &serverListener{
+			name:          name,
+			server:        server,
+			listener:      listener,
+			useTls:        tlsConfig != nil,
+			cancelBaseCtx: cancelBaseCtx,
+		})
+		return nil
+	}
+	err := listen("web", config.Port, instrumentHandler(web), tlsConfig)
+	if err == nil && separateHeartbeat {
+		err = listen("heartbeat", config.HeartbeatPort, instrumentHandler(heartbeat), tlsConfig)
+	}
+	if err == nil && tlsConfig != nil && config.Tls.RedirectPort != 0 {
+		err = listen("redirect", config.Tls.RedirectPort, redirectToHttps(config.Port), nil)
+	}
+	if err != nil {
+		for _, l := range listeners {
+			l.cancelBaseCtx()
+			l.listener.Close()
+		}
+		return nil, err
 	}
 
+	server := &Server{
+		db:                db,
+		sessions:          sessions,
+		separateHeartbeat: separateHeartbeat,
+		web:               web,
+		heartbeat:         heartbeat,
+		errs:              make(chan error, len(listeners)),
+	}
+	var serving sync.WaitGroup
+	for _, l := range listeners {
+		server.servers = append(server.servers, l.server)
+		serving.Add(1)
+		go func() {
+			defer serving.Done()
+			defer l.cancelBaseCtx()
+			scheme := "http"
+			if l.useTls {
+				scheme = "https"
+			}
+			logger.Infow("Starting server", "listener", l.name, "host", fmt.Sprintf("%s://localhost%s", scheme, l.server.Addr))
+			var err error
+			if l.useTls {
+				// certificates are provided by TLSConfig.GetCertificate
+				err = l.server.ServeTLS(l.listener, "", "")
+			} else {
+				err = l.server.Serve(l.listener)
+			}
+			if !errors.Is(err, http.ErrServerClosed) {
+				logger.Errorw("Server stopped unexpectedly", "listener", l.name, zap.Error(err))
+				server.errs <- fmt.Errorf("%s: %w", l.name, err)
+			}
+		}()
+	}
 	go func() {
-		logger.Infow("Starting server", "host", fmt.Sprint("http://localhost:", port))
-		if err := server.ListenAndServe(); err != http.ErrServerClosed {
-			logger.Panic(err)
-		}
+		serving.Wait()
+		close(server.errs)
 	}()
 	return server, nil
 }
+
+// Create handlers for the web GUI and heartbeats.
+// Both are the same handler unless separateHeartbeat is set.
+func buildHandlers(db storage.Storage, config *conf.Config, sessions *SessionStore, separateHeartbeat bool) (web *http.ServeMux, heartbeat *http.ServeMux) {
+	web = http.NewServeMux()
+	RegisterGuiHandlers(db, web, config, sessions)
+	RegisterApiHandlers(db, web, config, sessions)
+	RegisterPublicHandlers(db, web, config)
+	RegisterBadgeHandlers(db, web, config, sessions)
+	RegisterMetricsHandlers(db, web, config, sessions)
+	RegisterEventHandlers(db, web, config, sessions, monitor.Events)
+
+	heartbeat = web
+	if separateHeartbeat {
+		heartbeat = http.NewServeMux()
+	}
+	monitor.RegisterHeartbeatHandlers(db, heartbeat, config)
+	return web, heartbeat
+}
+
+// Handler that can be replaced while the server is running
+type swappableHandler struct {
+	handler atomic.Pointer[http.Handler]
+}
+
+func newSwappableHandler(handler http.Handler) *swappableHandler {
+	h := &swappableHandler{}
+	h.handler.Store(&handler)
+	return h
+}
+
+func (h *swappableHandler) Store(handler http.Handler) {
+	h.handler.Store(&handler)
+}
+
+func (h *swappableHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*h.handler.Load()).ServeHTTP(w, r)
+}
+
+// Use new config for requests that start after this call.
+//
+// Settings that affect listeners (ports, TLS) are not changed,
+// see conf.RestartRequiredChanges. Rate limit counters start over.
+func (s *Server) Reload(config *conf.Config) {
+	web, heartbeat := buildHandlers(s.db, config, s.sessions, s.separateHeartbeat)
+	s.web.Store(web)
+	if s.separateHeartbeat {
+		s.heartbeat.Store(heartbeat)
+	}
+}
+
+// Create server for the given port.
+//
+// Base context of the server is cancelled when shutdown starts, so that long-lived
+// requests (such as /events streams) end instead of blocking the shutdown.
+func newHttpServer(port int, handler http.Handler, tlsConfig *tls.Config) (*http.Server, context.CancelFunc) {
+	baseCtx, cancelBaseCtx := context.WithCancel(context.Background())
+	server := &http.Server{
+		Addr:      fmt.Sprintf(":%d", port),
+		Handler:   handler,
+		TLSConfig: tlsConfig,
+		BaseContext: func(net.Listener) context.Context {
+			return baseCtx
+		},
+	}
+	server.RegisterOnShutdown(cancelBaseCtx)
+	return server, cancelBaseCtx
+}
+
+// Receives error if any of the listeners stops unexpectedly.
+// Closed when all listeners stop.
+func (s *Server) Errors() <-chan error {
+	return s.errs
+}
+
+// Stop accepting new connections and wait for active requests to finish.
+// If ctx expires first, remaining connections are closed and ctx error returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	var err error
+	for _, server := range s.servers {
+		shutdownErr := server.Shutdown(ctx)
+		if shutdownErr != nil {
+			err = errors.Join(err, shutdownErr, server.Close())
+		}
+	}
+	return err
+}
+
+// Close the server immediately, see Shutdown for graceful alternative
+func (s *Server) Close() error {
+	var err error
+	for _, server := range s.servers {
+		err = errors.Join(err, server.Close())
+	}
+	return err
+}
+
+func RegisterGuiHandlers(db storage.Storage, mux *http.ServeMux, config *conf.Config, sessions *SessionStore) {
+	mux.HandleFunc("/{$}", requireLogin(db, config, sessions, handleIndex(db, config)))
+	mux.HandleFunc("/about", requireLogin(db, config, sessions, handleAbout(db, config)))
+	mux.HandleFunc("GET /services/{service_id}", requireLogin(db, config, sessions, handleServiceDetail(db, config)))
+	mux.HandleFunc("POST /services/{service_id}/adopt", requireUser(db, sessions, csrfProtect(handleServiceAdopt(db, config))))
+	mux.HandleFunc("POST /services/{service_id}/purge", requireUser(db, sessions, csrfProtect(handleServicePurge(db, config))))
+	mux.HandleFunc("GET /incidents", requireLogin(db, config, sessions, handleIncidents(db, config)))
+	mux.HandleFunc("GET /incidents/{incident_id}", requireLogin(db, config, sessions, handleIncidentDetail(db, config)))
+	mux.HandleFunc("POST /incidents/{incident_id}/acknowledge", requireUser(db, sessions, csrfProtect(handleIncidentAcknowledge(db))))
+	mux.HandleFunc("POST /incidents/{incident_id}/notes", requireUser(db, sessions, csrfProtect(handleIncidentNote(db))))
+	mux.HandleFunc("GET /login", handleLogin(db, sessions))
+	mux.HandleFunc("POST /login", csrfProtect(handleLogin(db, sessions)))
+	mux.HandleFunc("POST /logout", csrfProtect(handleLogout(sessions)))
+	mux.HandleFunc("GET /tokens", requireUser(db, sessions, handleTokens(db)))
+	mux.HandleFunc("POST /tokens", requireUser(db, sessions, csrfProtect(handleTokenCreate(db))))
+	mux.HandleFunc("POST /tokens/{token_id}/revoke", requireUser(db, sessions, csrfProtect(handleTokenRevoke(db))))
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"path/filepath"
 	"runtime/debug"
+	"slices"
 	"time"
 
 	"github.com/davidmasek/beacon/conf"
@@ -20,13 +21,22 @@ import (
 const (
 	SUMMARY_STATS_LOOKBACK = -30 * 24 * time.Hour
 	SUMMARY_STATS_INTERVAL = 30 * time.Minute
+	// number of checks in the details panel on the dashboard
+	RECENT_CHECKS_LIMIT = 5
 )
 
 var (
 	//go:embed templates/*
-	TEMPLATES      embed.FS
-	INDEX_TEMPLATE *template.Template
-	ABOUT_TEMPLATE *template.Template
+	TEMPLATES          embed.FS
+	INDEX_TEMPLATE     *template.Template
+	ABOUT_TEMPLATE     *template.Template
+	LOGIN_TEMPLATE     *template.Template
+	TOKENS_TEMPLATE    *template.Template
+	SERVICE_TEMPLATE   *template.Template
+	INCIDENTS_TEMPLATE *template.Template
+	INCIDENT_TEMPLATE  *template.Template
+	// public pages use separate templates, without GUI navigation
+	PUBLIC_STATUS_TEMPLATE *template.Template
 )
 
 func init() {
@@ -52,6 +62,69 @@ func init() {
 	))
 
 	ABOUT_TEMPLATE = tmpl
+
+	tmpl = template.New("login.html").Funcs(funcMap)
+	tmpl = template.Must(tmpl.ParseFS(TEMPLATES,
+		filepath.Join("templates", "login.html"),
+		filepath.Join("templates", "header.html"),
+		filepath.Join("templates", "common.css"),
+	))
+
+	LOGIN_TEMPLATE = tmpl
+
+	tmpl = template.New("tokens.html").Funcs(funcMap)
+	tmpl = template.Must(tmpl.ParseFS(TEMPLATES,
+		filepath.Join("templates", "tokens.html"),
+		filepath.Join("templates", "header.html"),
+		filepath.Join("templates", "common.css"),
+	))
+
+	TOKENS_TEMPLATE = tmpl
+
+	tmpl = template.New("service.html").Funcs(funcMap)
+	tmpl = template.Must(tmpl.ParseFS(TEMPLATES,
+		filepath.Join("templates", "service.html"),
+		filepath.Join("templates", "header.html"),
+		filepath.Join("templates", "common.css"),
+	))
+
+	SERVICE_TEMPLATE = tmpl
+
+	tmpl = template.New("incidents.html").Funcs(funcMap)
+	tmpl = template.Must(tmpl.ParseFS(TEMPLATES,
+		filepath.Join("templates", "incidents.html"),
+		filepath.Join("templates", "header.html"),
+		filepath.Join("templates", "common.css"),
+	))
+
+	INCIDENTS_TEMPLATE = tmpl
+
+	tmpl = template.New("incident.html").Funcs(funcMap)
+	tmpl = template.Must(tmpl.ParseFS(TEMPLATES,
+		filepath.Join("templates", "incident.html"),
+		filepath.Join("templates", "header.html"),
+		filepath.Join("templates", "common.css"),
+	))
+
+	INCIDENT_TEMPLATE = tmpl
+
+	tmpl = template.New("status.html").Funcs(funcMap)
+	tmpl = template.Must(tmpl.ParseFS(TEMPLATES,
+		filepath.Join("templates", "public", "status.html"),
+		filepath.Join("templates", "public", "status.css"),
+	))
+
+	PUBLIC_STATUS_TEMPLATE = tmpl
+}
+
+// Template data shared by all GUI pages.
+// Must be called before writing the response, since it may set a cookie.
+func pageData(w http.ResponseWriter, r *http.Request, currentPage string) map[string]any {
+	return map[string]any{
+		"CurrentPage": currentPage,
+		"User":        CurrentUser(r),
+		"CsrfToken":   csrfToken(w, r),
+	}
 }
 
 // Human-readable time difference (e.g., "5 minutes ago")
@@ -73,24 +146,78 @@ func TimeAgo(t time.Time) string {
 	}
 }
 
-// Show services status
+// Services matching the dashboard filter, see handleIndex.
+// Empty tag or group matches all services.
+func filterServices(services []conf.ServiceConfig, tag string, group string) []conf.ServiceConfig {
+	filtered := []conf.ServiceConfig{}
+	for _, service := range services {
+		if tag != "" && !service.HasTag(tag) {
+			continue
+		}
+		if group != "" && service.Group != group {
+			continue
+		}
+		filtered = append(filtered, service)
+	}
+	return filtered
+}
+
+// All tags of the services, sorted
+func serviceTags(services []conf.ServiceConfig) []string {
+	tags := []string{}
+	for _, service := range services {
+		tags = append(tags, service.Tags...)
+	}
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+// Show services status, grouped by service group.
+// Services can be filtered by `tag` and `group` query parameters.
 func handleIndex(db storage.Storage, config *conf.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		logger := logging.Get()
 
 		type ServiceView struct {
 			ServiceId     string
+			Tags          []string
 			LastChecked   string
 			CurrentStatus monitor.ServiceStatus
 			UptimeSummary string
 			RecentChecks  []*storage.HealthCheck
+			Adopted       bool
+			Unconfigured  bool
+			Timeout       string
+			// description of maintenance in progress, empty if none
+			Maintenance string
 		}
-		var services []ServiceView
+		type GroupView struct {
+			Name     string
+			Services []ServiceView
+		}
+		var groups []GroupView
+		tagFilter := r.URL.Query().Get("tag")
+		groupFilter := r.URL.Query().Get("group")
 
 		now := time.Now().UTC()
 		from := now.Add(SUMMARY_STATS_LOOKBACK)
+		loc := config.Timezone.Location
 
-		for _, serviceCfg := range config.AllServices() {
+		allServices, err := monitor.AllServices(db, config)
+		if err != nil {
+			logger.Errorw("Failed to load services", zap.Error(err))
+			http.Error(w, "Failed to load services", http.StatusInternalServerError)
+			return
+		}
+		windows, err := monitor.MaintenanceWindows(db, config)
+		if err != nil {
+			logger.Errorw("Failed to load maintenance windows", zap.Error(err))
+			http.Error(w, "Failed to load maintenance windows", http.StatusInternalServerError)
+			return
+		}
+		shownServices := filterServices(allServices, tagFilter, groupFilter)
+		services := map[string][]ServiceView{}
+		for _, serviceCfg := range shownServices {
 			logger.Debugw("Querying", "service", serviceCfg.Id)
 			checks, err := db.HealthChecksSince(serviceCfg.Id, from)
 			if err != nil {
@@ -101,14 +228,16 @@ func handleIndex(db storage.Storage, config *conf.Config) http.HandlerFunc {
 			serviceStatus := monitor.GetServiceStatus(serviceCfg, checks)
 
 			// Get the last checks for the details panel
-			recentChecks, err := db.LatestHealthChecks(serviceCfg.Id, 5)
+			recentChecks, err := db.LatestHealthChecks(serviceCfg.Id, RECENT_CHECKS_LIMIT)
 			if err != nil {
 				logger.Errorw("Failed to load recent health checks", "service", serviceCfg.Id, zap.Error(err))
 				http.Error(w, "Failed to load recent health checks", http.StatusInternalServerError)
 				return
 			}
 
+			serviceWindows := monitor.ServiceMaintenance(windows, &serviceCfg)
 			intervals := monitor.BuildStatusIntervals(checks, from, now, SUMMARY_STATS_INTERVAL)
+			monitor.MarkMaintenance(intervals, serviceWindows, loc)
 			up, down := monitor.SummarizeIntervals(intervals)
 			uptimeSummary := fmt.Sprintf("%.2f%% up, %.2f%% down", up, down)
 
@@ -117,22 +246,38 @@ func handleIndex(db storage.Storage, config *conf.Config) http.HandlerFunc {
 				lastChecked = TimeAgo(checks[len(checks)-1].Timestamp)
 			}
 
-			services = append(services, ServiceView{
+			services[serviceCfg.Group] = append(services[serviceCfg.Group], ServiceView{
 				ServiceId:     serviceCfg.Id,
+				Tags:          serviceCfg.Tags,
 				LastChecked:   lastChecked,
 				UptimeSummary: uptimeSummary,
 				CurrentStatus: serviceStatus,
 				RecentChecks:  recentChecks,
+				Adopted:       serviceCfg.Adopted,
+				Unconfigured:  serviceCfg.Unconfigured,
+				Timeout:       conf.FormatDuration(serviceCfg.Timeout),
+				Maintenance:   maintenanceNote(monitor.ActiveMaintenance(serviceWindows, now, loc), loc),
 			})
 		}
 
+		for _, name := range conf.ServiceGroups(shownServices) {
+			groups = append(groups, GroupView{Name: name, Services: services[name]})
+		}
+
 		emailMissingConfig := config.EmailConf.MissingConfigurationFields()
 
-		err := INDEX_TEMPLATE.Execute(w, map[string]any{
-			"services":           services,
-			"CurrentPage":        "home",
-			"EmailMissingConfig": emailMissingConfig,
+		data := pageData(w, r, "home")
+		data["groups"] = groups
+		data["Grouped"] = len(groups) > 1
+		data["AllTags"] = serviceTags(allServices)
+		data["AllGroups"] = slices.DeleteFunc(conf.ServiceGroups(allServices), func(name string) bool {
+			return name == ""
 		})
+		data["TagFilter"] = tagFilter
+		data["GroupFilter"] = groupFilter
+		data["EmailMissingConfig"] = emailMissingConfig
+		data["RecentChecksLimit"] = RECENT_CHECKS_LIMIT
+		err = INDEX_TEMPLATE.Execute(w, data)
 		if err != nil {
 			logger.Errorw("Error rendering template", zap.Error(err))
 			http.Error(w, "Failed to render page", http.StatusInternalServerError)
@@ -191,18 +336,17 @@ func handleAbout(db storage.Storage, config *conf.Config) http.HandlerFunc {
 
 		zone, offset := time.Now().In(config.Timezone.Location).Zone()
 
-		err = ABOUT_TEMPLATE.Execute(w, map[string]any{
-			"lastReportTime":        lastReportTime,
-			"lastReportStatus":      lastReportStatus,
-			"serverTime":            serverTime,
-			"nextReportTime":        nextReportAfter,
-			"ReportAfter":           config.ReportAfter,
-			"CurrentPage":           "about",
-			"Timezone":              config.Timezone.Location.String(),
-			"TimezoneAlt":           zone,
-			"TimezoneOffsetMinutes": offset / 60,
-			"BeaconVersion":         beaconVersion,
-		})
+		data := pageData(w, r, "about")
+		data["lastReportTime"] = lastReportTime
+		data["lastReportStatus"] = lastReportStatus
+		data["serverTime"] = serverTime
+		data["nextReportTime"] = nextReportAfter
+		data["ReportAfter"] = config.ReportAfter
+		data["Timezone"] = config.Timezone.Location.String()
+		data["TimezoneAlt"] = zone
+		data["TimezoneOffsetMinutes"] = offset / 60
+		data["BeaconVersion"] = beaconVersion
+		err = ABOUT_TEMPLATE.Execute(w, data)
 		if err != nil {
 			logger.Errorw("Failed to render", zap.Error(err))
 			http.Error(w, "Failed to render page", http.StatusInternalServerError)
@@ -83,3 +83,46 @@ func TestHandleAbout(t *testing.T) {
 	require.Contains(t, body, "Timezone:")
 	require.Contains(t, body, "Europe/Prague")
 }
+
+func TestHandleIndexGroupsAndFilters(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes([]byte(`
+services:
+  checkout:
+    group: Shop
+    tags: [payments]
+  postgres:
+    group: Infrastructure
+    tags: [db]
+  homepage:
+`))
+	require.NoError(t, err)
+	handler := handleIndex(db, config)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	body := rr.Body.String()
+	require.Contains(t, body, `<h2 class="group-name">Shop</h2>`)
+	require.Contains(t, body, `<h2 class="group-name">Other services</h2>`)
+	require.Contains(t, body, `href="/?tag=payments"`)
+	require.Contains(t, body, `href="/?group=Infrastructure"`)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/?tag=db", nil))
+	body = rr.Body.String()
+	require.Contains(t, body, `data-service-id="postgres"`)
+	require.NotContains(t, body, `data-service-id="checkout"`)
+	require.NotContains(t, body, `data-service-id="homepage"`)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/?group=Shop", nil))
+	body = rr.Body.String()
+	require.Contains(t, body, `data-service-id="checkout"`)
+	require.NotContains(t, body, `data-service-id="postgres"`)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/?tag=missing", nil))
+	require.Contains(t, rr.Body.String(), "No services match the filter")
+}
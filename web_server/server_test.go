@@ -0,0 +1,83 @@
+package web_server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/storage"
+)
+
+func TestServerShutdown(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	config.Port = 9101
+
+	server, err := StartServer(db, config)
+	require.NoError(t, err)
+
+	_, err = StartServer(db, config)
+	require.Error(t, err, "port already in use")
+
+	// open event stream, which would otherwise keep the server from shutting down
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/events", config.Port))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "retry: 5000\n", line)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	start := time.Now()
+	err = server.Shutdown(ctx)
+	require.NoError(t, err)
+	require.Less(t, time.Since(start), time.Second)
+
+	// stopped on request, no error reported
+	err, ok := <-server.Errors()
+	require.False(t, ok)
+	require.NoError(t, err)
+
+	_, err = http.Get(fmt.Sprintf("http://localhost:%d/", config.Port))
+	require.Error(t, err, "server should not accept new connections")
+}
+
+func TestServerReload(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	config.Port = 9107
+
+	server, err := StartServer(db, config)
+	require.NoError(t, err)
+	defer server.Close()
+
+	statusUrl := fmt.Sprintf("http://localhost:%d/status", config.Port)
+	resp, err := http.Get(statusUrl)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode, "no public services")
+
+	newConfig, err := conf.ConfigFromBytes([]byte(`
+services:
+  beacon-periodic-checker:
+    public: true
+`))
+	require.NoError(t, err)
+	server.Reload(newConfig)
+
+	resp, err = http.Get(statusUrl)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,299 @@
+package web_server
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/logging"
+	"github.com/davidmasek/beacon/monitor"
+	"github.com/davidmasek/beacon/storage"
+	"go.uber.org/zap"
+)
+
+const (
+	SERVICE_HISTORY_PAGE_SIZE = 50
+	LATENCY_CHART_WIDTH       = 560
+	LATENCY_CHART_HEIGHT      = 120
+	TIMELINE_LABEL_FORMAT     = "Jan 02 15:04"
+)
+
+// Time range of the status timeline on the service detail page
+type TimelineRange struct {
+	Name     string
+	Duration time.Duration
+	// length of a single timeline segment
+	Segment time.Duration
+}
+
+var TIMELINE_RANGES = []TimelineRange{
+	{Name: "day", Duration: 24 * time.Hour, Segment: 30 * time.Minute},
+	{Name: "week", Duration: 7 * 24 * time.Hour, Segment: 3 * time.Hour},
+	{Name: "month", Duration: 30 * 24 * time.Hour, Segment: 12 * time.Hour},
+}
+
+type OutageView struct {
+	Start    string
+	End      string
+	Duration string
+	// outage still in progress at the end of the range
+	Ongoing bool
+}
+
+type CheckView struct {
+	Timestamp time.Time
+	Status    monitor.ServiceStatus
+	Metadata  map[string]string
+}
+
+type LatencyChart struct {
+	// SVG polyline points
+	Points    string
+	MaxMs     int64
+	AverageMs int64
+	Width     int
+	Height    int
+}
+
+func timelineRange(name string) (TimelineRange, bool) {
+	for _, r := range TIMELINE_RANGES {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return TimelineRange{}, false
+}
+
+// Build latency chart from web check metadata. Returns nil if there is no latency data.
+// Checks must be sorted ascending.
+func buildLatencyChart(checks []*storage.HealthCheck, from, to time.Time) *LatencyChart {
+	type point struct {
+		ts      time.Time
+		latency int64
+	}
+	points := []point{}
+	var maxMs, sumMs int64
+	for _, check := range checks {
+		if check.Timestamp.Before(from) {
+			continue
+		}
+		value, ok := check.Metadata[monitor.LATENCY_METADATA_KEY]
+		if !ok {
+			continue
+		}
+		latency, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		points = append(points, point{ts: check.Timestamp, latency: latency})
+		maxMs = max(maxMs, latency)
+		sumMs += latency
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	chart := &LatencyChart{
+		MaxMs:     maxMs,
+		AverageMs: sumMs / int64(len(points)),
+		Width:     LATENCY_CHART_WIDTH,
+		Height:    LATENCY_CHART_HEIGHT,
+	}
+	span := to.Sub(from).Seconds()
+	scale := float64(max(maxMs, 1))
+	coords := make([]string, 0, len(points))
+	for _, p := range points {
+		x := p.ts.Sub(from).Seconds() / span * LATENCY_CHART_WIDTH
+		y := LATENCY_CHART_HEIGHT - float64(p.latency)/scale*LATENCY_CHART_HEIGHT
+		coords = append(coords, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	chart.Points = strings.Join(coords, " ")
+	return chart
+}
+
+// Show details and history of a single service
+func handleServiceDetail(db storage.Storage, config *conf.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		serviceCfg, err := findAnyService(db, config, r.PathValue("service_id"))
+		if err != nil {
+			logger.Errorw("Failed to load services", zap.Error(err))
+			http.Error(w, "Failed to load services", http.StatusInternalServerError)
+			return
+		}
+		if serviceCfg == nil {
+			http.NotFound(w, r)
+			return
+		}
+		query := r.URL.Query()
+		rangeName := query.Get("range")
+		if rangeName == "" {
+			rangeName = "week"
+		}
+		timeline, ok := timelineRange(rangeName)
+		if !ok {
+			http.Error(w, fmt.Sprintf("Invalid range %q", rangeName), http.StatusBadRequest)
+			return
+		}
+		page := 1
+		if value := query.Get("page"); value != "" {
+			var err error
+			page, err = strconv.Atoi(value)
+			if err != nil || page < 1 {
+				http.Error(w, fmt.Sprintf("Invalid page %q", value), http.StatusBadRequest)
+				return
+			}
+		}
+
+		now := time.Now()
+		from := now.Add(-timeline.Duration)
+		loc := config.Timezone.Location
+
+		// include checks from one interval before start, they are used
+		// to decide the status of the first interval
+		checks, err := db.ListHealthChecks(storage.HealthCheckQuery{
+			ServiceId: serviceCfg.Id,
+			From:      from.Add(-SUMMARY_STATS_INTERVAL),
+		})
+		if err != nil {
+			logger.Errorw("Failed to load health checks", "service", serviceCfg.Id, zap.Error(err))
+			http.Error(w, "Failed to load health checks", http.StatusInternalServerError)
+			return
+		}
+		// BuildStatusIntervals expects oldest first
+		slices.Reverse(checks)
+		hasOlderChecks, err := hasChecksBefore(db, serviceCfg.Id, from.Add(-SUMMARY_STATS_INTERVAL))
+		if err != nil {
+			logger.Errorw("Failed to count health checks", "service", serviceCfg.Id, zap.Error(err))
+			http.Error(w, "Failed to load health checks", http.StatusInternalServerError)
+			return
+		}
+
+		windows, err := serviceMaintenance(db, config, serviceCfg)
+		if err != nil {
+			logger.Errorw("Failed to load maintenance windows", "service", serviceCfg.Id, zap.Error(err))
+			http.Error(w, "Failed to load maintenance windows", http.StatusInternalServerError)
+			return
+		}
+
+		intervals := intervalsSinceFirstCheck(checks, hasOlderChecks, from, now)
+		monitor.MarkMaintenance(intervals, windows, loc)
+		boundaries := []time.Time{}
+		for ts := from; ts.Before(now); ts = ts.Add(timeline.Segment) {
+			boundaries = append(boundaries, ts)
+		}
+		boundaries = append(boundaries, now)
+		segments := summarizeSegments(intervals, boundaries, func(ts time.Time) string {
+			return ts.In(loc).Format(TIMELINE_LABEL_FORMAT)
+		})
+		up, down := monitor.SummarizeIntervals(intervals)
+
+		outages := []OutageView{}
+		for _, outage := range monitor.FindOutages(intervals) {
+			outages = append(outages, OutageView{
+				Start:    outage.Start.In(loc).Format(time.DateTime),
+				End:      outage.End.In(loc).Format(time.DateTime),
+				Duration: outage.DurationHuman(),
+				Ongoing:  !outage.End.Before(now),
+			})
+		}
+		// newest first
+		slices.Reverse(outages)
+
+		// latest check might be older than the selected range
+		latest, err := db.LatestHealthCheck(serviceCfg.Id)
+		if err != nil {
+			logger.Errorw("Failed to load latest health check", "service", serviceCfg.Id, zap.Error(err))
+			http.Error(w, "Failed to load health checks", http.StatusInternalServerError)
+			return
+		}
+		latestChecks := []*storage.HealthCheck{}
+		lastChecked := "never"
+		if latest != nil {
+			latestChecks = append(latestChecks, latest)
+			lastChecked = TimeAgo(latest.Timestamp)
+		}
+
+		var latency *LatencyChart
+		if serviceCfg.IsWebService() {
+			latency = buildLatencyChart(checks, from, now)
+		}
+
+		incidents, err := db.ListIncidents(storage.IncidentQuery{ServiceId: serviceCfg.Id, Limit: SERVICE_INCIDENTS_LIMIT})
+		if err != nil {
+			logger.Errorw("Failed to load incidents", "service", serviceCfg.Id, zap.Error(err))
+			http.Error(w, "Failed to load incidents", http.StatusInternalServerError)
+			return
+		}
+		incidentViews := make([]IncidentView, 0, len(incidents))
+		for _, incident := range incidents {
+			incidentViews = append(incidentViews, toIncidentView(incident, now, loc))
+		}
+
+		historyQuery := storage.HealthCheckQuery{ServiceId: serviceCfg.Id}
+		total, err := db.CountHealthChecks(historyQuery)
+		if err != nil {
+			logger.Errorw("Failed to count health checks", "service", serviceCfg.Id, zap.Error(err))
+			http.Error(w, "Failed to load health checks", http.StatusInternalServerError)
+			return
+		}
+		historyQuery.Limit = SERVICE_HISTORY_PAGE_SIZE
+		historyQuery.Offset = (page - 1) * SERVICE_HISTORY_PAGE_SIZE
+		history, err := db.ListHealthChecks(historyQuery)
+		if err != nil {
+			logger.Errorw("Failed to load health checks", "service", serviceCfg.Id, zap.Error(err))
+			http.Error(w, "Failed to load health checks", http.StatusInternalServerError)
+			return
+		}
+		historyViews := make([]CheckView, 0, len(history))
+		for _, check := range history {
+			historyViews = append(historyViews, CheckView{
+				Timestamp: check.Timestamp.In(loc),
+				Status:    monitor.HealthCheckStatus(check),
+				Metadata:  check.Metadata,
+			})
+		}
+		pages := max(1, int(math.Ceil(float64(total)/SERVICE_HISTORY_PAGE_SIZE)))
+
+		pageUrl := func(p int) string {
+			values := url.Values{"range": {timeline.Name}, "page": {strconv.Itoa(p)}}
+			return "/services/" + url.PathEscape(serviceCfg.Id) + "?" + values.Encode()
+		}
+		prevUrl, nextUrl := "", ""
+		if page > 1 {
+			prevUrl = pageUrl(page - 1)
+		}
+		if page < pages {
+			nextUrl = pageUrl(page + 1)
+		}
+
+		data := pageData(w, r, "service")
+		data["Service"] = serviceCfg
+		data["Status"] = monitor.GetServiceStatus(*serviceCfg, latestChecks)
+		data["LastChecked"] = lastChecked
+		data["Maintenance"] = maintenanceNote(monitor.ActiveMaintenance(windows, now, loc), loc)
+		data["Ranges"] = TIMELINE_RANGES
+		data["Range"] = timeline.Name
+		data["Timeline"] = segments
+		data["Uptime"] = fmt.Sprintf("%.2f%% up, %.2f%% down", up, down)
+		data["Outages"] = outages
+		data["Incidents"] = incidentViews
+		data["Latency"] = latency
+		data["History"] = historyViews
+		data["Total"] = total
+		data["Page"] = page
+		data["Pages"] = pages
+		data["PrevUrl"] = prevUrl
+		data["NextUrl"] = nextUrl
+		err = SERVICE_TEMPLATE.Execute(w, data)
+		if err != nil {
+			logger.Errorw("Failed to render", zap.Error(err))
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		}
+	}
+}
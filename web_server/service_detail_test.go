@@ -0,0 +1,104 @@
+package web_server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/monitor"
+	"github.com/davidmasek/beacon/storage"
+)
+
+func TestServiceDetail(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	RegisterGuiHandlers(db, mux, config, NewSessionStore())
+	// heartbeat routes share the /services prefix
+	monitor.RegisterHeartbeatHandlers(db, mux, config)
+
+	now := time.Now()
+	// up for the last 12 hours, with a failure 30 hours ago
+	for i := range 12 * 6 {
+		err := db.AddHealthCheck(&storage.HealthCheckInput{
+			ServiceId: "beacon-github",
+			Timestamp: now.Add(-time.Duration(i) * 10 * time.Minute),
+			Metadata:  map[string]string{"status": "OK", monitor.LATENCY_METADATA_KEY: "120"},
+		})
+		require.NoError(t, err)
+	}
+	err = db.AddHealthCheck(&storage.HealthCheckInput{
+		ServiceId: "beacon-github",
+		Timestamp: now.Add(-30 * time.Hour),
+		Metadata:  map[string]string{"status": "FAIL", "error": "connection refused"},
+	})
+	require.NoError(t, err)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/services/beacon-github")
+	require.Equal(t, http.StatusOK, rr.Code)
+	body := rr.Body.String()
+	assert.Contains(t, body, "https://github.com/davidmasek/beacon")
+	assert.Contains(t, body, "status-OK")
+	// default range is week, segments of 3 hours
+	assert.Equal(t, 7*8, strings.Count(body, `class="segment segment-`))
+	assert.Contains(t, body, "latency_ms")
+	assert.Contains(t, body, "<polyline")
+	assert.Contains(t, body, "max 120 ms")
+	assert.Contains(t, body, "Page 1 of 2 (73 checks)")
+	assert.Contains(t, body, "page=2")
+
+	rr = get("/services/beacon-github?range=day&page=2")
+	require.Equal(t, http.StatusOK, rr.Code)
+	body = rr.Body.String()
+	assert.Equal(t, 48, strings.Count(body, `class="segment segment-`))
+	assert.Contains(t, body, "Page 2 of 2")
+	assert.Contains(t, body, "connection refused")
+	assert.NotContains(t, body, "No outages in this period.", "time before the first check in range counts as outage when older checks exist")
+
+	rr = get("/services/beacon-periodic-checker?range=month")
+	require.Equal(t, http.StatusOK, rr.Code)
+	body = rr.Body.String()
+	assert.Contains(t, body, "No checks found.")
+	assert.Contains(t, body, "No outages in this period.")
+	assert.NotContains(t, body, "Response time", "latency chart only for web services")
+
+	rr = get("/services/beacon-github?range=year")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = get("/services/beacon-github?page=0")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = get("/services/unknown")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestBuildLatencyChart(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+	checks := []*storage.HealthCheck{
+		{Timestamp: from.Add(-time.Minute), Metadata: map[string]string{monitor.LATENCY_METADATA_KEY: "999"}},
+		{Timestamp: from, Metadata: map[string]string{monitor.LATENCY_METADATA_KEY: "100"}},
+		{Timestamp: from.Add(30 * time.Minute), Metadata: map[string]string{}},
+		{Timestamp: to, Metadata: map[string]string{monitor.LATENCY_METADATA_KEY: "200"}},
+	}
+	chart := buildLatencyChart(checks, from, to)
+	require.NotNil(t, chart)
+	assert.Equal(t, int64(200), chart.MaxMs)
+	assert.Equal(t, int64(150), chart.AverageMs)
+	assert.Equal(t, "0.0,60.0 560.0,0.0", chart.Points)
+
+	assert.Nil(t, buildLatencyChart(checks[2:3], from, to))
+}
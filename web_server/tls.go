@@ -0,0 +1,110 @@
+package web_server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidmasek/beacon/logging"
+	"go.uber.org/zap"
+)
+
+// Serves TLS certificate from files, reloading it when the files change.
+// This allows renewing certificates (e.g. by certbot) without restarting Beacon.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	reloader := &certReloader{certFile: certFile, keyFile: keyFile}
+	certMod, keyMod, err := reloader.modTimes()
+	if err != nil {
+		return nil, err
+	}
+	err = reloader.load(certMod, keyMod)
+	if err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+func (c *certReloader) modTimes() (certMod, keyMod time.Time, err error) {
+	certInfo, err := os.Stat(c.certFile)
+	if err != nil {
+		return certMod, keyMod, fmt.Errorf("cannot read certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(c.keyFile)
+	if err != nil {
+		return certMod, keyMod, fmt.Errorf("cannot read key: %w", err)
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+// Expects c.mu to be held, or c not shared yet
+func (c *certReloader) load(certMod, keyMod time.Time) error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("cannot load certificate: %w", err)
+	}
+	c.cert = &cert
+	c.certMod = certMod
+	c.keyMod = keyMod
+	return nil
+}
+
+// Return current certificate, reloading it first if the files changed.
+// Keeps serving the previous certificate if the new one cannot be loaded,
+// e.g. when only one of the files has been replaced so far.
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	logger := logging.Get()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	certMod, keyMod, err := c.modTimes()
+	if err != nil {
+		logger.Errorw("Failed to check certificate files, using previous certificate", zap.Error(err))
+		return c.cert, nil
+	}
+	if certMod.Equal(c.certMod) && keyMod.Equal(c.keyMod) {
+		return c.cert, nil
+	}
+	err = c.load(certMod, keyMod)
+	if err != nil {
+		logger.Errorw("Failed to reload certificate, using previous certificate", zap.Error(err))
+		return c.cert, nil
+	}
+	logger.Infow("Reloaded TLS certificate", "cert_file", c.certFile)
+	return c.cert, nil
+}
+
+// Redirect plain HTTP requests to HTTPS served on httpsPort
+func redirectToHttps(httpsPort int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			// no port in request
+			host = strings.Trim(r.Host, "[]")
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		// 308 keeps the method and body, so that heartbeats (POST) survive the redirect
+		code := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			code = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, target, code)
+	}
+}
@@ -0,0 +1,168 @@
+package web_server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/storage"
+)
+
+// Write self-signed certificate for localhost with the given serial number
+func writeTestCert(t *testing.T, certFile, keyFile string, serial int64) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	require.NoError(t, err)
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	require.NoError(t, err)
+}
+
+func TestServerTls(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeTestCert(t, certFile, keyFile, 1)
+
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	config.Port = 9103
+	config.HeartbeatPort = 9104
+	config.Tls = conf.TlsConfig{CertFile: certFile, KeyFile: keyFile, RedirectPort: 9105}
+	server, err := StartServer(db, config)
+	require.NoError(t, err)
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			// new connection for each request, so that certificate reload is visible
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	serial := func(resp *http.Response) int64 {
+		return resp.TLS.PeerCertificates[0].SerialNumber.Int64()
+	}
+
+	resp, err := client.Get("https://localhost:9103/about")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, int64(1), serial(resp))
+
+	t.Log("Heartbeats only on heartbeat port")
+	resp, err = client.Post("https://localhost:9104/services/beacon-github/beat", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = client.Post("https://localhost:9103/services/beacon-github/beat", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, err = client.Get("https://localhost:9104/about")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	t.Log("Redirect to HTTPS")
+	resp, err = client.Get("http://localhost:9105/about?x=1")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	require.Equal(t, "https://localhost:9103/about?x=1", resp.Header.Get("Location"))
+
+	t.Log("Reload certificate on change")
+	writeTestCert(t, certFile, keyFile, 2)
+	// make sure modification time changes even on filesystems with coarse timestamps
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	require.NoError(t, os.Chtimes(keyFile, future, future))
+	resp, err = client.Get("https://localhost:9103/about")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, int64(2), serial(resp))
+
+	t.Log("Keep previous certificate if new one is invalid")
+	require.NoError(t, os.WriteFile(keyFile, []byte("garbage"), 0600))
+	future = future.Add(time.Minute)
+	require.NoError(t, os.Chtimes(keyFile, future, future))
+	resp, err = client.Get("https://localhost:9103/about")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, int64(2), serial(resp))
+}
+
+func TestServerTlsInvalidConfig(t *testing.T) {
+	db := storage.NewTestDb(t)
+	defer db.Close()
+	dir := t.TempDir()
+	config, err := conf.ConfigFromBytes(TEST_CFG)
+	require.NoError(t, err)
+	config.Port = 9106
+
+	config.Tls = conf.TlsConfig{CertFile: filepath.Join(dir, "cert.pem")}
+	_, err = StartServer(db, config)
+	require.ErrorContains(t, err, "key_file")
+
+	config.Tls = conf.TlsConfig{CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "key.pem")}
+	_, err = StartServer(db, config)
+	require.ErrorContains(t, err, "cannot read certificate")
+}
+
+func TestRedirectToHttps(t *testing.T) {
+	testCases := []struct {
+		port     int
+		method   string
+		host     string
+		target   string
+		expected string
+		code     int
+	}{
+		{443, http.MethodGet, "example.com", "/", "https://example.com/", http.StatusMovedPermanently},
+		{443, http.MethodGet, "example.com:80", "/about", "https://example.com/about", http.StatusMovedPermanently},
+		{8443, http.MethodPost, "example.com", "/services/x/beat", "https://example.com:8443/services/x/beat", http.StatusPermanentRedirect},
+		{8443, http.MethodGet, "[::1]:8080", "/?a=b", "https://[::1]:8443/?a=b", http.StatusMovedPermanently},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s %s%s", tc.method, tc.host, tc.target), func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, nil)
+			req.Host = tc.host
+			rr := httptest.NewRecorder()
+			redirectToHttps(tc.port)(rr, req)
+			assert.Equal(t, tc.code, rr.Code)
+			assert.Equal(t, tc.expected, rr.Header().Get("Location"))
+		})
+	}
+}
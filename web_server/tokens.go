@@ -0,0 +1,128 @@
+package web_server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/logging"
+	"github.com/davidmasek/beacon/storage"
+	"go.uber.org/zap"
+)
+
+type TokenView struct {
+	Id         int
+	Name       string
+	Scopes     string
+	CreatedAt  string
+	ExpiresAt  string
+	LastUsedAt string
+	Status     string
+}
+
+func formatTokenTime(t time.Time, empty string) string {
+	if t.IsZero() {
+		return empty
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// Render token management page. newToken is shown once after creation.
+func renderTokens(w http.ResponseWriter, r *http.Request, db storage.Storage, status int, newToken string, errorMessage string) {
+	logger := logging.Get()
+	tokens, err := db.ListApiTokens()
+	if err != nil {
+		logger.Errorw("Failed to list tokens", zap.Error(err))
+		http.Error(w, "Failed to list tokens", http.StatusInternalServerError)
+		return
+	}
+	now := time.Now()
+	views := make([]TokenView, 0, len(tokens))
+	for _, token := range tokens {
+		tokenStatus := "active"
+		if !token.RevokedAt.IsZero() {
+			tokenStatus = "revoked"
+		} else if !token.IsActive(now) {
+			tokenStatus = "expired"
+		}
+		views = append(views, TokenView{
+			Id:         token.Id,
+			Name:       token.Name,
+			Scopes:     strings.Join(token.Scopes, " "),
+			CreatedAt:  formatTokenTime(token.CreatedAt, ""),
+			ExpiresAt:  formatTokenTime(token.ExpiresAt, "never"),
+			LastUsedAt: formatTokenTime(token.LastUsedAt, "never"),
+			Status:     tokenStatus,
+		})
+	}
+
+	data := pageData(w, r, "tokens")
+	data["Tokens"] = views
+	data["NewToken"] = newToken
+	data["Error"] = errorMessage
+	w.WriteHeader(status)
+	err = TOKENS_TEMPLATE.Execute(w, data)
+	if err != nil {
+		logger.Errorw("Failed to render", zap.Error(err))
+	}
+}
+
+func handleTokens(db storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		renderTokens(w, r, db, http.StatusOK, "", "")
+	}
+}
+
+func handleTokenCreate(db storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		name := strings.TrimSpace(r.PostFormValue("name"))
+		if name == "" {
+			renderTokens(w, r, db, http.StatusBadRequest, "", "Name is required")
+			return
+		}
+		scopes := strings.Fields(r.PostFormValue("scopes"))
+		for _, scope := range scopes {
+			if err := storage.ValidateScope(scope); err != nil {
+				renderTokens(w, r, db, http.StatusBadRequest, "", err.Error())
+				return
+			}
+		}
+		var expiresAt time.Time
+		if expires := strings.TrimSpace(r.PostFormValue("expires")); expires != "" {
+			duration, err := conf.ParseDuration(expires)
+			if err != nil || duration <= 0 {
+				renderTokens(w, r, db, http.StatusBadRequest, "", "Invalid expiration, use e.g. 30d or 12h")
+				return
+			}
+			expiresAt = time.Now().Add(duration)
+		}
+		value, token, err := db.CreateApiToken(name, scopes, expiresAt)
+		if err != nil {
+			renderTokens(w, r, db, http.StatusBadRequest, "", err.Error())
+			return
+		}
+		logger.Infow("API token created", "token", token.Id, "name", token.Name, "scopes", token.Scopes, "user", CurrentUser(r))
+		renderTokens(w, r, db, http.StatusOK, value, "")
+	}
+}
+
+func handleTokenRevoke(db storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.Get()
+		id, err := strconv.Atoi(r.PathValue("token_id"))
+		if err != nil {
+			http.Error(w, "Invalid token id", http.StatusBadRequest)
+			return
+		}
+		err = db.RevokeApiToken(id, time.Now())
+		if err != nil {
+			renderTokens(w, r, db, http.StatusNotFound, "", err.Error())
+			return
+		}
+		logger.Infow("API token revoked", "token", id, "user", CurrentUser(r))
+		http.Redirect(w, r, "/tokens", http.StatusSeeOther)
+	}
+}
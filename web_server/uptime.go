@@ -0,0 +1,101 @@
+package web_server
+
+import (
+	"time"
+
+	"github.com/davidmasek/beacon/conf"
+	"github.com/davidmasek/beacon/monitor"
+	"github.com/davidmasek/beacon/storage"
+)
+
+// Uptime summary of a part of a timeline, e.g. a single day
+type UptimeSegment struct {
+	Label     string
+	UpPercent float64
+	// false if there are no intervals in the segment, e.g. before the first check of a service
+	HasData bool
+}
+
+// "up", "degraded", "down" or "none", used for styling
+func (segment UptimeSegment) Level() string {
+	switch {
+	case !segment.HasData:
+		return "none"
+	case segment.UpPercent >= 100:
+		return "up"
+	case segment.UpPercent >= 95:
+		return "degraded"
+	default:
+		return "down"
+	}
+}
+
+// Check if the service has any checks before given time
+func hasChecksBefore(db storage.Storage, serviceId string, before time.Time) (bool, error) {
+	count, err := db.CountHealthChecks(storage.HealthCheckQuery{ServiceId: serviceId, To: before})
+	return count > 0, err
+}
+
+// Maintenance windows of the service, see monitor.MarkMaintenance
+func serviceMaintenance(db storage.Storage, config *conf.Config, service *conf.ServiceConfig) ([]conf.MaintenanceWindow, error) {
+	windows, err := monitor.MaintenanceWindows(db, config)
+	if err != nil {
+		return nil, err
+	}
+	return monitor.ServiceMaintenance(windows, service), nil
+}
+
+// Description of maintenance in progress, empty if window is nil
+func maintenanceNote(window *conf.MaintenanceWindow, loc *time.Location) string {
+	if window == nil {
+		return ""
+	}
+	if window.Reason == "" {
+		return "Planned maintenance: " + window.Schedule(loc)
+	}
+	return window.Reason + " (" + window.Schedule(loc) + ")"
+}
+
+// Build status intervals from `from` until `to`.
+// Checks must be sorted ascending and include checks from one interval before `from`.
+//
+// If there are no older checks, start from the first check instead, since time before
+// the first check should not count as downtime (the service might not have existed).
+func intervalsSinceFirstCheck(checks []*storage.HealthCheck, hasOlderChecks bool, from, to time.Time) []monitor.IntervalStatus {
+	start := from
+	if !hasOlderChecks {
+		if len(checks) == 0 {
+			return nil
+		}
+		if checks[0].Timestamp.After(start) {
+			start = checks[0].Timestamp
+		}
+	}
+	return monitor.BuildStatusIntervals(checks, start, to, SUMMARY_STATS_INTERVAL)
+}
+
+// Group intervals into segments [boundaries[i], boundaries[i+1]) by interval start and summarize each.
+// Both intervals and boundaries must be sorted ascending.
+func summarizeSegments(intervals []monitor.IntervalStatus, boundaries []time.Time, label func(time.Time) string) []UptimeSegment {
+	segments := []UptimeSegment{}
+	idx := 0
+	for i := 0; i+1 < len(boundaries); i++ {
+		start, end := boundaries[i], boundaries[i+1]
+		for idx < len(intervals) && intervals[idx].Interval.Start.Before(start) {
+			idx++
+		}
+		first := idx
+		for idx < len(intervals) && intervals[idx].Interval.Start.Before(end) {
+			idx++
+		}
+		segment := UptimeSegment{Label: label(start)}
+		if idx > first {
+			up, down := monitor.SummarizeIntervals(intervals[first:idx])
+			segment.UpPercent = up
+			// segment spent in maintenance has no data
+			segment.HasData = up+down > 0
+		}
+		segments = append(segments, segment)
+	}
+	return segments
+}